@@ -0,0 +1,116 @@
+package proto
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+)
+
+// SignedData возвращает данные запроса, покрываемые подписью:
+// детерминированную сериализацию запроса с пустым полем hash.
+func (x *UpdateMetricsRequest) SignedData() ([]byte, error) {
+	unsigned := proto.Clone(x).(*UpdateMetricsRequest)
+	unsigned.Hash = ""
+
+	return proto.MarshalOptions{Deterministic: true}.Marshal(unsigned)
+}
+
+// FromModel преобразует метрику в сообщение protobuf.
+func FromModel(metric models.Metrics) *Metric {
+	m := &Metric{Id: metric.ID, Labels: metric.Labels}
+
+	switch metric.MType {
+	case models.Gauge:
+		m.Type = MetricType_GAUGE
+		if metric.Value != nil {
+			m.Value = *metric.Value
+		}
+	case models.Counter:
+		m.Type = MetricType_COUNTER
+		if metric.Delta != nil {
+			m.Delta = *metric.Delta
+		}
+	case models.Histogram:
+		m.Type = MetricType_HISTOGRAM
+		if h := metric.Histogram; h != nil {
+			m.Histogram = &Histogram{Buckets: h.Buckets, Counts: h.Counts, Sum: h.Sum, Count: h.Count}
+		}
+	case models.Summary:
+		m.Type = MetricType_SUMMARY
+		if s := metric.Summary; s != nil {
+			m.Summary = &Summary{Sum: s.Sum, Count: s.Count, Quantiles: make([]*Quantile, 0, len(s.Quantiles))}
+			for _, q := range s.Quantiles {
+				m.Summary.Quantiles = append(m.Summary.Quantiles, &Quantile{Quantile: q.Quantile, Value: q.Value})
+			}
+		}
+	}
+
+	return m
+}
+
+// ToModel преобразует сообщение protobuf в метрику.
+func ToModel(m *Metric) (models.Metrics, error) {
+	metric := models.Metrics{ID: m.GetId()}
+	if len(m.GetLabels()) > 0 {
+		metric.Labels = models.Labels(m.GetLabels())
+	}
+
+	switch m.GetType() {
+	case MetricType_GAUGE:
+		value := m.GetValue()
+		metric.MType = models.Gauge
+		metric.Value = &value
+	case MetricType_COUNTER:
+		delta := m.GetDelta()
+		metric.MType = models.Counter
+		metric.Delta = &delta
+	case MetricType_HISTOGRAM:
+		h := m.GetHistogram()
+		if h == nil {
+			return models.Metrics{}, fmt.Errorf("histogram is required for histogram metric")
+		}
+		metric.MType = models.Histogram
+		metric.Histogram = &models.HistogramValue{
+			Buckets: h.GetBuckets(),
+			Counts:  h.GetCounts(),
+			Sum:     h.GetSum(),
+			Count:   h.GetCount(),
+		}
+	case MetricType_SUMMARY:
+		s := m.GetSummary()
+		if s == nil {
+			return models.Metrics{}, fmt.Errorf("summary is required for summary metric")
+		}
+		metric.MType = models.Summary
+		metric.Summary = &models.SummaryValue{
+			Quantiles: make([]models.Quantile, 0, len(s.GetQuantiles())),
+			Sum:       s.GetSum(),
+			Count:     s.GetCount(),
+		}
+		for _, q := range s.GetQuantiles() {
+			metric.Summary.Quantiles = append(metric.Summary.Quantiles, models.Quantile{Quantile: q.GetQuantile(), Value: q.GetValue()})
+		}
+	default:
+		return models.Metrics{}, fmt.Errorf("invalid metric type: %s", m.GetType())
+	}
+
+	return metric, nil
+}
+
+// ModelType возвращает тип метрики в нотации models.
+func ModelType(t MetricType) string {
+	switch t {
+	case MetricType_GAUGE:
+		return models.Gauge
+	case MetricType_COUNTER:
+		return models.Counter
+	case MetricType_HISTOGRAM:
+		return models.Histogram
+	case MetricType_SUMMARY:
+		return models.Summary
+	}
+
+	return ""
+}
@@ -8,15 +8,49 @@
 // Конфигурация агента загружается из:
 //   - Флагов командной строки (приоритет 1)
 //   - Переменных окружения (приоритет 2)
-//   - Значений по умолчанию (приоритет 3)
+//   - Файла конфигурации в формате JSON (приоритет 3)
+//   - Значений по умолчанию (приоритет 4)
+//
+// Путь к файлу конфигурации задается флагом -c (-config) или переменной окружения CONFIG.
+// Ключи файла совпадают с именами переменных окружения в нижнем регистре.
+// Интервалы задаются строками вида "1s"; число без единицы измерения означает секунды:
+//
+//	{
+//	    "address": "localhost:8080",
+//	    "report_interval": "1s",
+//	    "poll_interval": "1s",
+//	    "crypto_key": "/path/to/key.pem"
+//	}
 //
 // Поддерживаемые флаги командной строки:
 //
+//	-c, -config: путь к файлу конфигурации в формате JSON (опционально)
 //	-a: адрес сервера (пример: -a "localhost:8080")
-//	-r: интервал отправки метрик в секундах (пример: -r 10)
-//	-p: интервал сбора метрик в секундах (пример: -p 2)
+//	-r: интервал отправки метрик (пример: -r 10s)
+//	-p: интервал сбора метрик (пример: -p 2s)
 //	-k: ключ для хеширования (опционально)
 //	-l: лимит одновременных запросов (пример: -l 3)
+//	--labels: метки, добавляемые ко всем метрикам (пример: --labels "host=web1,env=prod")
+//	--transport: транспорт отправки метрик: http или grpc, для grpc в -a указывается адрес gRPC-сервера (пример: --transport grpc)
+//	--crypto-key: путь к открытому ключу сервера для шифрования (опционально)
+//	--spool-dir: директория очереди неотправленных метрик на диске (опционально)
+//	--spool-max-segments: лимит сегментов очереди до их объединения (пример: --spool-max-segments 100)
+//	--collectors: включенные плагины сбора метрик с необязательным интервалом (пример: --collectors "runtime,system,disk:30s")
+//	--collector-timeout: время на сбор метрик одним плагином (пример: --collector-timeout 5s)
+//	--disk-mountpoints, --disk-exclude-mountpoints: шаблоны точек монтирования для плагина disk (пример: --disk-exclude-mountpoints "/boot/*")
+//	--disk-fstypes, --disk-exclude-fstypes: шаблоны типов файловых систем для плагина disk (пример: --disk-fstypes "ext4,xfs")
+//
+// Метрики собираются плагинами: runtime (метрики среды выполнения Go и RandomValue),
+// system (память, загрузка каждого CPU и доли режимов user, system, iowait, steal;
+// загрузка CPU измеряется в фоне и не задерживает сбор) и disk (заполненность и inode
+// файловых систем с метками mountpoint и fstype, счетчики чтения и записи устройств
+// с меткой device). По умолчанию включены все три плагина, а disk пропускает squashfs.
+// Плагины, не указанные в --collectors, отключены.
+// Каждый плагин собирает метрики не чаще своего интервала и не дольше таймаута,
+// поэтому медленный источник не задерживает остальные.
+//
+// По сигналам SIGINT, SIGTERM и SIGQUIT агент выполняет последний сбор и отправку
+// метрик и завершает работу. Повторный сигнал завершает агента немедленно.
 //
 // Пример запуска:
 //
@@ -24,19 +58,69 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/Ko4etov/go-metrics/internal/agent"
 	"github.com/Ko4etov/go-metrics/internal/agent/config"
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
 )
 
+// shutdownTimeout - время на последнюю отправку метрик при остановке агента.
+const shutdownTimeout = 15 * time.Second
+
 // main является точкой входа для агента сбора метрик.
 // Функция выполняет:
 //  1. Инициализацию конфигурации из флагов и переменных окружения
 //  2. Создание экземпляра агента с полученной конфигурацией
 //  3. Запуск основного цикла работы агента
+//  4. Остановку агента с последней отправкой метрик при получении сигнала
+//
+// В случае ошибки при инициализации конфигурации программа завершается с panic.
 func main() {
 	// Инициализация конфигурации агента
-	agentConfig := config.New()
+	agentConfig, err := config.New()
+
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Журнал нужен для сообщений об отброшенных батчах
+	if err := logger.Initialize("info"); err != nil {
+		panic(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer stop()
 
 	// Создание и запуск агента
-	agent.New(agentConfig).Run()
-}
\ No newline at end of file
+	metricsAgent := agent.New(agentConfig)
+	go metricsAgent.Run()
+
+	<-ctx.Done()
+	// Восстанавливаем обработку сигналов по умолчанию: повторный сигнал прервет остановку.
+	stop()
+
+	stopped := make(chan struct{})
+	go func() {
+		metricsAgent.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		fmt.Fprintf(os.Stderr, "agent did not stop in %s, exiting\n", shutdownTimeout)
+		os.Exit(1)
+	}
+}
@@ -3,12 +3,14 @@ package agent
 
 import (
 	"context"
+	"io"
 	"sync"
 	"time"
 
 	"github.com/Ko4etov/go-metrics/internal/agent/config"
 	"github.com/Ko4etov/go-metrics/internal/agent/interfaces"
 	"github.com/Ko4etov/go-metrics/internal/agent/repository/collector"
+	"github.com/Ko4etov/go-metrics/internal/models"
 	metricssender "github.com/Ko4etov/go-metrics/internal/agent/service/metrics_sender"
 )
 
@@ -18,6 +20,8 @@ type Agent struct {
 	reportInterval time.Duration // интервал отправки метрик
 	serverAddress  string        // адрес сервера
 	collector      interfaces.Collector // сборщик метрик
+	pollDuration   *collector.Histogram // длительность сбора метрик
+	labels         models.Labels // метки, добавляемые ко всем метрикам
 	sender         interfaces.MetricsSender // отправитель метрик
 	ctx            context.Context // контекст для управления жизненным циклом
 	cancel         context.CancelFunc // функция отмены контекста
@@ -28,15 +32,27 @@ type Agent struct {
 
 // New создает нового агента.
 func New(config *config.AgentConfig) *Agent {
-	collector := collector.New()
+	metricsCollector := collector.New()
+	if config.Collectors != nil {
+		metricsCollector = collector.NewWithPlugins(config.CollectorTimeout, config.Collectors...)
+	}
+	pollDuration := collector.NewHistogram(collector.DefaultBuckets)
+	metricsCollector.RegisterHistogram("PollDuration", pollDuration)
 	sender := metricssender.New(config.Address, config.HashKey, config.RateLimit)
+	sender.CryptoKey = config.CryptoKey
+	sender.Transport = config.Transport
+	if config.Spool != nil {
+		sender.EnableSpool(config.Spool)
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &Agent{
 		pollInterval:   config.PollInterval,
 		reportInterval: config.ReportInterval,
 		serverAddress:  config.Address,
-		collector:      collector,
+		collector:      metricsCollector,
+		pollDuration:   pollDuration,
+		labels:         config.Labels,
 		sender:         sender,
 		ctx:            ctx,
 		cancel:         cancel,
@@ -99,7 +115,8 @@ func (a *Agent) runReporting() {
 	}
 }
 
-// Stop останавливает агента.
+// Stop останавливает агента: завершает циклы сбора и отправки, выполняет последний
+// сбор и отправку метрик и дожидается завершения отправки.
 func (a *Agent) Stop() {
 	a.mu.RLock()
 	if !a.isRunning {
@@ -110,11 +127,18 @@ func (a *Agent) Stop() {
 
 	a.cancel()
 
+	// Последняя отправка выполняется после завершения циклов сбора и отправки,
+	// чтобы не пересекаться с очередной периодической отправкой.
+	a.wg.Wait()
+	a.flush()
+
 	if sender, ok := a.sender.(interface{ Stop() }); ok {
 		sender.Stop()
 	}
 
-	a.wg.Wait()
+	if closer, ok := a.collector.(io.Closer); ok {
+		closer.Close()
+	}
 }
 
 // IsRunning возвращает состояние агента.
@@ -130,7 +154,16 @@ func (a *Agent) pollMetrics() {
 	case <-a.ctx.Done():
 		return
 	default:
-		a.collector.Collect()
+		a.collect()
+	}
+}
+
+// collect собирает метрики и учитывает длительность сбора.
+func (a *Agent) collect() {
+	start := time.Now()
+	a.collector.Collect()
+	if a.pollDuration != nil {
+		a.pollDuration.ObserveDuration(start)
 	}
 }
 
@@ -140,8 +173,36 @@ func (a *Agent) reportMetrics() {
 	case <-a.ctx.Done():
 		return
 	default:
-		metrics := a.collector.Metrics()
+		metrics := withLabels(a.collector.Metrics(), a.labels)
 		a.sender.SendMetrics(metrics)
 		a.collector.PollCountReset()
 	}
-}
\ No newline at end of file
+}
+
+// flush выполняет последний сбор и отправку метрик при остановке агента,
+// чтобы значения, собранные после последней периодической отправки, не были потеряны.
+func (a *Agent) flush() {
+	a.collect()
+	metrics := withLabels(a.collector.Metrics(), a.labels)
+
+	if flusher, ok := a.sender.(interface{ Flush([]models.Metrics) }); ok {
+		flusher.Flush(metrics)
+	} else {
+		a.sender.SendMetrics(metrics)
+	}
+
+	a.collector.PollCountReset()
+}
+
+// withLabels добавляет метки агента к метрикам. Метки самой метрики имеют приоритет.
+func withLabels(metrics []models.Metrics, labels models.Labels) []models.Metrics {
+	if len(labels) == 0 {
+		return metrics
+	}
+
+	for i := range metrics {
+		metrics[i].Labels = labels.Merge(metrics[i].Labels)
+	}
+
+	return metrics
+}
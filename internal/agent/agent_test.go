@@ -1,10 +1,16 @@
 package agent
 
 import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/Ko4etov/go-metrics/internal/agent/config"
+	"github.com/Ko4etov/go-metrics/internal/models"
 )
 
 func TestNewAgent(t *testing.T) {
@@ -62,3 +68,53 @@ func TestAgent_PollMetrics(t *testing.T) {
 		t.Error("Agent should be stopped after Stop() call")
 	}
 }
+
+func TestAgent_StopReportsFinalMetrics(t *testing.T) {
+	var mu sync.Mutex
+	var received []models.Metrics
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Errorf("Expected gzip body: %v", err)
+			return
+		}
+
+		var metrics []models.Metrics
+		json.NewDecoder(gz).Decode(&metrics)
+
+		mu.Lock()
+		received = append(received, metrics...)
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// Интервалы больше длительности теста: метрики отправляются только при остановке.
+	agent := New(&config.AgentConfig{
+		PollInterval:   time.Hour,
+		ReportInterval: time.Hour,
+		Address:        server.URL[7:],
+		RateLimit:      1,
+	})
+
+	go agent.Run()
+	time.Sleep(50 * time.Millisecond)
+
+	agent.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, metric := range received {
+		if metric.ID == "PollCount" {
+			if *metric.Delta != 1 {
+				t.Errorf("Expected PollCount 1 from final collect, got %d", *metric.Delta)
+			}
+			return
+		}
+	}
+
+	t.Errorf("Expected PollCount in final report, got %d metrics", len(received))
+}
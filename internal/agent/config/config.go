@@ -1,26 +1,135 @@
 // Package config предоставляет конфигурацию для агента сбора метрик.
 package config
 
-import "time"
+import (
+	"crypto/rsa"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Ko4etov/go-metrics/internal/agent/interfaces"
+	"github.com/Ko4etov/go-metrics/internal/agent/repository/collector"
+	"github.com/Ko4etov/go-metrics/internal/agent/repository/spool"
+	grpcsender "github.com/Ko4etov/go-metrics/internal/agent/service/grpc_sender"
+	"github.com/Ko4etov/go-metrics/internal/models"
+	configloader "github.com/Ko4etov/go-metrics/internal/service/config_loader"
+	hybridcrypto "github.com/Ko4etov/go-metrics/internal/service/hybrid_crypto"
+)
 
 // AgentConfig содержит конфигурационные параметры агента.
 type AgentConfig struct {
-	Address        string        // адрес сервера для отправки метрик
-	PollInterval   time.Duration // интервал опроса метрик системы
-	ReportInterval time.Duration // интервал отправки метрик на сервер
-	HashKey        string        // ключ для хеширования (опционально)
-	RateLimit      int           // лимит одновременных запросов
+	Address          string                       // адрес сервера для отправки метрик
+	PollInterval     time.Duration                // интервал опроса метрик системы
+	ReportInterval   time.Duration                // интервал отправки метрик на сервер
+	HashKey          string                       // ключ для хеширования (опционально)
+	RateLimit        int                          // лимит одновременных запросов
+	CryptoKey        *rsa.PublicKey               // открытый ключ сервера для шифрования (опционально)
+	Spool            *spool.Spool                 // очередь неотправленных батчей на диске (опционально)
+	Transport        interfaces.BatchTransport    // транспорт отправки батчей, nil - HTTP
+	Labels           models.Labels                // метки, добавляемые ко всем метрикам (опционально)
+	Collectors       []interfaces.CollectorPlugin // включенные плагины сбора метрик, nil - плагины по умолчанию
+	CollectorTimeout time.Duration                // время на сбор метрик одним плагином
 }
 
-// New создает новую конфигурацию агента.
-func New() *AgentConfig {
-	parameters := parseAgentParameters()
+// Транспорты отправки метрик.
+const (
+	TransportHTTP = "http" // отправка батчей по HTTP
+	TransportGRPC = "grpc" // отправка батчей по gRPC
+)
 
-	return &AgentConfig{
-		Address:        parameters.Address,
-		PollInterval:   time.Duration(parameters.PollInterval) * time.Second,
-		ReportInterval: time.Duration(parameters.ReportInterval) * time.Second,
-		HashKey:        parameters.HashKey,
-		RateLimit:      parameters.RateLimit,
+// New создает новую конфигурацию агента из аргументов командной строки, переменных
+// окружения и файла конфигурации. Если запрошена справка, возвращается flag.ErrHelp.
+func New() (*AgentConfig, error) {
+	parameters, err := parseAgentParameters(os.Args[1:], configloader.New(os.Args[0]))
+	if err != nil {
+		return nil, fmt.Errorf("parameters error: %w", err)
+	}
+
+	if parameters.PollInterval <= 0 {
+		return nil, fmt.Errorf("poll interval must be positive: %s", parameters.PollInterval)
+	}
+
+	if parameters.ReportInterval <= 0 {
+		return nil, fmt.Errorf("report interval must be positive: %s", parameters.ReportInterval)
+	}
+
+	if parameters.RateLimit <= 0 {
+		return nil, fmt.Errorf("rate limit must be positive: %d", parameters.RateLimit)
+	}
+
+	if parameters.CollectorTimeout <= 0 {
+		return nil, fmt.Errorf("collector timeout must be positive: %s", parameters.CollectorTimeout)
+	}
+
+	collectors, err := collector.ParsePlugins(parameters.Collectors)
+	if err != nil {
+		return nil, fmt.Errorf("collectors error: %v", err)
+	}
+
+	diskFilter := collector.DiskFilter{
+		Mountpoints:        collector.ParseDiskPatterns(parameters.DiskMountpoints),
+		ExcludeMountpoints: collector.ParseDiskPatterns(parameters.DiskExcludeMountpoints),
+		FSTypes:            collector.ParseDiskPatterns(parameters.DiskFSTypes),
+		ExcludeFSTypes:     collector.ParseDiskPatterns(parameters.DiskExcludeFSTypes),
 	}
-}
\ No newline at end of file
+	if err := diskFilter.Validate(); err != nil {
+		return nil, fmt.Errorf("disk filter error: %v", err)
+	}
+
+	for _, plugin := range collectors {
+		if diskPlugin, ok := plugin.(*collector.DiskPlugin); ok {
+			diskPlugin.Filter = diskFilter
+		}
+	}
+
+	var cryptoKey *rsa.PublicKey
+	if parameters.CryptoKeyPath != "" {
+		key, err := hybridcrypto.LoadPublicKey(parameters.CryptoKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("crypto key error: %v", err)
+		}
+		cryptoKey = key
+	}
+
+	var metricsSpool *spool.Spool
+	if parameters.SpoolDir != "" {
+		sp, err := spool.Open(parameters.SpoolDir, parameters.SpoolSegments)
+		if err != nil {
+			return nil, fmt.Errorf("spool error: %v", err)
+		}
+		metricsSpool = sp
+	}
+
+	labels, err := models.ParseLabels(parameters.Labels)
+	if err != nil {
+		return nil, fmt.Errorf("labels error: %v", err)
+	}
+
+	var transport interfaces.BatchTransport
+	switch parameters.Transport {
+	case TransportHTTP:
+	case TransportGRPC:
+		grpcTransport, err := grpcsender.New(parameters.Address, parameters.HashKey)
+		if err != nil {
+			return nil, fmt.Errorf("grpc transport error: %v", err)
+		}
+		transport = grpcTransport
+	default:
+		return nil, fmt.Errorf("invalid transport %q: expected %s or %s",
+			parameters.Transport, TransportHTTP, TransportGRPC)
+	}
+
+	return &AgentConfig{
+		Address:          parameters.Address,
+		PollInterval:     parameters.PollInterval,
+		ReportInterval:   parameters.ReportInterval,
+		HashKey:          parameters.HashKey,
+		RateLimit:        parameters.RateLimit,
+		CryptoKey:        cryptoKey,
+		Spool:            metricsSpool,
+		Transport:        transport,
+		Labels:           labels,
+		Collectors:       collectors,
+		CollectorTimeout: parameters.CollectorTimeout,
+	}, nil
+}
@@ -1,125 +1,121 @@
 package config
 
 import (
-	"flag"
-	"os"
-	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
+
+	"github.com/Ko4etov/go-metrics/internal/agent/repository/collector"
+	configloader "github.com/Ko4etov/go-metrics/internal/service/config_loader"
 )
 
 const (
-	address        string = ":8080" // адрес сервера по умолчанию
-	reportInterval int    = 2       // интервал отправки метрик по умолчанию (в секундах)
-	pollInterval   int    = 10      // интервал опроса метрик по умолчанию (в секундах)
-	rateLimit      int    = 1       // лимит запросов по умолчанию
+	address        string        = ":8080"          // адрес сервера по умолчанию
+	reportInterval time.Duration = 2 * time.Second  // интервал отправки метрик по умолчанию
+	pollInterval   time.Duration = 10 * time.Second // интервал опроса метрик по умолчанию
+	rateLimit      int           = 1                // лимит запросов по умолчанию
+	spoolSegments  int           = 100              // лимит сегментов очереди на диске по умолчанию
+	transport      string        = "http"           // транспорт отправки метрик по умолчанию
+	diskExcludeFS  string        = "squashfs"       // исключаемые типы файловых систем по умолчанию
 )
 
 // AgentParameters содержит конфигурационные параметры для агента.
 type AgentParameters struct {
-	Address        string
-	ReportInterval int
-	PollInterval   int
-	HashKey        string
-	RateLimit      int
+	Address                string
+	ReportInterval         time.Duration
+	PollInterval           time.Duration
+	HashKey                string
+	RateLimit              int
+	CryptoKeyPath          string
+	SpoolDir               string
+	SpoolSegments          int
+	Transport              string
+	Labels                 string
+	Collectors             string
+	CollectorTimeout       time.Duration
+	DiskMountpoints        string
+	DiskExcludeMountpoints string
+	DiskFSTypes            string
+	DiskExcludeFSTypes     string
 }
 
-// parseAgentParameters парсит параметры агента.
-func parseAgentParameters() *AgentParameters {
+// parseAgentParameters разбирает параметры агента из аргументов командной строки args,
+// переменных окружения и файла конфигурации. Приоритет источников: флаги,
+// переменные окружения, файл конфигурации, значения по умолчанию.
+func parseAgentParameters(args []string, loader *configloader.Loader) (*AgentParameters, error) {
 	godotenv.Load()
 
-	addressParameter := addressParameter()
-	reportIntervalParameter := reportIntervalParameter()
-	pollIntervalParameter := pollIntervalParameter()
-	hashKeyParameter := hashKeyParameter()
-	rateLimitParameter := rateLimitParameter()
-
-	flag.Parse()
-
-	return &AgentParameters{
-		Address:        addressParameter,
-		ReportInterval: reportIntervalParameter,
-		PollInterval:   pollIntervalParameter,
-		HashKey:        hashKeyParameter,
-		RateLimit:      rateLimitParameter,
-	}
-}
-
-func rateLimitParameter() int {
-	rateLimit := rateLimit
-
-	if env, ok := os.LookupEnv("RATE_LIMIT"); ok {
-		val, err := strconv.Atoi(env)
-
-		if err != nil {
-			os.Exit(1)
-		}
-
-		rateLimit = val
-	}
-
-	flag.IntVar(&rateLimit, "l", rateLimit, "Hash key")
-
-	return rateLimit
-}
-
-func hashKeyParameter() string {
-	hashKey := ""
-
-	if env, ok := os.LookupEnv("KEY"); ok {
-		hashKey = env
-	}
-
-	flag.StringVar(&hashKey, "k", hashKey, "Hash key")
-
-	return hashKey
-}
-
-func addressParameter() string {
-	address := address
-
-	if addressEnv, ok := os.LookupEnv("ADDRESS"); ok {
-		address = addressEnv
-		return address
+	p := &AgentParameters{}
+
+	loader.String(&p.Address, configloader.Param{
+		Key: "address", Env: "ADDRESS", Flag: "a",
+		Usage: "Server address",
+	}, address)
+	loader.Duration(&p.ReportInterval, configloader.Param{
+		Key: "report_interval", Env: "REPORT_INTERVAL", Flag: "r",
+		Usage: "Report interval, e.g. 10s (a bare number means seconds)",
+	}, reportInterval, time.Second)
+	loader.Duration(&p.PollInterval, configloader.Param{
+		Key: "poll_interval", Env: "POLL_INTERVAL", Flag: "p",
+		Usage: "Poll interval, e.g. 2s (a bare number means seconds)",
+	}, pollInterval, time.Second)
+	loader.String(&p.HashKey, configloader.Param{
+		Key: "key", Env: "KEY", Flag: "k",
+		Usage: "Hash key",
+	}, "")
+	loader.Int(&p.RateLimit, configloader.Param{
+		Key: "rate_limit", Env: "RATE_LIMIT", Flag: "l",
+		Usage: "Maximum number of concurrent requests to the server",
+	}, rateLimit)
+	loader.String(&p.CryptoKeyPath, configloader.Param{
+		Key: "crypto_key", Env: "CRYPTO_KEY", Flag: "crypto-key",
+		Usage: "Path to server RSA public key for payload encryption",
+	}, "")
+	loader.String(&p.SpoolDir, configloader.Param{
+		Key: "spool_dir", Env: "SPOOL_DIR", Flag: "spool-dir",
+		Usage: "Directory for unsent metrics spool",
+	}, "")
+	loader.Int(&p.SpoolSegments, configloader.Param{
+		Key: "spool_max_segments", Env: "SPOOL_MAX_SEGMENTS", Flag: "spool-max-segments",
+		Usage: "Maximum number of spool segments before compaction",
+	}, spoolSegments)
+	loader.String(&p.Transport, configloader.Param{
+		Key: "transport", Env: "TRANSPORT", Flag: "transport",
+		Usage: "Metrics transport: http or grpc",
+	}, transport)
+	loader.String(&p.Labels, configloader.Param{
+		Key: "labels", Env: "LABELS", Flag: "labels",
+		Usage: "Labels attached to all metrics: name=value,name=value",
+	}, "")
+
+	loader.String(&p.Collectors, configloader.Param{
+		Key: "collectors", Env: "COLLECTORS", Flag: "collectors",
+		Usage: "Enabled metric collectors with optional intervals: name[:interval],...",
+	}, collector.DefaultPlugins)
+	loader.Duration(&p.CollectorTimeout, configloader.Param{
+		Key: "collector_timeout", Env: "COLLECTOR_TIMEOUT", Flag: "collector-timeout",
+		Usage: "Time limit for a single collector run, e.g. 5s (a bare number means seconds)",
+	}, collector.DefaultTimeout, time.Second)
+	loader.String(&p.DiskMountpoints, configloader.Param{
+		Key: "disk_mountpoints", Env: "DISK_MOUNTPOINTS", Flag: "disk-mountpoints",
+		Usage: "Mountpoint patterns reported by the disk collector, e.g. /,/data/* (empty means all)",
+	}, "")
+	loader.String(&p.DiskExcludeMountpoints, configloader.Param{
+		Key: "disk_exclude_mountpoints", Env: "DISK_EXCLUDE_MOUNTPOINTS", Flag: "disk-exclude-mountpoints",
+		Usage: "Mountpoint patterns skipped by the disk collector",
+	}, "")
+	loader.String(&p.DiskFSTypes, configloader.Param{
+		Key: "disk_fstypes", Env: "DISK_FSTYPES", Flag: "disk-fstypes",
+		Usage: "Filesystem type patterns reported by the disk collector, e.g. ext4,xfs (empty means all)",
+	}, "")
+	loader.String(&p.DiskExcludeFSTypes, configloader.Param{
+		Key: "disk_exclude_fstypes", Env: "DISK_EXCLUDE_FSTYPES", Flag: "disk-exclude-fstypes",
+		Usage: "Filesystem type patterns skipped by the disk collector",
+	}, diskExcludeFS)
+
+	if err := loader.Load(args); err != nil {
+		return nil, err
 	}
 
-	flag.StringVar(&address, "a", address, "Server address")
-
-	return address
+	return p, nil
 }
-
-func reportIntervalParameter() int {
-	reportInterval := reportInterval
-
-	reportIntervalEnv, ok := os.LookupEnv("REPORT_INTERVAL")
-
-	if !ok {
-		flag.IntVar(&reportInterval, "r", reportInterval, "Report interval in seconds")
-		return reportInterval
-	}
-
-	if result, err := strconv.ParseInt(reportIntervalEnv, 0, 64); err == nil {
-		reportInterval = int(result)
-	}
-
-	return reportInterval
-}
-
-func pollIntervalParameter() int {
-	pollInterval := pollInterval
-
-	pollIntervalEnv, ok := os.LookupEnv("POLL_INTERVAL")
-
-	if !ok {
-		flag.IntVar(&pollInterval, "p", pollInterval, "Poll interval in seconds")
-		return pollInterval
-	}
-
-	result, err := strconv.ParseInt(pollIntervalEnv, 0, 64)
-
-	if err != nil {
-		os.Exit(1)
-	}
-
-	return int(result)
-}
\ No newline at end of file
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	configloader "github.com/Ko4etov/go-metrics/internal/service/config_loader"
+)
+
+// newTestLoader создает загрузчик с переменными окружения env.
+func newTestLoader(env map[string]string) *configloader.Loader {
+	loader := configloader.New("agent")
+	loader.SetOutput(io.Discard)
+	loader.LookupEnv = func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+
+	return loader
+}
+
+func TestParseAgentParameters_ConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.json")
+	content := `{
+		"address": "localhost:9090",
+		"report_interval": "1s",
+		"poll_interval": "500ms",
+		"crypto_key": "/path/to/key.pem",
+		"rate_limit": 4,
+		"collectors": "runtime:5s"
+	}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	env := map[string]string{"CONFIG": path, "RATE_LIMIT": "2"}
+
+	params, err := parseAgentParameters([]string{"-r", "3"}, newTestLoader(env))
+	if err != nil {
+		t.Fatalf("parseAgentParameters() error = %v", err)
+	}
+
+	if params.Address != "localhost:9090" {
+		t.Errorf("Address = %q, want value from config file", params.Address)
+	}
+	if params.ReportInterval != 3*time.Second {
+		t.Errorf("ReportInterval = %s, want value from flag", params.ReportInterval)
+	}
+	if params.PollInterval != 500*time.Millisecond {
+		t.Errorf("PollInterval = %s, want value from config file", params.PollInterval)
+	}
+	if params.RateLimit != 2 {
+		t.Errorf("RateLimit = %d, want value from environment", params.RateLimit)
+	}
+	if params.CryptoKeyPath != "/path/to/key.pem" {
+		t.Errorf("CryptoKeyPath = %q, want value from config file", params.CryptoKeyPath)
+	}
+	if params.Collectors != "runtime:5s" {
+		t.Errorf("Collectors = %q, want value from config file", params.Collectors)
+	}
+	if params.CollectorTimeout != 5*time.Second {
+		t.Errorf("CollectorTimeout = %s, want default", params.CollectorTimeout)
+	}
+}
+
+func TestParseAgentParameters_InvalidValue(t *testing.T) {
+	_, err := parseAgentParameters(nil, newTestLoader(map[string]string{"POLL_INTERVAL": "often"}))
+	if err == nil {
+		t.Fatal("parseAgentParameters() error = nil, want error for invalid POLL_INTERVAL")
+	}
+}
@@ -0,0 +1,8 @@
+package interfaces
+
+import "github.com/Ko4etov/go-metrics/internal/agent/repository/spool"
+
+// BatchTransport определяет интерфейс транспорта отправки батча метрик на сервер.
+type BatchTransport interface {
+	SendBatch(batch spool.Batch) error
+}
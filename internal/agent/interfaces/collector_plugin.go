@@ -0,0 +1,22 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+)
+
+// MetricsSink принимает значения метрик, собранные плагином.
+type MetricsSink interface {
+	Gauge(name string, value float64)            // значение измерителя
+	Counter(name string, delta int64)            // приращение счетчика с прошлого сбора
+	WithLabels(labels models.Labels) MetricsSink // приемник, добавляющий метки к метрикам
+}
+
+// CollectorPlugin определяет интерфейс плагина сбора метрик.
+type CollectorPlugin interface {
+	Name() string                                        // имя плагина
+	Interval() time.Duration                             // интервал сбора, 0 - при каждом опросе агента
+	Collect(ctx context.Context, sink MetricsSink) error // сбор метрик с учетом срока ctx
+}
@@ -2,146 +2,159 @@
 package collector
 
 import (
-	"math/rand"
-	"runtime"
-	"strconv"
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"sync"
 	"time"
 
-	"github.com/shirou/gopsutil/v3/cpu"
-	"github.com/shirou/gopsutil/v3/mem"
-
+	"github.com/Ko4etov/go-metrics/internal/agent/interfaces"
 	"github.com/Ko4etov/go-metrics/internal/models"
 )
 
 // MetricsCollector реализует сбор и хранение метрик.
+// Метрики собираются подключенными плагинами, каждый по своему расписанию
+// и с ограничением времени, чтобы медленный источник не задерживал остальные.
 type MetricsCollector struct {
 	mu          sync.RWMutex
-	metrics     map[string]models.Metrics
+	metrics     map[string]models.Metrics // измерители и PollCount по ключам рядов
+	counters    map[string]models.Metrics // приращения счетчиков плагинов с последней отправки
 	pollCounter int
-	rand        *rand.Rand
+	histograms  map[string]*Histogram
+	summaries   map[string]*Summary
+	plugins     []*scheduledPlugin // подключенные плагины
+	timeout     time.Duration      // время на сбор метрик одним плагином
 }
 
-// New создает новый сборщик метрик.
+// New создает новый сборщик метрик с плагинами по умолчанию.
 func New() *MetricsCollector {
+	return NewWithPlugins(DefaultTimeout, NewRuntimePlugin(0), NewSystemPlugin(0), NewDiskPlugin(0))
+}
+
+// NewWithPlugins создает сборщик метрик с плагинами plugins.
+// Если timeout не положителен, используется DefaultTimeout.
+func NewWithPlugins(timeout time.Duration, plugins ...interfaces.CollectorPlugin) *MetricsCollector {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	scheduled := make([]*scheduledPlugin, 0, len(plugins))
+	for _, plugin := range plugins {
+		scheduled = append(scheduled, &scheduledPlugin{plugin: plugin})
+	}
+
 	return &MetricsCollector{
 		metrics:     make(map[string]models.Metrics),
+		counters:    make(map[string]models.Metrics),
 		pollCounter: 0,
-		rand:        rand.New(rand.NewSource(time.Now().UnixNano())),
+		histograms:  make(map[string]*Histogram),
+		summaries:   make(map[string]*Summary),
+		plugins:     scheduled,
+		timeout:     timeout,
 	}
 }
 
-// Collect собирает метрики системы.
+// Collect выполняет опрос: увеличивает счетчик опросов и запускает плагины,
+// у которых истек интервал сбора. Плагины работают параллельно; Collect ждет
+// каждый не дольше таймаута. Результат плагина, не уложившегося в таймаут,
+// отбрасывается, а сам плагин пропускает опросы, пока не завершится.
 func (c *MetricsCollector) Collect() {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	c.pollCounter++
-
-	var stats runtime.MemStats
-	runtime.ReadMemStats(&stats)
-
-	runtimeMetrics := map[string]float64{
-		"Alloc":         float64(stats.Alloc),
-		"BuckHashSys":   float64(stats.BuckHashSys),
-		"Frees":         float64(stats.Frees),
-		"GCCPUFraction": stats.GCCPUFraction,
-		"GCSys":         float64(stats.GCSys),
-		"HeapAlloc":     float64(stats.HeapAlloc),
-		"HeapIdle":      float64(stats.HeapIdle),
-		"HeapInuse":     float64(stats.HeapInuse),
-		"HeapObjects":   float64(stats.HeapObjects),
-		"HeapReleased":  float64(stats.HeapReleased),
-		"HeapSys":       float64(stats.HeapSys),
-		"LastGC":        float64(stats.LastGC),
-		"Lookups":       float64(stats.Lookups),
-		"MCacheInuse":   float64(stats.MCacheInuse),
-		"MCacheSys":     float64(stats.MCacheSys),
-		"MSpanInuse":    float64(stats.MSpanInuse),
-		"MSpanSys":      float64(stats.MSpanSys),
-		"Mallocs":       float64(stats.Mallocs),
-		"NextGC":        float64(stats.NextGC),
-		"NumForcedGC":   float64(stats.NumForcedGC),
-		"NumGC":         float64(stats.NumGC),
-		"OtherSys":      float64(stats.OtherSys),
-		"PauseTotalNs":  float64(stats.PauseTotalNs),
-		"StackInuse":    float64(stats.StackInuse),
-		"StackSys":      float64(stats.StackSys),
-		"Sys":           float64(stats.Sys),
-		"TotalAlloc":    float64(stats.TotalAlloc),
-	}
-
-	pollCount := c.pollCounter
-
-	for name, value := range runtimeMetrics {
-		valueCopy := value
-		c.metrics[name] = models.Metrics{
-			ID:    name,
-			MType: models.Gauge,
-			Value: &valueCopy,
-		}
-	}
-
-	pollCountCopy := int64(pollCount)
+	pollCount := int64(c.pollCounter)
 	c.metrics["PollCount"] = models.Metrics{
 		ID:    "PollCount",
 		MType: models.Counter,
-		Delta: &pollCountCopy,
+		Delta: &pollCount,
 	}
 
-	randValue := rand.Float64() * 100
-	c.metrics["RandomValue"] = models.Metrics{
-		ID:    "RandomValue",
-		MType: models.Gauge,
-		Value: &randValue,
+	now := time.Now()
+	due := make([]*scheduledPlugin, 0, len(c.plugins))
+	for _, p := range c.plugins {
+		if p.running {
+			continue
+		}
+		if interval := p.plugin.Interval(); !p.lastRun.IsZero() && now.Sub(p.lastRun) < interval {
+			continue
+		}
+		p.running = true
+		p.lastRun = now
+		due = append(due, p)
 	}
-
-	c.collectGopsutilMetrics()
+	c.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, p := range due {
+		wg.Add(1)
+		go func(p *scheduledPlugin) {
+			defer wg.Done()
+			c.runPlugin(p)
+		}(p)
+	}
+	wg.Wait()
 }
 
-// collectGopsutilMetrics собирает метрики системы через gopsutil.
-func (c *MetricsCollector) collectGopsutilMetrics() {
-	if memStats, err := mem.VirtualMemory(); err == nil {
-		totalMemory := float64(memStats.Total)
-		freeMemory := float64(memStats.Free)
+// runPlugin собирает метрики плагина p с ограничением времени.
+// Ошибка плагина не отменяет значения, которые он успел передать.
+func (c *MetricsCollector) runPlugin(p *scheduledPlugin) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
 
-		c.metrics["TotalMemory"] = models.Metrics{
-			ID:    "TotalMemory",
-			MType: models.Gauge,
-			Value: &totalMemory,
-		}
+	sink := newPluginSink()
+	done := make(chan struct{})
 
-		c.metrics["FreeMemory"] = models.Metrics{
-			ID:    "FreeMemory",
-			MType: models.Gauge,
-			Value: &freeMemory,
-		}
-	}
+	go func() {
+		defer close(done)
+		p.plugin.Collect(ctx, sink)
 
-	if cpuPercent, err := cpu.Percent(time.Second, false); err == nil && len(cpuPercent) > 0 {
-		cpuUtilization := cpuPercent[0]
-		c.metrics["CPUutilization1"] = models.Metrics{
-			ID:    "CPUutilization1",
-			MType: models.Gauge,
-			Value: &cpuUtilization,
-		}
+		c.mu.Lock()
+		p.running = false
+		c.mu.Unlock()
+	}()
+
+	select {
+	case <-done:
+		c.applyValues(sink)
+	case <-ctx.Done():
 	}
+}
 
-	if cpuPercent, err := cpu.Percent(time.Second, true); err == nil {
-		for i, percent := range cpuPercent {
-			percentCopy := percent
-			c.metrics[formatCPUutilization(i)] = models.Metrics{
-				ID:    formatCPUutilization(i),
-				MType: models.Gauge,
-				Value: &percentCopy,
+// Close освобождает ресурсы плагинов, например останавливает фоновые измерения.
+func (c *MetricsCollector) Close() error {
+	var errs []error
+	for _, p := range c.plugins {
+		if closer, ok := p.plugin.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("collector %s: %w", p.plugin.Name(), err))
 			}
 		}
 	}
+
+	return errors.Join(errs...)
 }
 
-// formatCPUutilization форматирует имя метрики загрузки CPU.
-func formatCPUutilization(index int) string {
-	return "CPUutilization" + strconv.Itoa(index+1)
+// applyValues сохраняет значения, собранные плагином: измерители заменяются,
+// приращения счетчиков накапливаются до сброса после отправки.
+func (c *MetricsCollector) applyValues(sink *pluginSink) {
+	sink.values.mu.Lock()
+	defer sink.values.mu.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, metric := range sink.values.gauges {
+		c.metrics[key] = metric
+	}
+
+	for key, metric := range sink.values.counters {
+		delta := *metric.Delta
+		if current, ok := c.counters[key]; ok {
+			delta += *current.Delta
+		}
+		metric.Delta = &delta
+		c.counters[key] = metric
+	}
 }
 
 // Metrics возвращает все собранные метрики.
@@ -149,20 +162,58 @@ func (c *MetricsCollector) Metrics() []models.Metrics {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	metrics := make([]models.Metrics, 0, len(c.metrics))
+	metrics := make([]models.Metrics, 0, len(c.metrics)+len(c.counters))
 	for _, metric := range c.metrics {
 		metrics = append(metrics, metric)
 	}
 
+	for _, metric := range c.counters {
+		delta := *metric.Delta
+		metric.Delta = &delta
+		metrics = append(metrics, metric)
+	}
+
+	for name, histogram := range c.histograms {
+		if value := histogram.Snapshot(); value.Count > 0 {
+			metrics = append(metrics, models.Metrics{ID: name, MType: models.Histogram, Histogram: value})
+		}
+	}
+
+	for name, summary := range c.summaries {
+		if value := summary.Snapshot(); value.Count > 0 {
+			metrics = append(metrics, models.Metrics{ID: name, MType: models.Summary, Summary: value})
+		}
+	}
+
 	return metrics
 }
 
-// PollCountReset сбрасывает счетчик опросов.
+// RegisterHistogram регистрирует гистограмму, наблюдения которой отправляются
+// вместе с остальными метриками под именем name.
+func (c *MetricsCollector) RegisterHistogram(name string, histogram *Histogram) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.histograms[name] = histogram
+}
+
+// RegisterSummary регистрирует сводку, наблюдения которой отправляются
+// вместе с остальными метриками под именем name.
+func (c *MetricsCollector) RegisterSummary(name string, summary *Summary) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.summaries[name] = summary
+}
+
+// PollCountReset сбрасывает счетчик опросов и приращения счетчиков плагинов
+// после отправки метрик.
 func (c *MetricsCollector) PollCountReset() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	c.pollCounter = 0
+	clear(c.counters)
 }
 
 // PollCount возвращает текущее количество опросов.
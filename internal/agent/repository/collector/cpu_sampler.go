@@ -0,0 +1,181 @@
+package collector
+
+import (
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+// DefaultCPUSampleInterval - интервал измерения загрузки CPU по умолчанию.
+const DefaultCPUSampleInterval = time.Second
+
+// CPUSnapshot - загрузка CPU в процентах за последний интервал измерения.
+type CPUSnapshot struct {
+	PerCPU []float64 // загрузка каждого CPU
+	User   float64   // доля времени в пользовательском режиме по всем CPU
+	System float64   // доля времени в режиме ядра по всем CPU
+	IOWait float64   // доля времени ожидания ввода-вывода по всем CPU
+	Steal  float64   // доля времени, отданного гипервизором другим машинам, по всем CPU
+}
+
+// CPUSampler в фоне вычисляет загрузку CPU по разнице последовательных
+// значений cpu.Times, чтобы сбор метрик только читал последний снимок.
+type CPUSampler struct {
+	interval time.Duration
+	times    func() ([]cpu.TimesStat, error) // чтение времен CPU, по умолчанию cpu.Times(true)
+
+	mu       sync.RWMutex
+	previous []cpu.TimesStat // времена CPU предыдущего измерения
+	snapshot *CPUSnapshot    // последний снимок, nil - измерений еще не было
+
+	stateMu sync.Mutex
+	started bool          // фоновое измерение запущено
+	stopped bool          // измеритель остановлен
+	stop    chan struct{} // сигнал остановки
+	done    chan struct{} // закрывается по завершении фонового измерения
+}
+
+// NewCPUSampler создает измеритель загрузки CPU с интервалом interval.
+// Если interval не положителен, используется DefaultCPUSampleInterval.
+func NewCPUSampler(interval time.Duration) *CPUSampler {
+	if interval <= 0 {
+		interval = DefaultCPUSampleInterval
+	}
+
+	return &CPUSampler{
+		interval: interval,
+		times:    func() ([]cpu.TimesStat, error) { return cpu.Times(true) },
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start запускает фоновое измерение. Первый снимок появляется через интервал измерения.
+// Повторные вызовы и вызовы после Stop ничего не делают.
+func (s *CPUSampler) Start() {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	if s.started || s.stopped {
+		return
+	}
+	s.started = true
+
+	s.sample()
+	go s.run()
+}
+
+// Stop останавливает фоновое измерение и ждет его завершения.
+func (s *CPUSampler) Stop() {
+	s.stateMu.Lock()
+	if !s.stopped {
+		s.stopped = true
+		close(s.stop)
+	}
+	started := s.started
+	s.stateMu.Unlock()
+
+	if started {
+		<-s.done
+	}
+}
+
+// Snapshot возвращает последний снимок загрузки CPU.
+// Если измерений еще не было, второй результат равен false.
+func (s *CPUSampler) Snapshot() (CPUSnapshot, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.snapshot == nil {
+		return CPUSnapshot{}, false
+	}
+
+	snapshot := *s.snapshot
+	snapshot.PerCPU = append([]float64(nil), s.snapshot.PerCPU...)
+
+	return snapshot, true
+}
+
+// run периодически измеряет загрузку CPU до остановки.
+func (s *CPUSampler) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.sample()
+		}
+	}
+}
+
+// sample читает времена CPU и обновляет снимок по разнице с предыдущим измерением.
+// При ошибке чтения снимок не меняется.
+func (s *CPUSampler) sample() {
+	current, err := s.times()
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.previous != nil && len(s.previous) == len(current) {
+		s.snapshot = cpuSnapshot(s.previous, current)
+	}
+	s.previous = current
+}
+
+// cpuSnapshot вычисляет загрузку CPU между измерениями previous и current.
+func cpuSnapshot(previous, current []cpu.TimesStat) *CPUSnapshot {
+	snapshot := &CPUSnapshot{PerCPU: make([]float64, len(current))}
+
+	var total, user, system, iowait, steal float64
+	for i := range current {
+		prev, cur := previous[i], current[i]
+
+		delta := cpuTotal(cur) - cpuTotal(prev)
+		idle := (cur.Idle - prev.Idle) + (cur.Iowait - prev.Iowait)
+		snapshot.PerCPU[i] = percent(delta-idle, delta)
+
+		total += delta
+		user += cur.User - prev.User
+		system += cur.System - prev.System
+		iowait += cur.Iowait - prev.Iowait
+		steal += cur.Steal - prev.Steal
+	}
+
+	snapshot.User = percent(user, total)
+	snapshot.System = percent(system, total)
+	snapshot.IOWait = percent(iowait, total)
+	snapshot.Steal = percent(steal, total)
+
+	return snapshot
+}
+
+// cpuTotal возвращает суммарное время CPU. Время гостевых систем уже учтено в User.
+func cpuTotal(t cpu.TimesStat) float64 {
+	return t.User + t.System + t.Idle + t.Nice + t.Iowait + t.Irq + t.Softirq + t.Steal
+}
+
+// percent возвращает долю part от total в процентах в пределах [0, 100].
+func percent(part, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+
+	value := part / total * 100
+	switch {
+	case value < 0:
+		return 0
+	case value > 100:
+		return 100
+	}
+
+	return value
+}
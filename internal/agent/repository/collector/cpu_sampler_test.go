@@ -0,0 +1,131 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+// approxEqual сравнивает проценты с точностью до погрешности вычислений.
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestCPUSnapshot_Deltas(t *testing.T) {
+	previous := []cpu.TimesStat{
+		{User: 10, System: 5, Idle: 80, Iowait: 5},
+		{User: 0, System: 0, Idle: 100},
+	}
+	current := []cpu.TimesStat{
+		{User: 40, System: 15, Idle: 120, Iowait: 15, Steal: 10},
+		{User: 25, System: 0, Idle: 175},
+	}
+
+	snapshot := cpuSnapshot(previous, current)
+
+	// CPU 1: 100 единиц времени, из них простой 40 + ожидание 10.
+	// CPU 2: 100 единиц времени, из них простой 75.
+	wantPerCPU := []float64{50, 25}
+	for i, want := range wantPerCPU {
+		if !approxEqual(snapshot.PerCPU[i], want) {
+			t.Errorf("PerCPU[%d] = %v, want %v", i, snapshot.PerCPU[i], want)
+		}
+	}
+
+	modes := map[string][2]float64{
+		"User":   {snapshot.User, 27.5},
+		"System": {snapshot.System, 5},
+		"IOWait": {snapshot.IOWait, 5},
+		"Steal":  {snapshot.Steal, 5},
+	}
+	for name, values := range modes {
+		if !approxEqual(values[0], values[1]) {
+			t.Errorf("%s = %v, want %v", name, values[0], values[1])
+		}
+	}
+}
+
+func TestCPUSnapshot_NoElapsedTime(t *testing.T) {
+	times := []cpu.TimesStat{{User: 10, Idle: 10}}
+
+	snapshot := cpuSnapshot(times, times)
+	if snapshot.PerCPU[0] != 0 || snapshot.User != 0 {
+		t.Errorf("cpuSnapshot() = %+v, want zero utilization without elapsed time", snapshot)
+	}
+}
+
+func TestCPUSampler_Background(t *testing.T) {
+	var idle float64
+	sampler := NewCPUSampler(10 * time.Millisecond)
+	sampler.times = func() ([]cpu.TimesStat, error) {
+		idle += 3
+		return []cpu.TimesStat{{User: idle / 3, Idle: idle}}, nil
+	}
+
+	if _, ok := sampler.Snapshot(); ok {
+		t.Fatal("Snapshot() before Start() is ready, want no snapshot")
+	}
+
+	sampler.Start()
+	defer sampler.Stop()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		snapshot, ok := sampler.Snapshot()
+		if ok {
+			if !approxEqual(snapshot.PerCPU[0], 25) {
+				t.Errorf("PerCPU[0] = %v, want 25", snapshot.PerCPU[0])
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no snapshot after sampling interval")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCPUSampler_KeepsSnapshotOnError(t *testing.T) {
+	sampler := NewCPUSampler(time.Hour)
+	sampler.times = func() ([]cpu.TimesStat, error) {
+		return []cpu.TimesStat{{User: 1, Idle: 1}}, nil
+	}
+	sampler.sample()
+	sampler.times = func() ([]cpu.TimesStat, error) {
+		return []cpu.TimesStat{{User: 2, Idle: 3}}, nil
+	}
+	sampler.sample()
+
+	sampler.times = func() ([]cpu.TimesStat, error) {
+		return nil, errors.New("times unavailable")
+	}
+	sampler.sample()
+
+	snapshot, ok := sampler.Snapshot()
+	if !ok || !approxEqual(snapshot.User, 100.0/3) {
+		t.Errorf("Snapshot() = %+v, %v, want previous snapshot kept", snapshot, ok)
+	}
+}
+
+func TestCPUSampler_StopWithoutStart(t *testing.T) {
+	sampler := NewCPUSampler(time.Millisecond)
+	sampler.Stop()
+	sampler.Start()
+	sampler.Stop()
+}
+
+func TestSystemPlugin_CollectDoesNotBlock(t *testing.T) {
+	plugin := NewSystemPlugin(0)
+	defer plugin.Close()
+
+	sink := newPluginSink()
+	start := time.Now()
+	plugin.Collect(context.Background(), sink)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Collect() took %s, want it to read the latest CPU snapshot without waiting", elapsed)
+	}
+}
@@ -0,0 +1,223 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+
+	"github.com/Ko4etov/go-metrics/internal/agent/interfaces"
+	"github.com/Ko4etov/go-metrics/internal/models"
+)
+
+// DiskPluginName - имя плагина метрик дисков.
+const DiskPluginName = "disk"
+
+// DiskFilter задает правила выбора файловых систем по шаблонам path.Match,
+// например "/mnt/*" или "ext*". Пустой список включений выбирает все файловые
+// системы, исключения применяются после включений.
+type DiskFilter struct {
+	Mountpoints        []string // включаемые точки монтирования
+	ExcludeMountpoints []string // исключаемые точки монтирования
+	FSTypes            []string // включаемые типы файловых систем
+	ExcludeFSTypes     []string // исключаемые типы файловых систем
+}
+
+// Validate проверяет шаблоны фильтра.
+func (f DiskFilter) Validate() error {
+	for _, patterns := range [][]string{f.Mountpoints, f.ExcludeMountpoints, f.FSTypes, f.ExcludeFSTypes} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Match проверяет, выбирает ли фильтр файловую систему partition.
+func (f DiskFilter) Match(partition disk.PartitionStat) bool {
+	if len(f.Mountpoints) > 0 && !matchAny(f.Mountpoints, partition.Mountpoint) {
+		return false
+	}
+	if len(f.FSTypes) > 0 && !matchAny(f.FSTypes, partition.Fstype) {
+		return false
+	}
+
+	return !matchAny(f.ExcludeMountpoints, partition.Mountpoint) && !matchAny(f.ExcludeFSTypes, partition.Fstype)
+}
+
+// matchAny проверяет, соответствует ли value одному из шаблонов patterns.
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseDiskPatterns разбирает список шаблонов через запятую.
+func ParseDiskPatterns(s string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(s, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	return patterns
+}
+
+// DiskPlugin собирает заполненность файловых систем и счетчики ввода-вывода устройств.
+//
+// Для каждой точки монтирования передаются измерители DiskTotal, DiskUsed, DiskFree,
+// DiskUsedPercent, DiskInodesTotal, DiskInodesUsed, DiskInodesFree с метками
+// mountpoint и fstype. Для каждого устройства выбранных файловых систем передаются
+// счетчики DiskReadBytes, DiskWriteBytes, DiskReadOps, DiskWriteOps с меткой device.
+type DiskPlugin struct {
+	interval time.Duration
+	Filter   DiskFilter // правила выбора файловых систем
+
+	partitions    func(ctx context.Context) ([]disk.PartitionStat, error)
+	usage         func(ctx context.Context, path string) (*disk.UsageStat, error)
+	ioCounters    func(ctx context.Context, names ...string) (map[string]disk.IOCountersStat, error)
+	resolveDevice func(path string) (string, error)
+
+	mu       sync.Mutex
+	previous map[string]disk.IOCountersStat // счетчики устройств предыдущего сбора
+}
+
+// NewDiskPlugin создает плагин метрик дисков с интервалом сбора interval.
+func NewDiskPlugin(interval time.Duration) *DiskPlugin {
+	return &DiskPlugin{
+		interval: interval,
+		partitions: func(ctx context.Context) ([]disk.PartitionStat, error) {
+			return disk.PartitionsWithContext(ctx, false)
+		},
+		usage:         disk.UsageWithContext,
+		ioCounters:    disk.IOCountersWithContext,
+		resolveDevice: filepath.EvalSymlinks,
+	}
+}
+
+// Name возвращает имя плагина.
+func (p *DiskPlugin) Name() string {
+	return DiskPluginName
+}
+
+// Interval возвращает интервал сбора метрик.
+func (p *DiskPlugin) Interval() time.Duration {
+	return p.interval
+}
+
+// Collect собирает метрики файловых систем, выбранных фильтром. Счетчики ввода-вывода
+// передаются как приращения с прошлого сбора, при первом сборе они нулевые.
+// Ошибка одной файловой системы не мешает сбору остальных.
+func (p *DiskPlugin) Collect(ctx context.Context, sink interfaces.MetricsSink) error {
+	partitions, err := p.partitions(ctx)
+	if err != nil {
+		return fmt.Errorf("partitions: %w", err)
+	}
+
+	var errs []error
+	mountpoints := make(map[string]bool)
+	devices := make(map[string]bool)
+
+	for _, partition := range partitions {
+		if !p.Filter.Match(partition) || mountpoints[partition.Mountpoint] {
+			continue
+		}
+		mountpoints[partition.Mountpoint] = true
+
+		usage, err := p.usage(ctx, partition.Mountpoint)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("usage of %s: %w", partition.Mountpoint, err))
+			continue
+		}
+
+		mount := sink.WithLabels(models.Labels{"mountpoint": partition.Mountpoint, "fstype": partition.Fstype})
+		mount.Gauge("DiskTotal", float64(usage.Total))
+		mount.Gauge("DiskUsed", float64(usage.Used))
+		mount.Gauge("DiskFree", float64(usage.Free))
+		mount.Gauge("DiskUsedPercent", usage.UsedPercent)
+		mount.Gauge("DiskInodesTotal", float64(usage.InodesTotal))
+		mount.Gauge("DiskInodesUsed", float64(usage.InodesUsed))
+		mount.Gauge("DiskInodesFree", float64(usage.InodesFree))
+
+		if strings.HasPrefix(partition.Device, "/dev/") {
+			devices[p.deviceName(partition.Device)] = true
+		}
+	}
+
+	if len(devices) > 0 {
+		if err := p.collectIO(ctx, sink, devices); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// deviceName возвращает имя устройства, под которым ядро сообщает его счетчики
+// ввода-вывода. Пути вида /dev/mapper/vg-root являются ссылками на /dev/dm-N,
+// поэтому имя берется из пути после раскрытия ссылок.
+func (p *DiskPlugin) deviceName(device string) string {
+	if resolved, err := p.resolveDevice(device); err == nil {
+		device = resolved
+	}
+
+	return filepath.Base(device)
+}
+
+// collectIO передает приращения счетчиков ввода-вывода устройств devices.
+func (p *DiskPlugin) collectIO(ctx context.Context, sink interfaces.MetricsSink, devices map[string]bool) error {
+	names := make([]string, 0, len(devices))
+	for name := range devices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	counters, err := p.ioCounters(ctx, names...)
+	if err != nil {
+		return fmt.Errorf("io counters: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for name, current := range counters {
+		// Для нового устройства передаются нулевые приращения, чтобы ряды появились сразу.
+		previous, ok := p.previous[name]
+		if !ok {
+			previous = current
+		}
+
+		device := sink.WithLabels(models.Labels{"device": name})
+		device.Counter("DiskReadBytes", counterDelta(previous.ReadBytes, current.ReadBytes))
+		device.Counter("DiskWriteBytes", counterDelta(previous.WriteBytes, current.WriteBytes))
+		device.Counter("DiskReadOps", counterDelta(previous.ReadCount, current.ReadCount))
+		device.Counter("DiskWriteOps", counterDelta(previous.WriteCount, current.WriteCount))
+	}
+	p.previous = counters
+
+	return nil
+}
+
+// counterDelta возвращает приращение счетчика; при сбросе счетчика возвращает 0.
+func counterDelta(previous, current uint64) int64 {
+	if current < previous {
+		return 0
+	}
+
+	return int64(current - previous)
+}
@@ -0,0 +1,197 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shirou/gopsutil/v3/disk"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+)
+
+// newTestDiskPlugin создает плагин дисков с заданными файловыми системами и счетчиками.
+func newTestDiskPlugin(partitions []disk.PartitionStat, counters *map[string]disk.IOCountersStat) *DiskPlugin {
+	p := NewDiskPlugin(0)
+	p.partitions = func(context.Context) ([]disk.PartitionStat, error) {
+		return partitions, nil
+	}
+	p.usage = func(_ context.Context, path string) (*disk.UsageStat, error) {
+		if path == "/broken" {
+			return nil, errors.New("permission denied")
+		}
+		return &disk.UsageStat{Path: path, Total: 100, Used: 90, Free: 10, UsedPercent: 90, InodesTotal: 50, InodesUsed: 5, InodesFree: 45}, nil
+	}
+	p.ioCounters = func(_ context.Context, names ...string) (map[string]disk.IOCountersStat, error) {
+		result := make(map[string]disk.IOCountersStat)
+		for _, name := range names {
+			if stat, ok := (*counters)[name]; ok {
+				result[name] = stat
+			}
+		}
+		return result, nil
+	}
+	p.resolveDevice = func(path string) (string, error) {
+		return path, nil
+	}
+
+	return p
+}
+
+// sinkMetric возвращает метрику сбора плагина по имени и меткам.
+func sinkMetric(t *testing.T, sink *pluginSink, mType, id string, labels models.Labels) (models.Metrics, bool) {
+	t.Helper()
+
+	values := sink.values.gauges
+	if mType == models.Counter {
+		values = sink.values.counters
+	}
+
+	metric, ok := values[models.MetricKey(id, labels)]
+	return metric, ok
+}
+
+var testPartitions = []disk.PartitionStat{
+	{Device: "/dev/sda1", Mountpoint: "/", Fstype: "ext4"},
+	{Device: "/dev/sdb1", Mountpoint: "/data", Fstype: "xfs"},
+	{Device: "/dev/loop0", Mountpoint: "/snap/core/1", Fstype: "squashfs"},
+	{Device: "/dev/sdc1", Mountpoint: "/broken", Fstype: "ext4"},
+}
+
+func TestDiskPlugin_Usage(t *testing.T) {
+	counters := map[string]disk.IOCountersStat{}
+	p := newTestDiskPlugin(testPartitions, &counters)
+	p.Filter = DiskFilter{ExcludeFSTypes: []string{"squashfs"}}
+
+	sink := newPluginSink()
+	err := p.Collect(context.Background(), sink)
+	if err == nil {
+		t.Error("Collect() error = nil, want error for unreadable mountpoint")
+	}
+
+	root := models.Labels{"mountpoint": "/", "fstype": "ext4"}
+	used, ok := sinkMetric(t, sink, models.Gauge, "DiskUsed", root)
+	if !ok || *used.Value != 90 {
+		t.Errorf("DiskUsed%s = %v, want 90", root, used.Value)
+	}
+	if free, ok := sinkMetric(t, sink, models.Gauge, "DiskInodesFree", root); !ok || *free.Value != 45 {
+		t.Errorf("DiskInodesFree%s = %v, want 45", root, free.Value)
+	}
+	if _, ok := sinkMetric(t, sink, models.Gauge, "DiskUsed", models.Labels{"mountpoint": "/data", "fstype": "xfs"}); !ok {
+		t.Error("metrics of /data are missing, want them collected despite the broken mountpoint")
+	}
+	if _, ok := sinkMetric(t, sink, models.Gauge, "DiskUsed", models.Labels{"mountpoint": "/snap/core/1", "fstype": "squashfs"}); ok {
+		t.Error("metrics of excluded filesystem type are present")
+	}
+}
+
+func TestDiskPlugin_IOCounters(t *testing.T) {
+	counters := map[string]disk.IOCountersStat{
+		"sda1": {Name: "sda1", ReadBytes: 1000, WriteBytes: 2000, ReadCount: 10, WriteCount: 20},
+		"sdb1": {Name: "sdb1", ReadBytes: 500},
+	}
+	p := newTestDiskPlugin(testPartitions[:2], &counters)
+	p.Filter = DiskFilter{Mountpoints: []string{"/"}}
+
+	first := newPluginSink()
+	if err := p.Collect(context.Background(), first); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	for key, metric := range first.values.counters {
+		if *metric.Delta != 0 {
+			t.Errorf("first Collect() %s = %d, want zero delta without a baseline", key, *metric.Delta)
+		}
+	}
+	if len(first.values.counters) != 4 {
+		t.Errorf("first Collect() reported %d counters, want 4 for the selected device", len(first.values.counters))
+	}
+
+	counters["sda1"] = disk.IOCountersStat{Name: "sda1", ReadBytes: 1500, WriteBytes: 2100, ReadCount: 15, WriteCount: 21}
+	counters["sdb1"] = disk.IOCountersStat{Name: "sdb1", ReadBytes: 900}
+
+	second := newPluginSink()
+	if err := p.Collect(context.Background(), second); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	device := models.Labels{"device": "sda1"}
+	want := map[string]int64{"DiskReadBytes": 500, "DiskWriteBytes": 100, "DiskReadOps": 5, "DiskWriteOps": 1}
+	for id, delta := range want {
+		metric, ok := sinkMetric(t, second, models.Counter, id, device)
+		if !ok || *metric.Delta != delta {
+			t.Errorf("%s%s = %v, want %d", id, device, metric.Delta, delta)
+		}
+	}
+
+	if _, ok := sinkMetric(t, second, models.Counter, "DiskReadBytes", models.Labels{"device": "sdb1"}); ok {
+		t.Error("counters of device outside the filter are present")
+	}
+}
+
+func TestDiskPlugin_DeviceMapper(t *testing.T) {
+	counters := map[string]disk.IOCountersStat{
+		"dm-0": {Name: "dm-0", ReadBytes: 1000},
+	}
+	partitions := []disk.PartitionStat{{Device: "/dev/mapper/vg-root", Mountpoint: "/", Fstype: "ext4"}}
+	p := newTestDiskPlugin(partitions, &counters)
+	p.resolveDevice = func(path string) (string, error) {
+		if path == "/dev/mapper/vg-root" {
+			return "/dev/dm-0", nil
+		}
+		return "", errors.New("no such file or directory")
+	}
+
+	sink := newPluginSink()
+	if err := p.Collect(context.Background(), sink); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	if _, ok := sinkMetric(t, sink, models.Counter, "DiskReadBytes", models.Labels{"device": "dm-0"}); !ok {
+		t.Error("counters of device-mapper device are missing, want them reported as dm-0")
+	}
+}
+
+func TestDiskFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter DiskFilter
+		want   []string
+	}{
+		{name: "all", filter: DiskFilter{}, want: []string{"/", "/data", "/snap/core/1", "/broken"}},
+		{name: "include mountpoints", filter: DiskFilter{Mountpoints: []string{"/", "/snap/*/*"}}, want: []string{"/", "/snap/core/1"}},
+		{name: "exclude mountpoints", filter: DiskFilter{ExcludeMountpoints: []string{"/snap/*/*", "/broken"}}, want: []string{"/", "/data"}},
+		{name: "include fstypes", filter: DiskFilter{FSTypes: []string{"ext*"}}, want: []string{"/", "/broken"}},
+		{name: "exclude wins", filter: DiskFilter{FSTypes: []string{"ext4"}, ExcludeMountpoints: []string{"/broken"}}, want: []string{"/"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, partition := range testPartitions {
+				if tt.filter.Match(partition) {
+					got = append(got, partition.Mountpoint)
+				}
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("Match() selected %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Match() selected %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	if err := (DiskFilter{Mountpoints: []string{"/data/["}}).Validate(); err == nil {
+		t.Error("Validate() error = nil, want error for malformed pattern")
+	}
+}
+
+func TestParseDiskPatterns(t *testing.T) {
+	got := ParseDiskPatterns(" /, /data/* ,,")
+	if len(got) != 2 || got[0] != "/" || got[1] != "/data/*" {
+		t.Errorf("ParseDiskPatterns() = %q, want [/ /data/*]", got)
+	}
+}
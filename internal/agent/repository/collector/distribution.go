@@ -0,0 +1,185 @@
+package collector
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+)
+
+// DefaultBuckets - границы корзин гистограммы по умолчанию для длительностей в секундах.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefaultObjectives - квантили сводки по умолчанию.
+var DefaultObjectives = []float64{0.5, 0.9, 0.99}
+
+// defaultSummarySamples - размер буфера наблюдений сводки по умолчанию.
+const defaultSummarySamples = 1024
+
+// Histogram накапливает наблюдения по корзинам между отправками метрик.
+type Histogram struct {
+	mu      sync.Mutex // мьютекс для безопасного доступа
+	buckets []float64  // верхние границы корзин
+	counts  []uint64   // количество наблюдений в корзинах, включая +Inf
+	sum     float64    // сумма наблюдений
+	count   uint64     // количество наблюдений
+}
+
+// NewHistogram создает гистограмму с заданными границами корзин.
+// Границы сортируются, повторы и бесконечности отбрасываются.
+// Если границы не заданы, используются DefaultBuckets.
+func NewHistogram(buckets []float64) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	bounds := make([]float64, 0, len(buckets))
+	for _, bound := range buckets {
+		if !math.IsNaN(bound) && !math.IsInf(bound, 0) {
+			bounds = append(bounds, bound)
+		}
+	}
+	sort.Float64s(bounds)
+	bounds = slices.Compact(bounds)
+
+	return &Histogram{
+		buckets: bounds,
+		counts:  make([]uint64, len(bounds)+1),
+	}
+}
+
+// Observe добавляет наблюдение в гистограмму.
+func (h *Histogram) Observe(value float64) {
+	if math.IsNaN(value) {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.counts[sort.SearchFloat64s(h.buckets, value)]++
+	h.sum += value
+	h.count++
+}
+
+// ObserveDuration добавляет в гистограмму время, прошедшее с start, в секундах.
+func (h *Histogram) ObserveDuration(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+// Snapshot возвращает наблюдения, накопленные с прошлого вызова, и сбрасывает их.
+func (h *Histogram) Snapshot() *models.HistogramValue {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	value := &models.HistogramValue{
+		Buckets: slices.Clone(h.buckets),
+		Counts:  h.counts,
+		Sum:     h.sum,
+		Count:   h.count,
+	}
+
+	h.counts = make([]uint64, len(h.buckets)+1)
+	h.sum = 0
+	h.count = 0
+
+	return value
+}
+
+// Summary накапливает наблюдения для расчета квантилей между отправками метрик.
+//
+// Хранится не более maxSamples последних наблюдений, квантили рассчитываются
+// по ним при вызове Snapshot. Сумма и количество учитывают все наблюдения.
+type Summary struct {
+	mu         sync.Mutex // мьютекс для безопасного доступа
+	objectives []float64  // рассчитываемые квантили
+	samples    []float64  // кольцевой буфер наблюдений
+	next       int        // позиция следующей записи в буфере
+	maxSamples int        // размер буфера наблюдений
+	sum        float64    // сумма наблюдений
+	count      uint64     // количество наблюдений
+}
+
+// NewSummary создает сводку с заданными квантилями и размером буфера наблюдений.
+// Квантили вне интервала [0, 1] отбрасываются. Если квантили не заданы,
+// используются DefaultObjectives, если размер буфера не положителен - 1024.
+func NewSummary(objectives []float64, maxSamples int) *Summary {
+	if len(objectives) == 0 {
+		objectives = DefaultObjectives
+	}
+	if maxSamples <= 0 {
+		maxSamples = defaultSummarySamples
+	}
+
+	quantiles := make([]float64, 0, len(objectives))
+	for _, q := range objectives {
+		if q >= 0 && q <= 1 {
+			quantiles = append(quantiles, q)
+		}
+	}
+	sort.Float64s(quantiles)
+
+	return &Summary{
+		objectives: slices.Compact(quantiles),
+		samples:    make([]float64, 0, maxSamples),
+		maxSamples: maxSamples,
+	}
+}
+
+// Observe добавляет наблюдение в сводку.
+func (s *Summary) Observe(value float64) {
+	if math.IsNaN(value) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.samples) < s.maxSamples {
+		s.samples = append(s.samples, value)
+	} else {
+		s.samples[s.next] = value
+		s.next = (s.next + 1) % s.maxSamples
+	}
+	s.sum += value
+	s.count++
+}
+
+// ObserveDuration добавляет в сводку время, прошедшее с start, в секундах.
+func (s *Summary) ObserveDuration(start time.Time) {
+	s.Observe(time.Since(start).Seconds())
+}
+
+// Snapshot рассчитывает квантили по наблюдениям, накопленным с прошлого вызова,
+// и сбрасывает их.
+func (s *Summary) Snapshot() *models.SummaryValue {
+	s.mu.Lock()
+	samples := s.samples
+	value := &models.SummaryValue{Sum: s.sum, Count: s.count}
+
+	s.samples = make([]float64, 0, s.maxSamples)
+	s.next = 0
+	s.sum = 0
+	s.count = 0
+	s.mu.Unlock()
+
+	if len(samples) == 0 {
+		return value
+	}
+
+	sort.Float64s(samples)
+
+	value.Quantiles = make([]models.Quantile, 0, len(s.objectives))
+	for _, q := range s.objectives {
+		index := int(math.Ceil(q*float64(len(samples)))) - 1
+		if index < 0 {
+			index = 0
+		}
+		value.Quantiles = append(value.Quantiles, models.Quantile{Quantile: q, Value: samples[index]})
+	}
+
+	return value
+}
@@ -0,0 +1,87 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+)
+
+func TestHistogram_ObserveAndSnapshot(t *testing.T) {
+	histogram := NewHistogram([]float64{1, 0.1, 1})
+
+	for _, value := range []float64{0.05, 0.1, 0.5, 2} {
+		histogram.Observe(value)
+	}
+
+	value := histogram.Snapshot()
+	if err := value.Validate(); err != nil {
+		t.Fatalf("snapshot is invalid: %v", err)
+	}
+	if len(value.Buckets) != 2 || value.Buckets[0] != 0.1 {
+		t.Errorf("expected sorted unique buckets [0.1 1], got %v", value.Buckets)
+	}
+	if value.Counts[0] != 2 || value.Counts[1] != 1 || value.Counts[2] != 1 {
+		t.Errorf("unexpected bucket counts %v", value.Counts)
+	}
+	if value.Count != 4 || value.Sum != 2.65 {
+		t.Errorf("expected count 4 and sum 2.65, got %d and %v", value.Count, value.Sum)
+	}
+
+	if next := histogram.Snapshot(); next.Count != 0 || next.Counts[0] != 0 {
+		t.Errorf("expected snapshot to reset observations, got %+v", next)
+	}
+}
+
+func TestSummary_ObserveAndSnapshot(t *testing.T) {
+	summary := NewSummary([]float64{0.9, 0.5}, 10)
+
+	for i := 1; i <= 20; i++ {
+		summary.Observe(float64(i))
+	}
+
+	value := summary.Snapshot()
+	if err := value.Validate(); err != nil {
+		t.Fatalf("snapshot is invalid: %v", err)
+	}
+	if value.Count != 20 || value.Sum != 210 {
+		t.Errorf("expected count 20 and sum 210, got %d and %v", value.Count, value.Sum)
+	}
+	if len(value.Quantiles) != 2 || value.Quantiles[0].Value != 15 || value.Quantiles[1].Value != 19 {
+		t.Errorf("expected quantiles over the last 10 samples, got %v", value.Quantiles)
+	}
+
+	if next := summary.Snapshot(); next.Count != 0 || len(next.Quantiles) != 0 {
+		t.Errorf("expected snapshot to reset observations, got %+v", next)
+	}
+}
+
+func TestMetrics_RegisteredDistributions(t *testing.T) {
+	collector := New()
+	histogram := NewHistogram(nil)
+	summary := NewSummary(nil, 0)
+	collector.RegisterHistogram("Latency", histogram)
+	collector.RegisterSummary("LatencySummary", summary)
+
+	for _, metric := range collector.Metrics() {
+		if metric.MType == models.Histogram || metric.MType == models.Summary {
+			t.Errorf("expected empty distributions to be skipped, got %s", metric.ID)
+		}
+	}
+
+	histogram.Observe(0.3)
+	summary.Observe(0.3)
+
+	found := map[string]bool{}
+	for _, metric := range collector.Metrics() {
+		switch metric.MType {
+		case models.Histogram:
+			found[metric.ID] = metric.Histogram != nil && metric.Histogram.Count == 1
+		case models.Summary:
+			found[metric.ID] = metric.Summary != nil && metric.Summary.Count == 1
+		}
+	}
+
+	if !found["Latency"] || !found["LatencySummary"] {
+		t.Errorf("expected both distributions to be reported, got %v", found)
+	}
+}
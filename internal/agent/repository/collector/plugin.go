@@ -0,0 +1,164 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ko4etov/go-metrics/internal/agent/interfaces"
+	"github.com/Ko4etov/go-metrics/internal/models"
+)
+
+// DefaultTimeout - время на сбор метрик одним плагином по умолчанию.
+const DefaultTimeout = 5 * time.Second
+
+// DefaultPlugins - плагины, включенные по умолчанию.
+const DefaultPlugins = "runtime,system,disk"
+
+// Factory создает плагин сбора метрик с интервалом interval.
+type Factory func(interval time.Duration) interfaces.CollectorPlugin
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{
+		RuntimePluginName: func(interval time.Duration) interfaces.CollectorPlugin { return NewRuntimePlugin(interval) },
+		SystemPluginName:  func(interval time.Duration) interfaces.CollectorPlugin { return NewSystemPlugin(interval) },
+		DiskPluginName:    func(interval time.Duration) interfaces.CollectorPlugin { return NewDiskPlugin(interval) },
+	}
+)
+
+// Register добавляет плагин name в реестр, после чего его можно включить в конфигурации.
+// Повторная регистрация имени заменяет плагин.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registry[name] = factory
+}
+
+// Available возвращает отсортированные имена зарегистрированных плагинов.
+func Available() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	return availableLocked()
+}
+
+// availableLocked возвращает имена плагинов; вызывается под registryMu.
+func availableLocked() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ParsePlugins создает плагины по списку spec вида "runtime,system:10s": имена плагинов
+// через запятую, после двоеточия можно указать интервал сбора плагина.
+// Плагины, не указанные в списке, отключены.
+func ParsePlugins(spec string) ([]interfaces.CollectorPlugin, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	plugins := make([]interfaces.CollectorPlugin, 0)
+	seen := make(map[string]bool)
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, intervalText, hasInterval := strings.Cut(item, ":")
+
+		factory, ok := registry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown collector %q: available collectors are %s", name, strings.Join(availableLocked(), ", "))
+		}
+
+		if seen[name] {
+			return nil, fmt.Errorf("collector %q is listed more than once", name)
+		}
+		seen[name] = true
+
+		var interval time.Duration
+		if hasInterval {
+			var err error
+			interval, err = time.ParseDuration(intervalText)
+			if err != nil || interval < 0 {
+				return nil, fmt.Errorf("invalid interval %q for collector %q", intervalText, name)
+			}
+		}
+
+		plugins = append(plugins, factory(interval))
+	}
+
+	return plugins, nil
+}
+
+// scheduledPlugin - подключенный плагин и состояние его расписания.
+type scheduledPlugin struct {
+	plugin  interfaces.CollectorPlugin
+	lastRun time.Time // время начала последнего сбора
+	running bool      // предыдущий сбор еще не завершен
+}
+
+// sinkValues - значения одного сбора плагина по ключам рядов.
+type sinkValues struct {
+	mu       sync.Mutex
+	gauges   map[string]models.Metrics
+	counters map[string]models.Metrics
+}
+
+// pluginSink накапливает значения одного сбора плагина.
+type pluginSink struct {
+	values *sinkValues
+	labels models.Labels // метки, добавляемые к метрикам
+}
+
+func newPluginSink() *pluginSink {
+	return &pluginSink{values: &sinkValues{
+		gauges:   make(map[string]models.Metrics),
+		counters: make(map[string]models.Metrics),
+	}}
+}
+
+// Gauge запоминает значение измерителя name.
+func (s *pluginSink) Gauge(name string, value float64) {
+	s.values.mu.Lock()
+	defer s.values.mu.Unlock()
+
+	s.values.gauges[models.MetricKey(name, s.labels)] = models.Metrics{
+		ID:     name,
+		MType:  models.Gauge,
+		Value:  &value,
+		Labels: s.labels,
+	}
+}
+
+// Counter добавляет приращение счетчика name.
+func (s *pluginSink) Counter(name string, delta int64) {
+	s.values.mu.Lock()
+	defer s.values.mu.Unlock()
+
+	key := models.MetricKey(name, s.labels)
+	if metric, ok := s.values.counters[key]; ok {
+		delta += *metric.Delta
+	}
+
+	s.values.counters[key] = models.Metrics{
+		ID:     name,
+		MType:  models.Counter,
+		Delta:  &delta,
+		Labels: s.labels,
+	}
+}
+
+// WithLabels возвращает приемник, добавляющий метки labels к метрикам.
+func (s *pluginSink) WithLabels(labels models.Labels) interfaces.MetricsSink {
+	return &pluginSink{values: s.values, labels: s.labels.Merge(labels)}
+}
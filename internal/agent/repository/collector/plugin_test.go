@@ -0,0 +1,198 @@
+package collector
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Ko4etov/go-metrics/internal/agent/interfaces"
+	"github.com/Ko4etov/go-metrics/internal/models"
+)
+
+// testPlugin - плагин с настраиваемым поведением для тестов.
+type testPlugin struct {
+	name     string
+	interval time.Duration
+	delay    time.Duration // время сбора метрик
+	calls    atomic.Int32  // количество запусков
+}
+
+func (p *testPlugin) Name() string            { return p.name }
+func (p *testPlugin) Interval() time.Duration { return p.interval }
+
+func (p *testPlugin) Collect(_ context.Context, sink interfaces.MetricsSink) error {
+	p.calls.Add(1)
+	time.Sleep(p.delay)
+	sink.Gauge(p.name, float64(p.calls.Load()))
+	return nil
+}
+
+// gauges возвращает значения измерителей сборщика по именам.
+func gauges(c *MetricsCollector) map[string]float64 {
+	result := make(map[string]float64)
+	for _, m := range c.Metrics() {
+		if m.Value != nil {
+			result[m.ID] = *m.Value
+		}
+	}
+	return result
+}
+
+func TestCollect_SlowPluginDoesNotStallOthers(t *testing.T) {
+	fast := &testPlugin{name: "fast"}
+	slow := &testPlugin{name: "slow", delay: 300 * time.Millisecond}
+	c := NewWithPlugins(50*time.Millisecond, fast, slow)
+
+	start := time.Now()
+	c.Collect()
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("Collect() took %s, want it bounded by the plugin timeout", elapsed)
+	}
+
+	values := gauges(c)
+	if _, ok := values["fast"]; !ok {
+		t.Error("fast plugin metrics are missing")
+	}
+	if _, ok := values["slow"]; ok {
+		t.Error("slow plugin metrics are present, want them discarded after timeout")
+	}
+
+	c.Collect()
+	if calls := slow.calls.Load(); calls != 1 {
+		t.Errorf("slow plugin started %d times, want it skipped while still running", calls)
+	}
+	if calls := fast.calls.Load(); calls != 2 {
+		t.Errorf("fast plugin started %d times, want 2", calls)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	c.Collect()
+	if calls := slow.calls.Load(); calls != 2 {
+		t.Errorf("slow plugin started %d times after finishing, want 2", calls)
+	}
+}
+
+func TestCollect_PluginInterval(t *testing.T) {
+	every := &testPlugin{name: "every"}
+	rare := &testPlugin{name: "rare", interval: time.Hour}
+	c := NewWithPlugins(time.Second, every, rare)
+
+	for i := 0; i < 3; i++ {
+		c.Collect()
+	}
+
+	if calls := every.calls.Load(); calls != 3 {
+		t.Errorf("plugin without interval started %d times, want 3", calls)
+	}
+	if calls := rare.calls.Load(); calls != 1 {
+		t.Errorf("plugin with interval started %d times, want 1", calls)
+	}
+	if _, ok := gauges(c)["rare"]; !ok {
+		t.Error("metrics of plugin with interval are missing")
+	}
+}
+
+// counterPlugin передает приращение счетчика с метками при каждом сборе.
+type counterPlugin struct{}
+
+func (counterPlugin) Name() string            { return "counter" }
+func (counterPlugin) Interval() time.Duration { return 0 }
+
+func (counterPlugin) Collect(_ context.Context, sink interfaces.MetricsSink) error {
+	sink.WithLabels(models.Labels{"device": "sda"}).Counter("Ops", 2)
+	return nil
+}
+
+func TestCollect_PluginCountersAccumulateUntilReset(t *testing.T) {
+	c := NewWithPlugins(time.Second, counterPlugin{})
+
+	c.Collect()
+	c.Collect()
+
+	opsDelta := func() (int64, bool) {
+		for _, m := range c.Metrics() {
+			if m.ID == "Ops" {
+				if m.MType != models.Counter || m.Labels["device"] != "sda" {
+					t.Errorf("Ops = %+v, want counter labeled with device", m)
+				}
+				return *m.Delta, true
+			}
+		}
+		return 0, false
+	}
+
+	if delta, ok := opsDelta(); !ok || delta != 4 {
+		t.Errorf("Ops delta = %d, want 4 accumulated over two collects", delta)
+	}
+
+	c.PollCountReset()
+	if _, ok := opsDelta(); ok {
+		t.Error("Ops is reported after reset, want it dropped until the next collect")
+	}
+
+	c.Collect()
+	if delta, _ := opsDelta(); delta != 2 {
+		t.Errorf("Ops delta after reset = %d, want 2", delta)
+	}
+}
+
+func TestParsePlugins(t *testing.T) {
+	plugins, err := ParsePlugins(" system:30s ")
+	if err != nil {
+		t.Fatalf("ParsePlugins() error = %v", err)
+	}
+
+	if len(plugins) != 1 || plugins[0].Name() != SystemPluginName {
+		t.Fatalf("ParsePlugins() = %v, want only the system plugin", plugins)
+	}
+	if plugins[0].Interval() != 30*time.Second {
+		t.Errorf("Interval() = %s, want 30s", plugins[0].Interval())
+	}
+
+	c := NewWithPlugins(time.Second, plugins...)
+	c.Collect()
+	if _, ok := gauges(c)["Alloc"]; ok {
+		t.Error("runtime metrics are present, want the runtime plugin disabled")
+	}
+
+	plugins, err = ParsePlugins("")
+	if err != nil || len(plugins) != 0 {
+		t.Errorf("ParsePlugins(\"\") = %v, %v, want no plugins", plugins, err)
+	}
+}
+
+func TestParsePlugins_Errors(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{spec: "runtime,gpu", want: `unknown collector "gpu"`},
+		{spec: "runtime,runtime", want: "listed more than once"},
+		{spec: "runtime:often", want: `invalid interval "often"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := ParsePlugins(tt.spec)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParsePlugins(%q) error = %v, want it to contain %q", tt.spec, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("test", func(interval time.Duration) interfaces.CollectorPlugin {
+		return &testPlugin{name: "test", interval: interval}
+	})
+
+	plugins, err := ParsePlugins("test:1m")
+	if err != nil {
+		t.Fatalf("ParsePlugins() error = %v", err)
+	}
+	if len(plugins) != 1 || plugins[0].Interval() != time.Minute {
+		t.Errorf("ParsePlugins() = %v, want registered plugin with interval 1m", plugins)
+	}
+}
@@ -0,0 +1,71 @@
+package collector
+
+import (
+	"context"
+	"math/rand"
+	"runtime"
+	"time"
+
+	"github.com/Ko4etov/go-metrics/internal/agent/interfaces"
+)
+
+// RuntimePluginName - имя плагина метрик среды выполнения Go.
+const RuntimePluginName = "runtime"
+
+// RuntimePlugin собирает метрики среды выполнения Go и случайное значение RandomValue.
+type RuntimePlugin struct {
+	interval time.Duration
+}
+
+// NewRuntimePlugin создает плагин метрик среды выполнения с интервалом сбора interval.
+func NewRuntimePlugin(interval time.Duration) *RuntimePlugin {
+	return &RuntimePlugin{interval: interval}
+}
+
+// Name возвращает имя плагина.
+func (p *RuntimePlugin) Name() string {
+	return RuntimePluginName
+}
+
+// Interval возвращает интервал сбора метрик.
+func (p *RuntimePlugin) Interval() time.Duration {
+	return p.interval
+}
+
+// Collect собирает метрики runtime.MemStats.
+func (p *RuntimePlugin) Collect(_ context.Context, sink interfaces.MetricsSink) error {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	sink.Gauge("Alloc", float64(stats.Alloc))
+	sink.Gauge("BuckHashSys", float64(stats.BuckHashSys))
+	sink.Gauge("Frees", float64(stats.Frees))
+	sink.Gauge("GCCPUFraction", stats.GCCPUFraction)
+	sink.Gauge("GCSys", float64(stats.GCSys))
+	sink.Gauge("HeapAlloc", float64(stats.HeapAlloc))
+	sink.Gauge("HeapIdle", float64(stats.HeapIdle))
+	sink.Gauge("HeapInuse", float64(stats.HeapInuse))
+	sink.Gauge("HeapObjects", float64(stats.HeapObjects))
+	sink.Gauge("HeapReleased", float64(stats.HeapReleased))
+	sink.Gauge("HeapSys", float64(stats.HeapSys))
+	sink.Gauge("LastGC", float64(stats.LastGC))
+	sink.Gauge("Lookups", float64(stats.Lookups))
+	sink.Gauge("MCacheInuse", float64(stats.MCacheInuse))
+	sink.Gauge("MCacheSys", float64(stats.MCacheSys))
+	sink.Gauge("MSpanInuse", float64(stats.MSpanInuse))
+	sink.Gauge("MSpanSys", float64(stats.MSpanSys))
+	sink.Gauge("Mallocs", float64(stats.Mallocs))
+	sink.Gauge("NextGC", float64(stats.NextGC))
+	sink.Gauge("NumForcedGC", float64(stats.NumForcedGC))
+	sink.Gauge("NumGC", float64(stats.NumGC))
+	sink.Gauge("OtherSys", float64(stats.OtherSys))
+	sink.Gauge("PauseTotalNs", float64(stats.PauseTotalNs))
+	sink.Gauge("StackInuse", float64(stats.StackInuse))
+	sink.Gauge("StackSys", float64(stats.StackSys))
+	sink.Gauge("Sys", float64(stats.Sys))
+	sink.Gauge("TotalAlloc", float64(stats.TotalAlloc))
+
+	sink.Gauge("RandomValue", rand.Float64()*100)
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/mem"
+
+	"github.com/Ko4etov/go-metrics/internal/agent/interfaces"
+)
+
+// SystemPluginName - имя плагина системных метрик.
+const SystemPluginName = "system"
+
+// SystemPlugin собирает метрики памяти и загрузки CPU через gopsutil.
+// Загрузка CPU измеряется в фоне, сбор метрик читает последний снимок.
+type SystemPlugin struct {
+	interval time.Duration
+	sampler  *CPUSampler
+}
+
+// NewSystemPlugin создает плагин системных метрик с интервалом сбора interval.
+func NewSystemPlugin(interval time.Duration) *SystemPlugin {
+	return &SystemPlugin{
+		interval: interval,
+		sampler:  NewCPUSampler(DefaultCPUSampleInterval),
+	}
+}
+
+// Name возвращает имя плагина.
+func (p *SystemPlugin) Name() string {
+	return SystemPluginName
+}
+
+// Interval возвращает интервал сбора метрик.
+func (p *SystemPlugin) Interval() time.Duration {
+	return p.interval
+}
+
+// Collect собирает метрики памяти и загрузки CPU. При первом вызове запускается
+// фоновое измерение CPU, поэтому метрики CPU появляются со следующих сборов.
+func (p *SystemPlugin) Collect(ctx context.Context, sink interfaces.MetricsSink) error {
+	p.sampler.Start()
+
+	memStats, err := mem.VirtualMemoryWithContext(ctx)
+	if err == nil {
+		sink.Gauge("TotalMemory", float64(memStats.Total))
+		sink.Gauge("FreeMemory", float64(memStats.Free))
+	}
+
+	if snapshot, ok := p.sampler.Snapshot(); ok {
+		for i, percent := range snapshot.PerCPU {
+			sink.Gauge(formatCPUutilization(i), percent)
+		}
+
+		sink.Gauge("CPUutilizationUser", snapshot.User)
+		sink.Gauge("CPUutilizationSystem", snapshot.System)
+		sink.Gauge("CPUutilizationIOWait", snapshot.IOWait)
+		sink.Gauge("CPUutilizationSteal", snapshot.Steal)
+	}
+
+	if err != nil {
+		return fmt.Errorf("memory: %w", err)
+	}
+
+	return nil
+}
+
+// Close останавливает фоновое измерение загрузки CPU.
+func (p *SystemPlugin) Close() error {
+	p.sampler.Stop()
+	return nil
+}
+
+// formatCPUutilization форматирует имя метрики загрузки CPU.
+func formatCPUutilization(index int) string {
+	return "CPUutilization" + strconv.Itoa(index+1)
+}
@@ -0,0 +1,451 @@
+// Package spool реализует ограниченную очередь неотправленных батчей метрик на диске.
+//
+// Каждый батч хранится в отдельном файле-сегменте с контрольной суммой.
+// Сегменты упорядочены по номеру и воспроизводятся в порядке записи.
+// При превышении лимита сегментов очередь сжимается: батчи объединяются в один сегмент,
+// значения измерителей заменяются последними, а приращения счетчиков суммируются,
+// поэтому счетчики не теряются даже при длительной недоступности сервера.
+// Батчи, которые уже отправлялись, не объединяются: сервер мог их применить,
+// и только исходный идентификатор защищает от повторного применения.
+package spool
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+)
+
+const (
+	segmentExt    = ".seg"    // расширение файлов сегментов
+	tempExt       = ".tmp"    // расширение временных файлов при записи
+	segmentMagic  = "MSP1"    // сигнатура формата сегмента
+	headerSize    = 4 + 4 + 8 // сигнатура, CRC32 и номер первого объединенного сегмента
+	defaultMaxLen = 100       // лимит сегментов по умолчанию
+)
+
+// ErrCorruptSegment возвращается при чтении поврежденного сегмента.
+var ErrCorruptSegment = errors.New("corrupt spool segment")
+
+// Batch - батч метрик с уникальным идентификатором.
+// Идентификатор передается серверу, чтобы повторная отправка не применялась дважды.
+type Batch struct {
+	ID      string           `json:"id"`             // идентификатор батча
+	Metrics []models.Metrics `json:"metrics"`        // метрики батча
+	Sent    bool             `json:"sent,omitempty"` // батч уже отправлялся и мог быть применен сервером
+}
+
+// NewBatch создает батч метрик с новым случайным идентификатором.
+func NewBatch(metrics []models.Metrics) Batch {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	return Batch{ID: hex.EncodeToString(id), Metrics: metrics}
+}
+
+// segment описывает сегмент очереди на диске.
+type segment struct {
+	seq   uint64 // номер сегмента (последний из объединенных)
+	first uint64 // номер первого объединенного сегмента
+	sent  bool   // батч сегмента уже отправлялся
+}
+
+// Spool - очередь неотправленных батчей метрик на диске.
+type Spool struct {
+	dir         string     // директория с сегментами
+	maxSegments int        // максимальное количество сегментов
+	segments    []segment  // сегменты в порядке воспроизведения
+	nextSeq     uint64     // номер следующего сегмента
+	inflight    uint64     // номер сегмента, который сейчас отправляется (0 - нет)
+	mu          sync.Mutex // мьютекс для безопасного доступа
+}
+
+// Open открывает очередь в директории dir, создавая ее при необходимости.
+// Поврежденные сегменты и сегменты, уже вошедшие в объединенные, удаляются.
+func Open(dir string, maxSegments int) (*Spool, error) {
+	if maxSegments < 2 {
+		maxSegments = defaultMaxLen
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	s := &Spool{
+		dir:         dir,
+		maxSegments: maxSegments,
+		nextSeq:     1,
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// load восстанавливает список сегментов из директории.
+func (s *Spool) load() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read spool directory: %w", err)
+	}
+
+	var segments []segment
+
+	for _, entry := range entries {
+		name := entry.Name()
+
+		if strings.HasSuffix(name, tempExt) {
+			os.Remove(filepath.Join(s.dir, name))
+			continue
+		}
+
+		if !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		first, batch, err := s.readSegment(seq)
+		if err != nil {
+			os.Remove(s.segmentPath(seq))
+			continue
+		}
+
+		segments = append(segments, segment{seq: seq, first: first, sent: batch.Sent})
+	}
+
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].seq < segments[j].seq
+	})
+
+	// Сегменты, входящие в диапазон объединенного сегмента, остаются после
+	// прерванного сжатия и уже учтены в нем.
+	for _, seg := range segments {
+		if len(s.segments) > 0 {
+			last := s.segments[len(s.segments)-1]
+			if seg.first <= last.seq {
+				for len(s.segments) > 0 && s.segments[len(s.segments)-1].seq >= seg.first {
+					os.Remove(s.segmentPath(s.segments[len(s.segments)-1].seq))
+					s.segments = s.segments[:len(s.segments)-1]
+				}
+			}
+		}
+		s.segments = append(s.segments, seg)
+	}
+
+	if len(s.segments) > 0 {
+		s.nextSeq = s.segments[len(s.segments)-1].seq + 1
+	}
+
+	return nil
+}
+
+// Len возвращает количество сегментов в очереди.
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.segments)
+}
+
+// Enqueue записывает батч метрик в конец очереди.
+// При превышении лимита сегменты, ожидающие отправки, объединяются в один.
+func (s *Spool) Enqueue(batch Batch) error {
+	if len(batch.Metrics) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seq := s.nextSeq
+	if err := s.writeSegment(seq, seq, batch); err != nil {
+		return err
+	}
+
+	s.nextSeq++
+	s.segments = append(s.segments, segment{seq: seq, first: seq, sent: batch.Sent})
+
+	if len(s.segments) > s.maxSegments {
+		return s.compact()
+	}
+
+	return nil
+}
+
+// Peek возвращает самый старый батч очереди и помечает его как отправляемый.
+// Сегмент остается на диске до вызова Remove. Отметка об отправке сохраняется
+// в сегменте, чтобы батч не объединялся с другими и после перезапуска.
+func (s *Spool) Peek() (uint64, Batch, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.segments) > 0 {
+		seq := s.segments[0].seq
+
+		_, batch, err := s.readSegment(seq)
+		if err != nil {
+			os.Remove(s.segmentPath(seq))
+			s.segments = s.segments[1:]
+			continue
+		}
+
+		if !batch.Sent {
+			batch.Sent = true
+			s.segments[0].sent = true
+			// При ошибке записи отметка остается в памяти до перезапуска.
+			s.writeSegment(seq, s.segments[0].first, batch)
+		}
+
+		s.inflight = seq
+		return seq, batch, true
+	}
+
+	return 0, Batch{}, false
+}
+
+// Remove удаляет отправленный сегмент из очереди.
+func (s *Spool) Remove(seq uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.inflight == seq {
+		s.inflight = 0
+	}
+
+	for i, seg := range s.segments {
+		if seg.seq == seq {
+			s.segments = append(s.segments[:i], s.segments[i+1:]...)
+			break
+		}
+	}
+
+	if err := os.Remove(s.segmentPath(seq)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove spool segment: %w", err)
+	}
+
+	return nil
+}
+
+// Release снимает отметку отправки с сегмента, не удаляя его.
+func (s *Spool) Release(seq uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.inflight == seq {
+		s.inflight = 0
+	}
+}
+
+// compact объединяет подряд идущие сегменты, которые еще не отправлялись.
+// Отправлявшиеся сегменты, включая отправляемый сейчас, сохраняются как есть.
+// Объединенный сегмент записывается под номером последнего сегмента и хранит
+// номер первого, поэтому прерванное сжатие корректно восстанавливается в load.
+// Объединенный батч получает новый идентификатор.
+func (s *Spool) compact() error {
+	compacted := make([]segment, 0, len(s.segments))
+
+	for start := 0; start < len(s.segments); {
+		if s.segments[start].sent || s.segments[start].seq == s.inflight {
+			compacted = append(compacted, s.segments[start])
+			start++
+			continue
+		}
+
+		end := start
+		for end < len(s.segments) && !s.segments[end].sent && s.segments[end].seq != s.inflight {
+			end++
+		}
+
+		merged, err := s.merge(s.segments[start:end])
+		if err != nil {
+			compacted = append(compacted, s.segments[start:]...)
+			s.segments = compacted
+			return err
+		}
+
+		compacted = append(compacted, merged)
+		start = end
+	}
+
+	s.segments = compacted
+
+	return nil
+}
+
+// merge объединяет подряд идущие сегменты run в один.
+func (s *Spool) merge(run []segment) (segment, error) {
+	if len(run) == 1 {
+		return run[0], nil
+	}
+
+	batches := make([][]models.Metrics, 0, len(run))
+	for _, seg := range run {
+		_, batch, err := s.readSegment(seg.seq)
+		if err != nil {
+			continue
+		}
+		batches = append(batches, batch.Metrics)
+	}
+
+	first := run[0].first
+	last := run[len(run)-1].seq
+
+	if err := s.writeSegment(last, first, NewBatch(Merge(batches...))); err != nil {
+		return segment{}, err
+	}
+
+	for _, seg := range run[:len(run)-1] {
+		os.Remove(s.segmentPath(seg.seq))
+	}
+
+	return segment{seq: last, first: first}, nil
+}
+
+// Merge объединяет батчи метрик в порядке их записи:
+// для измерителей сохраняется последнее значение, приращения счетчиков суммируются,
+// наблюдения гистограмм и сводок объединяются. Ряды с разными метками объединяются раздельно.
+func Merge(batches ...[]models.Metrics) []models.Metrics {
+	var merged []models.Metrics
+	index := make(map[string]int)
+
+	for _, batch := range batches {
+		for _, metric := range batch {
+			key := metric.MType + ":" + metric.Key()
+
+			i, ok := index[key]
+			if !ok {
+				index[key] = len(merged)
+				merged = append(merged, copyMetric(metric))
+				continue
+			}
+
+			switch metric.MType {
+			case models.Counter:
+				if metric.Delta != nil {
+					delta := *metric.Delta
+					if merged[i].Delta != nil {
+						delta += *merged[i].Delta
+					}
+					merged[i].Delta = &delta
+				}
+			case models.Histogram:
+				if metric.Histogram != nil {
+					merged[i].Histogram = merged[i].Histogram.Merge(metric.Histogram)
+				}
+			case models.Summary:
+				if metric.Summary != nil {
+					merged[i].Summary = merged[i].Summary.Merge(metric.Summary)
+				}
+			default:
+				merged[i] = copyMetric(metric)
+			}
+		}
+	}
+
+	return merged
+}
+
+// copyMetric копирует метрику вместе со значениями по указателям.
+func copyMetric(metric models.Metrics) models.Metrics {
+	if metric.Delta != nil {
+		delta := *metric.Delta
+		metric.Delta = &delta
+	}
+	if metric.Value != nil {
+		value := *metric.Value
+		metric.Value = &value
+	}
+	metric.Histogram = metric.Histogram.Copy()
+	metric.Summary = metric.Summary.Copy()
+	return metric
+}
+
+// segmentPath возвращает путь к файлу сегмента.
+func (s *Spool) segmentPath(seq uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", seq, segmentExt))
+}
+
+// writeSegment атомарно записывает сегмент через временный файл.
+func (s *Spool) writeSegment(seq, first uint64, batch Batch) error {
+	payload, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("failed to marshal spool segment: %w", err)
+	}
+
+	data := make([]byte, headerSize, headerSize+len(payload))
+	copy(data, segmentMagic)
+	binary.BigEndian.PutUint64(data[8:], first)
+	data = append(data, payload...)
+	binary.BigEndian.PutUint32(data[4:], crc32.ChecksumIEEE(data[8:]))
+
+	path := s.segmentPath(seq)
+	tempPath := path + tempExt
+
+	file, err := os.OpenFile(tempPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create spool segment: %w", err)
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to write spool segment: %w", err)
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to sync spool segment: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to close spool segment: %w", err)
+	}
+
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to commit spool segment: %w", err)
+	}
+
+	return nil
+}
+
+// readSegment читает сегмент и проверяет его контрольную сумму.
+func (s *Spool) readSegment(seq uint64) (uint64, Batch, error) {
+	data, err := os.ReadFile(s.segmentPath(seq))
+	if err != nil {
+		return 0, Batch{}, fmt.Errorf("failed to read spool segment: %w", err)
+	}
+
+	if len(data) < headerSize || string(data[:4]) != segmentMagic {
+		return 0, Batch{}, ErrCorruptSegment
+	}
+
+	if binary.BigEndian.Uint32(data[4:8]) != crc32.ChecksumIEEE(data[8:]) {
+		return 0, Batch{}, ErrCorruptSegment
+	}
+
+	var batch Batch
+	if err := json.Unmarshal(data[headerSize:], &batch); err != nil {
+		return 0, Batch{}, ErrCorruptSegment
+	}
+
+	return binary.BigEndian.Uint64(data[8:16]), batch, nil
+}
@@ -0,0 +1,256 @@
+package spool
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+)
+
+func gauge(id string, value float64) models.Metrics {
+	return models.Metrics{ID: id, MType: models.Gauge, Value: &value}
+}
+
+func counter(id string, delta int64) models.Metrics {
+	return models.Metrics{ID: id, MType: models.Counter, Delta: &delta}
+}
+
+func TestSpool_OrderAndRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	sp, err := Open(dir, 10)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	sp.Enqueue(NewBatch([]models.Metrics{gauge("Alloc", 1)}))
+	sp.Enqueue(NewBatch([]models.Metrics{gauge("Alloc", 2)}))
+
+	// Повторное открытие восстанавливает очередь с диска.
+	sp, err = Open(dir, 10)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+
+	if sp.Len() != 2 {
+		t.Fatalf("Expected 2 segments after restart, got %d", sp.Len())
+	}
+
+	sp.Enqueue(NewBatch([]models.Metrics{gauge("Alloc", 3)}))
+
+	for _, want := range []float64{1, 2, 3} {
+		seq, batch, ok := sp.Peek()
+		if !ok {
+			t.Fatalf("Expected segment with value %v", want)
+		}
+		if *batch.Metrics[0].Value != want {
+			t.Errorf("Expected value %v, got %v", want, *batch.Metrics[0].Value)
+		}
+		if err := sp.Remove(seq); err != nil {
+			t.Fatalf("Remove failed: %v", err)
+		}
+	}
+
+	if _, _, ok := sp.Peek(); ok {
+		t.Error("Expected empty spool")
+	}
+}
+
+func TestSpool_CompactMergesCounters(t *testing.T) {
+	sp, err := Open(t.TempDir(), 3)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	for i := 1; i <= 5; i++ {
+		sp.Enqueue(NewBatch([]models.Metrics{counter("PollCount", 5), gauge("Alloc", float64(i))}))
+	}
+
+	if sp.Len() > 3 {
+		t.Fatalf("Expected spool to stay within limit, got %d segments", sp.Len())
+	}
+
+	var total int64
+	var lastAlloc float64
+
+	for {
+		seq, batch, ok := sp.Peek()
+		if !ok {
+			break
+		}
+		for _, m := range batch.Metrics {
+			switch m.MType {
+			case models.Counter:
+				total += *m.Delta
+			case models.Gauge:
+				lastAlloc = *m.Value
+			}
+		}
+		sp.Remove(seq)
+	}
+
+	if total != 25 {
+		t.Errorf("Expected counter deltas to sum to 25, got %d", total)
+	}
+	if lastAlloc != 5 {
+		t.Errorf("Expected last gauge value 5, got %v", lastAlloc)
+	}
+}
+
+func TestSpool_CompactKeepsInflightSegment(t *testing.T) {
+	sp, err := Open(t.TempDir(), 2)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	sp.Enqueue(NewBatch([]models.Metrics{counter("PollCount", 1)}))
+
+	seq, _, _ := sp.Peek()
+
+	sp.Enqueue(NewBatch([]models.Metrics{counter("PollCount", 2)}))
+	sp.Enqueue(NewBatch([]models.Metrics{counter("PollCount", 3)}))
+
+	if err := sp.Remove(seq); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+
+	_, batch, ok := sp.Peek()
+	if !ok || *batch.Metrics[0].Delta != 5 {
+		t.Errorf("Expected merged delta 5 without inflight segment, got %+v", batch.Metrics)
+	}
+}
+
+func TestSpool_RecoversFromCorruptionAndInterruptedCompaction(t *testing.T) {
+	dir := t.TempDir()
+
+	sp, err := Open(dir, 10)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	sp.Enqueue(NewBatch([]models.Metrics{counter("PollCount", 1)}))
+	sp.Enqueue(NewBatch([]models.Metrics{counter("PollCount", 2)}))
+	sp.Enqueue(NewBatch([]models.Metrics{counter("PollCount", 3)}))
+
+	// Объединенный сегмент 2..3 записан, но исходный сегмент 2 не успел удалиться.
+	if err := sp.writeSegment(3, 2, NewBatch([]models.Metrics{counter("PollCount", 5)})); err != nil {
+		t.Fatalf("writeSegment failed: %v", err)
+	}
+
+	// Поврежденный сегмент должен быть отброшен.
+	corrupt := sp.segmentPath(4)
+	os.WriteFile(corrupt, []byte("MSP1garbage-garbage"), 0644)
+	os.WriteFile(filepath.Join(dir, "00000000000000000005.seg.tmp"), []byte("partial"), 0644)
+
+	sp, err = Open(dir, 10)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+
+	var deltas []int64
+	for {
+		seq, batch, ok := sp.Peek()
+		if !ok {
+			break
+		}
+		deltas = append(deltas, *batch.Metrics[0].Delta)
+		sp.Remove(seq)
+	}
+
+	if len(deltas) != 2 || deltas[0] != 1 || deltas[1] != 5 {
+		t.Errorf("Expected deltas [1 5], got %v", deltas)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("Expected spool directory to be empty, got %d entries", len(entries))
+	}
+}
+
+func TestMerge_KeepsLabelledSeriesApart(t *testing.T) {
+	web1 := counter("PollCount", 1)
+	web1.Labels = models.Labels{"host": "web1"}
+	web2 := counter("PollCount", 2)
+	web2.Labels = models.Labels{"host": "web2"}
+
+	merged := Merge([]models.Metrics{web1, web2}, []models.Metrics{web1})
+
+	if len(merged) != 2 {
+		t.Fatalf("Expected 2 series, got %d", len(merged))
+	}
+	if *merged[0].Delta != 2 || *merged[1].Delta != 2 {
+		t.Errorf("Expected deltas [2 2], got [%d %d]", *merged[0].Delta, *merged[1].Delta)
+	}
+}
+
+func TestMerge_Histograms(t *testing.T) {
+	histogram := func(counts ...uint64) models.Metrics {
+		var total uint64
+		for _, count := range counts {
+			total += count
+		}
+		return models.Metrics{
+			ID:        "PollDuration",
+			MType:     models.Histogram,
+			Histogram: &models.HistogramValue{Buckets: []float64{1}, Counts: counts, Sum: float64(total), Count: total},
+		}
+	}
+
+	first := histogram(1, 0)
+	merged := Merge([]models.Metrics{first}, []models.Metrics{histogram(2, 1)})
+
+	if len(merged) != 1 {
+		t.Fatalf("Expected 1 series, got %d", len(merged))
+	}
+	if h := merged[0].Histogram; h.Count != 4 || h.Counts[0] != 3 || h.Counts[1] != 1 {
+		t.Errorf("Expected merged histogram with counts [3 1], got %+v", h)
+	}
+	if first.Histogram.Count != 1 {
+		t.Error("Merge must not modify source batches")
+	}
+}
+
+func TestSpool_CompactKeepsSentBatches(t *testing.T) {
+	dir := t.TempDir()
+
+	sp, err := Open(dir, 2)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	peeked := NewBatch([]models.Metrics{counter("PollCount", 1)})
+	sp.Enqueue(peeked)
+	sp.Peek()
+
+	// Отметка об отправке переживает перезапуск.
+	sp, err = Open(dir, 2)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+
+	sent := NewBatch([]models.Metrics{counter("PollCount", 2)})
+	sent.Sent = true
+	sp.Enqueue(sent)
+	sp.Enqueue(NewBatch([]models.Metrics{counter("PollCount", 3)}))
+	sp.Enqueue(NewBatch([]models.Metrics{counter("PollCount", 4)}))
+
+	var ids []string
+	var deltas []int64
+	for {
+		seq, batch, ok := sp.Peek()
+		if !ok {
+			break
+		}
+		ids = append(ids, batch.ID)
+		deltas = append(deltas, *batch.Metrics[0].Delta)
+		sp.Remove(seq)
+	}
+
+	if len(ids) != 3 || ids[0] != peeked.ID || ids[1] != sent.ID {
+		t.Fatalf("Expected sent batches to keep their IDs, got %v", ids)
+	}
+	if deltas[0] != 1 || deltas[1] != 2 || deltas[2] != 7 {
+		t.Errorf("Expected deltas [1 2 7], got %v", deltas)
+	}
+}
@@ -0,0 +1,131 @@
+// Package grpcsender предоставляет транспорт отправки батчей метрик по gRPC.
+package grpcsender
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/Ko4etov/go-metrics/api/proto"
+	"github.com/Ko4etov/go-metrics/internal/agent/repository/spool"
+)
+
+const (
+	realIPMetadataKey = "x-real-ip"     // ключ метаданных с IP-адресом агента
+	requestTimeout    = 5 * time.Second // таймаут отправки одного батча
+)
+
+// Transport отправляет батчи метрик на gRPC-сервер.
+type Transport struct {
+	serverAddress string           // адрес gRPC-сервера
+	hashKey       string           // ключ для подписи батчей (опционально)
+	conn          *grpc.ClientConn // соединение с сервером
+	client        pb.MetricsClient // клиент сервиса метрик
+	realIP        string           // адрес исходящего интерфейса агента
+	realIPMu      sync.Mutex       // мьютекс для определения адреса
+}
+
+// New создает транспорт для gRPC-сервера по адресу serverAddress.
+// Соединение устанавливается при первой отправке.
+func New(serverAddress string, hashKey string) (*Transport, error) {
+	conn, err := grpc.NewClient(serverAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client: %w", err)
+	}
+
+	return &Transport{
+		serverAddress: serverAddress,
+		hashKey:       hashKey,
+		conn:          conn,
+		client:        pb.NewMetricsClient(conn),
+	}, nil
+}
+
+// SendBatch отправляет батч метрик.
+// Идентификатор батча передается в запросе, поэтому повторная отправка не применяется дважды.
+func (t *Transport) SendBatch(batch spool.Batch) error {
+	if len(batch.Metrics) == 0 {
+		return nil
+	}
+
+	req := &pb.UpdateMetricsRequest{
+		BatchId: batch.ID,
+		Metrics: make([]*pb.Metric, 0, len(batch.Metrics)),
+	}
+
+	for _, metric := range batch.Metrics {
+		req.Metrics = append(req.Metrics, pb.FromModel(metric))
+	}
+
+	if err := t.sign(req); err != nil {
+		return fmt.Errorf("sign batch failed: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	if ip := t.outboundIP(); ip != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, realIPMetadataKey, ip)
+	}
+
+	if _, err := t.client.UpdateMetrics(ctx, req); err != nil {
+		return fmt.Errorf("send batch request failed: %w", err)
+	}
+
+	return nil
+}
+
+// Close закрывает соединение с сервером.
+func (t *Transport) Close() error {
+	return t.conn.Close()
+}
+
+// sign подписывает запрос, если задан ключ.
+func (t *Transport) sign(req *pb.UpdateMetricsRequest) error {
+	if t.hashKey == "" {
+		return nil
+	}
+
+	data, err := req.SignedData()
+	if err != nil {
+		return err
+	}
+
+	h := hmac.New(sha256.New, []byte(t.hashKey))
+	h.Write(data)
+	req.Hash = hex.EncodeToString(h.Sum(nil))
+
+	return nil
+}
+
+// outboundIP возвращает адрес интерфейса, через который агент обращается к серверу.
+// Адрес определяется без отправки данных и кэшируется после первого успешного определения.
+func (t *Transport) outboundIP() string {
+	t.realIPMu.Lock()
+	defer t.realIPMu.Unlock()
+
+	if t.realIP != "" {
+		return t.realIP
+	}
+
+	conn, err := net.Dial("udp", t.serverAddress)
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+
+	if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+		t.realIP = addr.IP.String()
+	}
+
+	return t.realIP
+}
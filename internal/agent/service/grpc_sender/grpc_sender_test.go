@@ -0,0 +1,97 @@
+package grpcsender
+
+import (
+	"net"
+	"testing"
+
+	"github.com/Ko4etov/go-metrics/internal/agent/repository/spool"
+	"github.com/Ko4etov/go-metrics/internal/models"
+	grpcserver "github.com/Ko4etov/go-metrics/internal/server/grpc_server"
+	"github.com/Ko4etov/go-metrics/internal/server/middlewares"
+	"github.com/Ko4etov/go-metrics/internal/server/repository/idempotency"
+	"github.com/Ko4etov/go-metrics/internal/server/repository/storage"
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+)
+
+func TestTransport_SendBatch(t *testing.T) {
+	logger.Initialize("error")
+
+	store := storage.New(&storage.MetricsStorageConfig{
+		Idempotency: idempotency.New(&idempotency.AppliedBatchesConfig{}),
+	})
+	subnet, _ := middlewares.ParseTrustedSubnet("127.0.0.0/8")
+
+	server := grpcserver.New(&grpcserver.MetricsServerConfig{
+		Storage:       store,
+		HashKey:       "secret",
+		HashMode:      middlewares.HashModeEnforce,
+		TrustedSubnet: subnet,
+	})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go server.Serve(lis)
+	defer server.Stop()
+
+	transport, err := New(lis.Addr().String(), "secret")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer transport.Close()
+
+	delta := int64(2)
+	value := 1.5
+	batch := spool.NewBatch([]models.Metrics{
+		{ID: "PollCount", MType: models.Counter, Delta: &delta},
+		{ID: "Alloc", MType: models.Gauge, Value: &value},
+	})
+
+	// Повторная отправка того же батча не должна применяться дважды.
+	for i := 0; i < 2; i++ {
+		if err := transport.SendBatch(batch); err != nil {
+			t.Fatalf("SendBatch failed: %v", err)
+		}
+	}
+
+	counter, err := store.CounterMetricModel("PollCount")
+	if err != nil || *counter.Delta != 2 {
+		t.Errorf("Expected counter 2, got %+v, %v", counter, err)
+	}
+
+	gauge, err := store.GaugeMetricModel("Alloc")
+	if err != nil || *gauge.Value != 1.5 {
+		t.Errorf("Expected gauge 1.5, got %+v, %v", gauge, err)
+	}
+}
+
+func TestTransport_SendBatchRejectedWithWrongKey(t *testing.T) {
+	logger.Initialize("error")
+
+	server := grpcserver.New(&grpcserver.MetricsServerConfig{
+		Storage:  storage.New(&storage.MetricsStorageConfig{}),
+		HashKey:  "secret",
+		HashMode: middlewares.HashModeEnforce,
+	})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go server.Serve(lis)
+	defer server.Stop()
+
+	transport, err := New(lis.Addr().String(), "other")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer transport.Close()
+
+	value := 1.0
+	batch := spool.NewBatch([]models.Metrics{{ID: "Alloc", MType: models.Gauge, Value: &value}})
+
+	if err := transport.SendBatch(batch); err == nil {
+		t.Error("Expected error for batch signed with wrong key")
+	}
+}
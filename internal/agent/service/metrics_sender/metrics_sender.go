@@ -5,17 +5,28 @@ import (
 	"bytes"
 	"compress/gzip"
 	"crypto/hmac"
+	"crypto/rsa"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
+	neturl "net/url"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-resty/resty/v2"
 
+	"github.com/Ko4etov/go-metrics/internal/agent/interfaces"
+	"github.com/Ko4etov/go-metrics/internal/agent/repository/spool"
 	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+	hybridcrypto "github.com/Ko4etov/go-metrics/internal/service/hybrid_crypto"
 	retriableagent "github.com/Ko4etov/go-metrics/internal/service/retriable_agent"
 )
 
@@ -23,14 +34,44 @@ import (
 type MetricsSenderService struct {
 	ServerAddress string
 	HashKey       string
+	CryptoKey     *rsa.PublicKey // открытый ключ сервера для шифрования тел запросов (опционально)
 	Client        *resty.Client
 	BatchSize     int
 	RateLimit     int
 	RetiebleAgent *retriableagent.RetriableAgent
-	jobs          chan []models.Metrics
+	Transport     interfaces.BatchTransport // транспорт отправки батчей (по умолчанию HTTP)
+	jobs          chan spool.Batch
 	wg            sync.WaitGroup
+	realIP        string         // адрес исходящего интерфейса агента
+	realIPMu      sync.Mutex     // мьютекс для определения адреса
+	spool         *spool.Spool   // очередь неотправленных батчей на диске (опционально)
+	spoolDone     chan struct{}  // канал для остановки воспроизведения очереди
+	spoolWG       sync.WaitGroup // группа ожидания воспроизведения очереди
+	stopping      atomic.Bool    // отправитель останавливается: батчи отправляются без повторов
+	dropped       atomic.Int64   // количество отброшенных батчей
 }
 
+// RejectedError - сервер окончательно отклонил батч ответом 4xx, например из-за
+// неверной подписи или некорректных метрик. Повторная отправка того же батча
+// завершится так же, поэтому он не сохраняется в очередь на диске.
+type RejectedError struct {
+	Status string // статус ответа сервера
+}
+
+func (e *RejectedError) Error() string {
+	return "batch rejected by server: " + e.Status
+}
+
+// isRejectedStatus проверяет, отклонен ли запрос окончательно. Таймаут запроса
+// и превышение лимита запросов считаются временными ошибками.
+func isRejectedStatus(code int) bool {
+	return code >= 400 && code < 500 &&
+		code != http.StatusRequestTimeout && code != http.StatusTooManyRequests
+}
+
+// spoolReplayInterval - интервал попыток отправки батчей из очереди на диске.
+const spoolReplayInterval = 5 * time.Second
+
 // New создает новый отправитель метрик.
 func New(serverAddress string, hashKey string, rateLimit int) *MetricsSenderService {
 	client := resty.New().
@@ -46,7 +87,7 @@ func New(serverAddress string, hashKey string, rateLimit int) *MetricsSenderServ
 		BatchSize:     10,
 		RetiebleAgent: retriableAgent,
 		RateLimit:     rateLimit,
-		jobs:          make(chan []models.Metrics, rateLimit),
+		jobs:          make(chan spool.Batch, rateLimit),
 	}
 
 	sender.startWorkers()
@@ -64,10 +105,121 @@ func (s *MetricsSenderService) startWorkers() {
 func (s *MetricsSenderService) worker() {
 	defer s.wg.Done()
 
-	for metrics := range s.jobs {
-		s.RetiebleAgent.Send(func() error {
-			return s.sendBatch(metrics)
-		})
+	for batch := range s.jobs {
+		err := s.deliver(batch)
+
+		var rejected *RejectedError
+		switch {
+		case err == nil:
+		case !errors.As(err, &rejected) && s.RetiebleAgent.IsRetriable(err):
+			// Сервер мог применить батч до ошибки, поэтому в очереди
+			// он сохраняет идентификатор и не объединяется с другими.
+			batch.Sent = true
+			s.spoolBatch(batch)
+		default:
+			s.drop(batch, err)
+		}
+	}
+}
+
+// drop отбрасывает батч, который не может быть доставлен.
+func (s *MetricsSenderService) drop(batch spool.Batch, err error) {
+	s.dropped.Add(1)
+	logger.Logger.Warnf("dropping batch %s of %d metrics: %v", batch.ID, len(batch.Metrics), err)
+}
+
+// Dropped возвращает количество батчей, отброшенных без доставки.
+func (s *MetricsSenderService) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// deliver отправляет батч с повторами при временных ошибках. Во время остановки
+// батч отправляется один раз, чтобы паузы между повторами не задерживали завершение
+// агента: неотправленный батч остается в очереди на диске, если она включена.
+func (s *MetricsSenderService) deliver(batch spool.Batch) error {
+	if s.stopping.Load() {
+		return s.send(batch)
+	}
+
+	return s.RetiebleAgent.Send(func() error {
+		return s.send(batch)
+	})
+}
+
+// EnableSpool включает сохранение неотправленных батчей в очередь на диске
+// и их последующую отправку в порядке записи.
+func (s *MetricsSenderService) EnableSpool(sp *spool.Spool) {
+	s.spool = sp
+	s.spoolDone = make(chan struct{})
+
+	s.spoolWG.Add(1)
+	go s.replaySpool()
+}
+
+// spoolBatch сохраняет батч в очередь на диске.
+// Без очереди или при ошибке записи батч отбрасывается.
+func (s *MetricsSenderService) spoolBatch(batch spool.Batch) {
+	if s.spool == nil {
+		s.drop(batch, errors.New("spool is disabled"))
+		return
+	}
+
+	if err := s.spool.Enqueue(batch); err != nil {
+		s.drop(batch, fmt.Errorf("spool failed: %w", err))
+	}
+}
+
+// replaySpool периодически отправляет батчи из очереди на диске.
+func (s *MetricsSenderService) replaySpool() {
+	defer s.spoolWG.Done()
+
+	ticker := time.NewTicker(spoolReplayInterval)
+	defer ticker.Stop()
+
+	s.drainSpool()
+
+	for {
+		select {
+		case <-s.spoolDone:
+			return
+		case <-ticker.C:
+			s.drainSpool()
+		}
+	}
+}
+
+// drainSpool отправляет батчи из очереди по порядку, пока сервер их принимает.
+// Батчи, отклоненные сервером без возможности повтора, удаляются из очереди,
+// чтобы не блокировать отправку следующих.
+func (s *MetricsSenderService) drainSpool() {
+	for {
+		select {
+		case <-s.spoolDone:
+			return
+		default:
+		}
+
+		seq, batch, ok := s.spool.Peek()
+		if !ok {
+			return
+		}
+
+		err := s.send(batch)
+
+		var rejected *RejectedError
+		if err != nil && !errors.As(err, &rejected) && s.RetiebleAgent.IsRetriable(err) {
+			s.spool.Release(seq)
+			return
+		}
+
+		if err != nil {
+			s.drop(batch, err)
+		}
+
+		if err := s.spool.Remove(seq); err != nil {
+			logger.Logger.Warnf("failed to remove spooled batch %s: %v", batch.ID, err)
+			return
+		}
 	}
 }
 
@@ -89,12 +241,46 @@ func (s *MetricsSenderService) addHashHeaders(req *resty.Request, data []byte) *
 	return req
 }
 
+// outboundIP возвращает адрес интерфейса, через который агент обращается к серверу.
+// Адрес определяется без отправки данных и кэшируется после первого успешного определения.
+func (s *MetricsSenderService) outboundIP() string {
+	s.realIPMu.Lock()
+	defer s.realIPMu.Unlock()
+
+	if s.realIP != "" {
+		return s.realIP
+	}
+
+	conn, err := net.Dial("udp", s.ServerAddress)
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+
+	if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+		s.realIP = addr.IP.String()
+	}
+
+	return s.realIP
+}
+
+func (s *MetricsSenderService) addRealIPHeader(req *resty.Request) *resty.Request {
+	if ip := s.outboundIP(); ip != "" {
+		req.SetHeader("X-Real-IP", ip)
+	}
+	return req
+}
+
 func (s *MetricsSenderService) verifyResponseHash(resp *resty.Response) error {
 	if s.HashKey == "" {
 		return nil // Хеширование отключено
 	}
 
+	// Сервер передает подпись в трейлере ответа, более старые версии - в заголовке
 	receivedHash := resp.Header().Get("HashSHA256")
+	if receivedHash == "" && resp.RawResponse != nil {
+		receivedHash = resp.RawResponse.Trailer.Get("HashSHA256")
+	}
 	if receivedHash == "" {
 		return nil // Сервер может не отправлять хеш для некоторых ответов
 	}
@@ -114,16 +300,42 @@ func (s *MetricsSenderService) SendMetrics(metrics []models.Metrics) {
 		return
 	}
 
-	batches := s.splitIntoBatches(metrics)
+	for _, part := range s.splitIntoBatches(metrics) {
+		batch := spool.NewBatch(part)
+
+		// Пока в очереди на диске есть батчи, новые встают за ними,
+		// чтобы значения отправлялись в порядке сбора.
+		if s.spool != nil && s.spool.Len() > 0 {
+			s.spoolBatch(batch)
+			continue
+		}
 
-	for _, batch := range batches {
 		select {
 		case s.jobs <- batch:
 		default:
+			s.spoolBatch(batch)
 		}
 	}
 }
 
+// Flush отправляет последние метрики перед остановкой. В отличие от SendMetrics
+// батчи ждут места в очереди воркеров, а не сохраняются на диск или отбрасываются,
+// и отправляются без повторов. После Flush отправщик должен быть остановлен методом Stop.
+func (s *MetricsSenderService) Flush(metrics []models.Metrics) {
+	s.stopping.Store(true)
+
+	for _, part := range s.splitIntoBatches(metrics) {
+		batch := spool.NewBatch(part)
+
+		if s.spool != nil && s.spool.Len() > 0 {
+			s.spoolBatch(batch)
+			continue
+		}
+
+		s.jobs <- batch
+	}
+}
+
 func (s *MetricsSenderService) splitIntoBatches(metrics []models.Metrics) [][]models.Metrics {
 	var batches [][]models.Metrics
 
@@ -138,15 +350,26 @@ func (s *MetricsSenderService) splitIntoBatches(metrics []models.Metrics) [][]mo
 	return batches
 }
 
+// send отправляет батч через заданный транспорт, по умолчанию - по HTTP.
+func (s *MetricsSenderService) send(batch spool.Batch) error {
+	if s.Transport != nil {
+		return s.Transport.SendBatch(batch)
+	}
+
+	return s.sendBatch(batch)
+}
+
 // sendBatch отправляет один батч метрик на сервер с хэшированием.
-func (s *MetricsSenderService) sendBatch(metrics []models.Metrics) error {
-	if len(metrics) == 0 {
+// Идентификатор батча передается в заголовке Idempotency-Key и не меняется
+// между повторными попытками, поэтому сервер не применит батч дважды.
+func (s *MetricsSenderService) sendBatch(batch spool.Batch) error {
+	if len(batch.Metrics) == 0 {
 		return nil
 	}
 
 	url := fmt.Sprintf("http://%s/updates/", s.ServerAddress)
 
-	jsonData, err := json.Marshal(metrics)
+	jsonData, err := json.Marshal(batch.Metrics)
 	if err != nil {
 		return fmt.Errorf("marshal metrics failed: %w", err)
 	}
@@ -156,19 +379,34 @@ func (s *MetricsSenderService) sendBatch(metrics []models.Metrics) error {
 		return fmt.Errorf("compress data failed: %w", err)
 	}
 
+	body, err := s.encryptData(compressedData)
+	if err != nil {
+		return fmt.Errorf("encrypt data failed: %w", err)
+	}
+
 	req := s.Client.R().
-		SetBody(compressedData).
+		SetBody(body).
 		SetHeader("Content-Type", "application/json").
 		SetHeader("Content-Encoding", "gzip").
-		SetHeader("Accept-Encoding", "gzip")
+		SetHeader("Accept-Encoding", "gzip").
+		SetHeader("Idempotency-Key", batch.ID)
+
+	if s.CryptoKey != nil {
+		req.SetHeader(hybridcrypto.Header, hybridcrypto.Scheme)
+	}
 
 	req = s.addHashHeaders(req, jsonData)
+	req = s.addRealIPHeader(req)
 
 	resp, err := req.Post(url)
 	if err != nil {
 		return fmt.Errorf("send batch request failed: %w", err)
 	}
 
+	if isRejectedStatus(resp.StatusCode()) {
+		return &RejectedError{Status: resp.Status()}
+	}
+
 	if resp.IsError() {
 		return fmt.Errorf("server error: %s", resp.Status())
 	}
@@ -197,13 +435,29 @@ func (s *MetricsSenderService) compressData(data []byte) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// encryptData шифрует данные открытым ключом сервера, если он задан.
+func (s *MetricsSenderService) encryptData(data []byte) ([]byte, error) {
+	if s.CryptoKey == nil {
+		return data, nil
+	}
+
+	return hybridcrypto.Encrypt(s.CryptoKey, data)
+}
+
 // SendMetric отправляет одну метрику текстовым форматом.
 func (s *MetricsSenderService) SendMetric(metric models.Metrics) error {
 	url := s.BuildURL(metric)
 
-	resp, err := s.Client.R().
-		SetHeader("Content-Type", "text/plain").
-		Post(url)
+	req := s.Client.R().
+		SetHeader("Content-Type", "text/plain")
+
+	// Для запросов без тела подписывается путь запроса вместе с параметрами.
+	if path, err := neturl.Parse(url); err == nil {
+		req = s.addHashHeaders(req, []byte(path.RequestURI()))
+	}
+	req = s.addRealIPHeader(req)
+
+	resp, err := req.Post(url)
 	if err != nil {
 		return fmt.Errorf("send request failed: %w", err)
 	}
@@ -234,14 +488,24 @@ func (s *MetricsSenderService) SendMetricJSON(metric models.Metrics) error {
 		return fmt.Errorf("gzip close failed: %w", err)
 	}
 
+	body, err := s.encryptData(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("encrypt data failed: %w", err)
+	}
+
 	req := s.Client.R().
-		SetBody(buf.Bytes()).
+		SetBody(body).
 		SetHeader("Content-Type", "application/json").
 		SetHeader("Content-Encoding", "gzip").
 		SetHeader("Accept-Encoding", "gzip").
-		SetHeader("Content-Length", strconv.Itoa(buf.Len()))
+		SetHeader("Content-Length", strconv.Itoa(len(body)))
+
+	if s.CryptoKey != nil {
+		req.SetHeader(hybridcrypto.Header, hybridcrypto.Scheme)
+	}
 
 	req = s.addHashHeaders(req, jsonData)
+	req = s.addRealIPHeader(req)
 
 	resp, err := req.Post(url)
 	if err != nil {
@@ -260,6 +524,7 @@ func (s *MetricsSenderService) SendMetricJSON(metric models.Metrics) error {
 }
 
 // BuildURL строит URL для отправки одной метрики текстовым форматом.
+// Метки метрики передаются параметрами запроса.
 func (s *MetricsSenderService) BuildURL(metric models.Metrics) string {
 	var value string
 
@@ -270,12 +535,33 @@ func (s *MetricsSenderService) BuildURL(metric models.Metrics) string {
 		value = strconv.FormatInt(*metric.Delta, 10)
 	}
 
-	return fmt.Sprintf("http://%s/update/%s/%s/%s",
+	url := fmt.Sprintf("http://%s/update/%s/%s/%s",
 		s.ServerAddress, metric.MType, metric.ID, value)
+
+	if len(metric.Labels) > 0 {
+		query := neturl.Values{}
+		for name, labelValue := range metric.Labels {
+			query.Set(name, labelValue)
+		}
+		url += "?" + query.Encode()
+	}
+
+	return url
 }
 
 // Stop останавливает отправщик метрик и дожидается завершения всех воркеров.
+// Батчи, уже поставленные в очередь, отправляются без повторов.
 func (s *MetricsSenderService) Stop() {
+	s.stopping.Store(true)
 	close(s.jobs)
 	s.wg.Wait()
+
+	if s.spool != nil {
+		close(s.spoolDone)
+		s.spoolWG.Wait()
+	}
+
+	if closer, ok := s.Transport.(io.Closer); ok {
+		closer.Close()
+	}
 }
\ No newline at end of file
@@ -1,12 +1,17 @@
 package metricssender
 
 import (
+	"compress/gzip"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/Ko4etov/go-metrics/internal/agent/repository/spool"
 	"github.com/Ko4etov/go-metrics/internal/models"
 )
 
@@ -44,6 +49,25 @@ func TestBuildURL_GaugeMetric(t *testing.T) {
 	}
 }
 
+func TestBuildURL_Labels(t *testing.T) {
+	sender := New("localhost:8080", "", 3)
+
+	value := 1.0
+	metric := models.Metrics{
+		ID:     "TestMetric",
+		MType:  models.Gauge,
+		Value:  &value,
+		Labels: models.Labels{"host": "web1", "env": "prod"},
+	}
+
+	url := sender.BuildURL(metric)
+	expected := "http://localhost:8080/update/gauge/TestMetric/1?env=prod&host=web1"
+
+	if url != expected {
+		t.Errorf("Expected URL %s, got %s", expected, url)
+	}
+}
+
 func TestBuildURL_CounterMetric(t *testing.T) {
 	sender := New("localhost:8080", "", 3)
 
@@ -296,3 +320,262 @@ func TestSendMetricJSON_Success(t *testing.T) {
 		t.Errorf("Expected 1 request, got %d", atomic.LoadInt32(&requestCount))
 	}
 }
+
+func TestSendMetricJSON_SetsRealIP(t *testing.T) {
+	var realIP atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		realIP.Store(r.Header.Get("X-Real-IP"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sender := New(server.URL[7:], "", 1)
+	defer sender.Stop()
+
+	value := 1.0
+	err := sender.SendMetricJSON(models.Metrics{ID: "Alloc", MType: models.Gauge, Value: &value})
+	if err != nil {
+		t.Fatalf("SendMetricJSON failed: %v", err)
+	}
+
+	if got, _ := realIP.Load().(string); got != "127.0.0.1" {
+		t.Errorf("Expected X-Real-IP 127.0.0.1, got %q", got)
+	}
+}
+
+func TestEnableSpool_ReplaysInOrder(t *testing.T) {
+	var mu sync.Mutex
+	var received []float64
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Errorf("Expected gzip body: %v", err)
+			return
+		}
+
+		var metrics []models.Metrics
+		json.NewDecoder(gz).Decode(&metrics)
+
+		mu.Lock()
+		for _, m := range metrics {
+			received = append(received, *m.Value)
+		}
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sp, err := spool.Open(t.TempDir(), 10)
+	if err != nil {
+		t.Fatalf("spool.Open failed: %v", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		value := float64(i)
+		sp.Enqueue(spool.NewBatch([]models.Metrics{{ID: "Alloc", MType: models.Gauge, Value: &value}}))
+	}
+
+	sender := New(server.URL[7:], "", 1)
+	sender.EnableSpool(sp)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for sp.Len() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	sender.Stop()
+
+	if sp.Len() != 0 {
+		t.Errorf("Expected spool to be drained, %d segments left", sp.Len())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(received) != 3 || received[0] != 1 || received[1] != 2 || received[2] != 3 {
+		t.Errorf("Expected values replayed in order [1 2 3], got %v", received)
+	}
+}
+
+func TestSendMetrics_RejectedBatchIsNotSpooled(t *testing.T) {
+	var requestCount int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requestCount, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	sp, err := spool.Open(t.TempDir(), 10)
+	if err != nil {
+		t.Fatalf("spool.Open failed: %v", err)
+	}
+
+	sender := New(server.URL[7:], "", 1)
+	sender.EnableSpool(sp)
+
+	value := 1.0
+	sender.SendMetrics([]models.Metrics{{ID: "Alloc", MType: models.Gauge, Value: &value}})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for sender.Dropped() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	sender.Stop()
+
+	if sender.Dropped() != 1 {
+		t.Errorf("Expected rejected batch to be dropped, dropped %d", sender.Dropped())
+	}
+	if count := atomic.LoadInt32(&requestCount); count != 1 {
+		t.Errorf("Expected rejected batch to be sent once without retries, got %d requests", count)
+	}
+	if sp.Len() != 0 {
+		t.Errorf("Expected rejected batch not to be spooled, %d segments in spool", sp.Len())
+	}
+}
+
+func TestEnableSpool_DropsRejectedSegment(t *testing.T) {
+	var mu sync.Mutex
+	var received []float64
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Errorf("Expected gzip body: %v", err)
+			return
+		}
+
+		var metrics []models.Metrics
+		json.NewDecoder(gz).Decode(&metrics)
+
+		if *metrics[0].Value == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		received = append(received, *metrics[0].Value)
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sp, err := spool.Open(t.TempDir(), 10)
+	if err != nil {
+		t.Fatalf("spool.Open failed: %v", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		value := float64(i)
+		sp.Enqueue(spool.NewBatch([]models.Metrics{{ID: "Alloc", MType: models.Gauge, Value: &value}}))
+	}
+
+	sender := New(server.URL[7:], "", 1)
+	sender.EnableSpool(sp)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for sp.Len() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	sender.Stop()
+
+	if sp.Len() != 0 {
+		t.Errorf("Expected spool to be drained past the rejected segment, %d segments left", sp.Len())
+	}
+	if sender.Dropped() != 1 {
+		t.Errorf("Expected 1 dropped batch, got %d", sender.Dropped())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(received) != 2 || received[0] != 2 || received[1] != 3 {
+		t.Errorf("Expected values [2 3] delivered after the rejected one, got %v", received)
+	}
+}
+
+func TestSendMetricJSON_VerifiesTrailerHash(t *testing.T) {
+	const key = "secret"
+	responseBody := []byte(`{"id":"Alloc","type":"gauge","value":1}`)
+
+	tests := []struct {
+		name    string
+		hash    string
+		wantErr bool
+	}{
+		{"valid trailer", New("", key, 1).calculateHash(responseBody), false},
+		{"invalid trailer", "bad", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Trailer", "HashSHA256")
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Content-Encoding", "gzip")
+
+				gz := gzip.NewWriter(w)
+				gz.Write(responseBody)
+				gz.Close()
+
+				w.Header().Set("HashSHA256", tt.hash)
+			}))
+			defer server.Close()
+
+			sender := New(server.URL[7:], key, 1)
+			defer sender.Stop()
+
+			value := 1.0
+			err := sender.SendMetricJSON(models.Metrics{ID: "Alloc", MType: models.Gauge, Value: &value})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SendMetricJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFlush_SendsAllBatches(t *testing.T) {
+	var requestCount int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requestCount, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sender := New(server.URL[7:], "", 1)
+
+	metrics := make([]models.Metrics, 25)
+	for i := range metrics {
+		value := float64(i)
+		metrics[i] = models.Metrics{ID: "Metric" + strconv.Itoa(i), MType: models.Gauge, Value: &value}
+	}
+
+	sender.Flush(metrics)
+	sender.Stop()
+
+	if count := atomic.LoadInt32(&requestCount); count != 3 {
+		t.Errorf("Expected 3 batch requests, got %d", count)
+	}
+}
+
+func TestFlush_DoesNotWaitBetweenRetries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	sender := New(server.URL[7:], "", 1)
+
+	value := 1.0
+	start := time.Now()
+	sender.Flush([]models.Metrics{{ID: "Alloc", MType: models.Gauge, Value: &value}})
+	sender.Stop()
+
+	if elapsed := time.Since(start); elapsed >= sender.RetiebleAgent.RetryDelays[0] {
+		t.Errorf("Expected shutdown without retry delays, took %v", elapsed)
+	}
+}
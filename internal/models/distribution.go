@@ -0,0 +1,152 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// HistogramValue - значение гистограммы: распределение наблюдений по корзинам.
+//
+// Buckets содержит верхние границы корзин в порядке возрастания, корзина +Inf
+// подразумевается. Counts содержит количество наблюдений в каждой корзине
+// (не накопительно), последний элемент - корзина +Inf.
+// Как и у счетчика, агент передает наблюдения, накопленные с прошлой отправки,
+// а сервер суммирует их.
+type HistogramValue struct {
+	Buckets []float64 `json:"buckets"` // верхние границы корзин
+	Counts  []uint64  `json:"counts"`  // количество наблюдений в корзинах, включая +Inf
+	Sum     float64   `json:"sum"`     // сумма наблюдений
+	Count   uint64    `json:"count"`   // количество наблюдений
+}
+
+// Validate проверяет согласованность гистограммы.
+func (h *HistogramValue) Validate() error {
+	if len(h.Counts) != len(h.Buckets)+1 {
+		return fmt.Errorf("histogram must have %d counts for %d buckets, got %d",
+			len(h.Buckets)+1, len(h.Buckets), len(h.Counts))
+	}
+
+	for i, bound := range h.Buckets {
+		if math.IsNaN(bound) || math.IsInf(bound, 0) {
+			return fmt.Errorf("invalid histogram bucket bound: %v", bound)
+		}
+		if i > 0 && bound <= h.Buckets[i-1] {
+			return fmt.Errorf("histogram buckets must be strictly increasing")
+		}
+	}
+
+	var total uint64
+	for _, count := range h.Counts {
+		total += count
+	}
+
+	if total != h.Count {
+		return fmt.Errorf("histogram count %d does not match bucket counts %d", h.Count, total)
+	}
+
+	if math.IsNaN(h.Sum) || math.IsInf(h.Sum, 0) {
+		return fmt.Errorf("invalid histogram sum")
+	}
+
+	return nil
+}
+
+// Merge возвращает гистограмму, объединяющую наблюдения h и other.
+// Если границы корзин различаются, результатом становится копия other:
+// распределения с разными корзинами не объединяются.
+func (h *HistogramValue) Merge(other *HistogramValue) *HistogramValue {
+	if h == nil || !slices.Equal(h.Buckets, other.Buckets) {
+		return other.Copy()
+	}
+
+	merged := h.Copy()
+	for i := range merged.Counts {
+		merged.Counts[i] += other.Counts[i]
+	}
+	merged.Sum += other.Sum
+	merged.Count += other.Count
+
+	return merged
+}
+
+// Copy возвращает независимую копию гистограммы.
+func (h *HistogramValue) Copy() *HistogramValue {
+	if h == nil {
+		return nil
+	}
+
+	return &HistogramValue{
+		Buckets: slices.Clone(h.Buckets),
+		Counts:  slices.Clone(h.Counts),
+		Sum:     h.Sum,
+		Count:   h.Count,
+	}
+}
+
+// Quantile - значение квантиля сводки.
+type Quantile struct {
+	Quantile float64 `json:"quantile"` // уровень квантиля от 0 до 1
+	Value    float64 `json:"value"`    // значение квантиля
+}
+
+// SummaryValue - значение сводки: квантили наблюдений, их сумма и количество.
+//
+// Квантили не объединяются, поэтому сервер хранит последние переданные значения,
+// а сумма и количество наблюдений, как у счетчика, суммируются.
+type SummaryValue struct {
+	Quantiles []Quantile `json:"quantiles"` // квантили наблюдений за последний интервал
+	Sum       float64    `json:"sum"`       // сумма наблюдений
+	Count     uint64     `json:"count"`     // количество наблюдений
+}
+
+// Validate проверяет согласованность сводки.
+func (s *SummaryValue) Validate() error {
+	for i, q := range s.Quantiles {
+		if math.IsNaN(q.Quantile) || q.Quantile < 0 || q.Quantile > 1 {
+			return fmt.Errorf("invalid summary quantile: %v", q.Quantile)
+		}
+		if i > 0 && q.Quantile <= s.Quantiles[i-1].Quantile {
+			return fmt.Errorf("summary quantiles must be strictly increasing")
+		}
+		if math.IsNaN(q.Value) || math.IsInf(q.Value, 0) {
+			return fmt.Errorf("invalid value of summary quantile %v", q.Quantile)
+		}
+	}
+
+	if math.IsNaN(s.Sum) || math.IsInf(s.Sum, 0) {
+		return fmt.Errorf("invalid summary sum")
+	}
+
+	return nil
+}
+
+// Merge возвращает сводку с квантилями other и суммарными количеством и суммой наблюдений.
+// Если в other нет наблюдений, сохраняются квантили s.
+func (s *SummaryValue) Merge(other *SummaryValue) *SummaryValue {
+	if s == nil {
+		return other.Copy()
+	}
+
+	merged := other.Copy()
+	if other.Count == 0 {
+		merged.Quantiles = slices.Clone(s.Quantiles)
+	}
+	merged.Sum += s.Sum
+	merged.Count += s.Count
+
+	return merged
+}
+
+// Copy возвращает независимую копию сводки.
+func (s *SummaryValue) Copy() *SummaryValue {
+	if s == nil {
+		return nil
+	}
+
+	return &SummaryValue{
+		Quantiles: slices.Clone(s.Quantiles),
+		Sum:       s.Sum,
+		Count:     s.Count,
+	}
+}
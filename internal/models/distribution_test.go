@@ -0,0 +1,75 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHistogramValue_ValidateAndMerge(t *testing.T) {
+	first := &HistogramValue{Buckets: []float64{0.1, 1}, Counts: []uint64{1, 2, 0}, Sum: 1.5, Count: 3}
+	second := &HistogramValue{Buckets: []float64{0.1, 1}, Counts: []uint64{0, 1, 1}, Sum: 5.5, Count: 2}
+
+	for _, h := range []*HistogramValue{first, second} {
+		if err := h.Validate(); err != nil {
+			t.Fatalf("unexpected validation error: %v", err)
+		}
+	}
+
+	merged := first.Merge(second)
+	if merged.Count != 5 || merged.Sum != 7 {
+		t.Errorf("expected count 5 and sum 7, got %d and %v", merged.Count, merged.Sum)
+	}
+	if merged.Counts[0] != 1 || merged.Counts[1] != 3 || merged.Counts[2] != 1 {
+		t.Errorf("unexpected merged counts %v", merged.Counts)
+	}
+	if first.Count != 3 {
+		t.Errorf("merge must not modify the source histogram")
+	}
+
+	rebucketed := &HistogramValue{Buckets: []float64{10}, Counts: []uint64{1, 0}, Sum: 2, Count: 1}
+	if replaced := first.Merge(rebucketed); replaced.Count != 1 || len(replaced.Buckets) != 1 {
+		t.Errorf("expected histogram with other buckets to replace the value, got %+v", replaced)
+	}
+
+	invalid := []*HistogramValue{
+		{Buckets: []float64{1}, Counts: []uint64{1}, Count: 1},
+		{Buckets: []float64{1, 0.5}, Counts: []uint64{0, 0, 0}},
+		{Buckets: []float64{1}, Counts: []uint64{1, 1}, Count: 3},
+		{Buckets: []float64{1}, Counts: []uint64{1, 0}, Sum: math.Inf(1), Count: 1},
+	}
+	for _, h := range invalid {
+		if err := h.Validate(); err == nil {
+			t.Errorf("expected validation error for %+v", h)
+		}
+	}
+}
+
+func TestSummaryValue_ValidateAndMerge(t *testing.T) {
+	first := &SummaryValue{Quantiles: []Quantile{{0.5, 1}, {0.99, 3}}, Sum: 10, Count: 5}
+	second := &SummaryValue{Quantiles: []Quantile{{0.5, 2}, {0.99, 4}}, Sum: 6, Count: 2}
+
+	merged := first.Merge(second)
+	if merged.Count != 7 || merged.Sum != 16 {
+		t.Errorf("expected count 7 and sum 16, got %d and %v", merged.Count, merged.Sum)
+	}
+	if merged.Quantiles[0].Value != 2 {
+		t.Errorf("expected quantiles of the latest summary, got %v", merged.Quantiles)
+	}
+
+	if kept := first.Merge(&SummaryValue{}); kept.Quantiles[0].Value != 1 {
+		t.Errorf("expected quantiles to be kept for an empty summary, got %v", kept.Quantiles)
+	}
+
+	if err := (&SummaryValue{Quantiles: []Quantile{{1.5, 1}}}).Validate(); err == nil {
+		t.Error("expected validation error for quantile above 1")
+	}
+	if err := (&SummaryValue{Quantiles: []Quantile{{0.9, 1}, {0.5, 1}}}).Validate(); err == nil {
+		t.Error("expected validation error for unordered quantiles")
+	}
+	if err := (&SummaryValue{Quantiles: []Quantile{{0.5, math.NaN()}}}).Validate(); err == nil {
+		t.Error("expected validation error for NaN quantile value")
+	}
+	if err := (&SummaryValue{Sum: math.Inf(-1), Count: 1}).Validate(); err == nil {
+		t.Error("expected validation error for infinite sum")
+	}
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// MetricPoint представляет одну точку истории метрики.
+//
+// Для сырых точек у gauge заполняется Value, у counter - Delta (принятое приращение).
+// Для агрегированных точек Value содержит среднее значение gauge,
+// Delta - сумму приращений counter, а Count - количество исходных обновлений.
+type MetricPoint struct {
+	Timestamp time.Time `json:"ts"`               // время обновления или начало интервала агрегации
+	Value     *float64  `json:"value,omitempty"`  // значение (среднее для агрегатов) измерителя
+	Delta     *int64    `json:"delta,omitempty"`  // приращение (сумма для агрегатов) счетчика
+	Source    string    `json:"source,omitempty"` // источник обновления (только для сырых точек)
+	Min       *float64  `json:"min,omitempty"`    // минимальное значение измерителя за интервал
+	Max       *float64  `json:"max,omitempty"`    // максимальное значение измерителя за интервал
+	Last      *float64  `json:"last,omitempty"`   // последнее значение измерителя за интервал
+	Rate      *float64  `json:"rate,omitempty"`   // скорость роста счетчика в секунду за интервал
+	Count     int64     `json:"count,omitempty"`  // количество исходных обновлений в интервале
+}
+
+// MetricHistory представляет результат запроса истории метрики.
+type MetricHistory struct {
+	ID         string        `json:"id"`                  // имя метрики
+	MType      string        `json:"type"`                // тип метрики (counter или gauge)
+	Resolution int64         `json:"resolution"`          // шаг точек в секундах, 0 - сырые точки
+	Points     []MetricPoint `json:"points"`              // точки истории в порядке возрастания времени
+	Truncated  bool          `json:"truncated,omitempty"` // часть сырых точек интервала вытеснена, история неполна
+}
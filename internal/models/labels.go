@@ -0,0 +1,246 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Labels - набор меток метрики (например, host, service, env).
+// Метрики с одинаковым именем и разными наборами меток хранятся раздельно.
+type Labels map[string]string
+
+// labelNameRe - допустимое имя метки.
+var labelNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// reservedLabelNames - метки, которые добавляются при выгрузке в формате Prometheus:
+// le у корзин гистограммы и quantile у квантилей сводки.
+var reservedLabelNames = map[string]bool{"le": true, "quantile": true}
+
+// Validate проверяет имена и значения меток.
+func (l Labels) Validate() error {
+	for name, value := range l {
+		if !labelNameRe.MatchString(name) || strings.HasPrefix(name, "__") {
+			return fmt.Errorf("invalid label name: %q", name)
+		}
+		if reservedLabelNames[name] {
+			return fmt.Errorf("label name %q is reserved", name)
+		}
+		if value == "" {
+			return fmt.Errorf("empty value of label %q", name)
+		}
+	}
+
+	return nil
+}
+
+// Names возвращает имена меток в порядке возрастания.
+func (l Labels) Names() []string {
+	names := make([]string, 0, len(l))
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// String возвращает каноническое представление меток: {name="value",...}
+// с именами в порядке возрастания. Для пустого набора возвращает пустую строку.
+func (l Labels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+
+	for i, name := range l.Names() {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(l[name]))
+	}
+
+	b.WriteByte('}')
+
+	return b.String()
+}
+
+// Merge возвращает новый набор меток, в котором метки other дополняют
+// и переопределяют метки l.
+func (l Labels) Merge(other Labels) Labels {
+	if len(l) == 0 && len(other) == 0 {
+		return nil
+	}
+
+	merged := make(Labels, len(l)+len(other))
+	for name, value := range l {
+		merged[name] = value
+	}
+	for name, value := range other {
+		merged[name] = value
+	}
+
+	return merged
+}
+
+// ParseLabels разбирает метки в формате name=value,name=value.
+// Для пустой строки возвращает nil.
+func ParseLabels(s string) (Labels, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	labels := make(Labels)
+
+	for _, pair := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid label %q: expected name=value", pair)
+		}
+		labels[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+
+	if err := labels.Validate(); err != nil {
+		return nil, err
+	}
+
+	return labels, nil
+}
+
+// SanitizeName приводит имя метрики или метки из внешнего протокола к допустимому виду:
+// символы, кроме латинских букв, цифр и подчеркивания, заменяются на подчеркивание,
+// а перед ведущей цифрой добавляется подчеркивание. Например, api.requests-total
+// становится api_requests_total.
+func SanitizeName(name string) string {
+	var b strings.Builder
+	b.Grow(len(name) + 1)
+
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+
+	return b.String()
+}
+
+// MetricKey возвращает ключ ряда метрики: имя и каноническое представление меток.
+// Ключ метрики без меток совпадает с ее именем.
+func MetricKey(id string, labels Labels) string {
+	return id + labels.String()
+}
+
+// Key возвращает ключ ряда метрики.
+func (m Metrics) Key() string {
+	return MetricKey(m.ID, m.Labels)
+}
+
+// Операторы сравнения меток.
+const (
+	MatchEqual     = "="  // значение метки равно заданному
+	MatchNotEqual  = "!=" // значение метки не равно заданному
+	MatchRegexp    = "=~" // значение метки соответствует регулярному выражению
+	MatchNotRegexp = "!~" // значение метки не соответствует регулярному выражению
+)
+
+// LabelMatcher - условие отбора метрик по значению метки.
+// Отсутствующая метка считается меткой с пустым значением.
+type LabelMatcher struct {
+	Name  string         // имя метки
+	Op    string         // оператор сравнения
+	Value string         // значение или регулярное выражение
+	re    *regexp.Regexp // скомпилированное регулярное выражение
+}
+
+// ParseLabelMatcher разбирает условие вида name=value, name!=value,
+// name=~regexp или name!~regexp. Регулярное выражение должно совпадать со значением целиком.
+func ParseLabelMatcher(s string) (LabelMatcher, error) {
+	i := strings.IndexAny(s, "=!")
+	if i <= 0 {
+		return LabelMatcher{}, fmt.Errorf("invalid label matcher %q", s)
+	}
+
+	matcher := LabelMatcher{Name: strings.TrimSpace(s[:i])}
+	rest := s[i:]
+
+	for _, op := range []string{MatchRegexp, MatchNotRegexp, MatchNotEqual, MatchEqual} {
+		if strings.HasPrefix(rest, op) {
+			matcher.Op = op
+			matcher.Value = strings.TrimSpace(rest[len(op):])
+			break
+		}
+	}
+
+	if matcher.Op == "" || !labelNameRe.MatchString(matcher.Name) {
+		return LabelMatcher{}, fmt.Errorf("invalid label matcher %q", s)
+	}
+
+	if matcher.Op == MatchRegexp || matcher.Op == MatchNotRegexp {
+		re, err := regexp.Compile("^(?:" + matcher.Value + ")$")
+		if err != nil {
+			return LabelMatcher{}, fmt.Errorf("invalid label matcher %q: %w", s, err)
+		}
+		matcher.re = re
+	}
+
+	return matcher, nil
+}
+
+// ParseLabelMatchers разбирает список условий отбора по меткам.
+func ParseLabelMatchers(values []string) ([]LabelMatcher, error) {
+	matchers := make([]LabelMatcher, 0, len(values))
+
+	for _, value := range values {
+		matcher, err := ParseLabelMatcher(value)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, matcher)
+	}
+
+	return matchers, nil
+}
+
+// Matches проверяет, удовлетворяет ли набор меток условию.
+func (m LabelMatcher) Matches(labels Labels) bool {
+	value := labels[m.Name]
+
+	switch m.Op {
+	case MatchEqual:
+		return value == m.Value
+	case MatchNotEqual:
+		return value != m.Value
+	case MatchRegexp:
+		return m.re.MatchString(value)
+	case MatchNotRegexp:
+		return !m.re.MatchString(value)
+	}
+
+	return false
+}
+
+// MatchLabels проверяет, удовлетворяет ли набор меток всем условиям.
+func MatchLabels(labels Labels, matchers []LabelMatcher) bool {
+	for _, matcher := range matchers {
+		if !matcher.Matches(labels) {
+			return false
+		}
+	}
+
+	return true
+}
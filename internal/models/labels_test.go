@@ -0,0 +1,58 @@
+package models
+
+import "testing"
+
+func TestLabels_Key(t *testing.T) {
+	labels, err := ParseLabels(" host=web1, env = prod ")
+	if err != nil {
+		t.Fatalf("ParseLabels failed: %v", err)
+	}
+
+	if key := MetricKey("HeapAlloc", labels); key != `HeapAlloc{env="prod",host="web1"}` {
+		t.Errorf("unexpected key %q", key)
+	}
+
+	if key := MetricKey("HeapAlloc", nil); key != "HeapAlloc" {
+		t.Errorf("expected key without labels to equal name, got %q", key)
+	}
+
+	for _, invalid := range []string{"host", "1host=a", "__name__=a", "host=", "le=0.5", "quantile=0.99"} {
+		if _, err := ParseLabels(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
+
+func TestLabelMatcher(t *testing.T) {
+	labels := Labels{"host": "web1", "env": "prod"}
+
+	tests := []struct {
+		matcher string
+		want    bool
+	}{
+		{"host=web1", true},
+		{"host=web2", false},
+		{"host!=web2", true},
+		{"host=~web.*", true},
+		{"host=~eb", false},
+		{"env!~prod|dev", false},
+		{"region=", true},
+		{"region!=", false},
+	}
+
+	for _, tt := range tests {
+		m, err := ParseLabelMatcher(tt.matcher)
+		if err != nil {
+			t.Fatalf("ParseLabelMatcher(%q) failed: %v", tt.matcher, err)
+		}
+		if got := m.Matches(labels); got != tt.want {
+			t.Errorf("%q: expected %v, got %v", tt.matcher, tt.want, got)
+		}
+	}
+
+	for _, invalid := range []string{"host", "=web1", "host=~(", "1host=a"} {
+		if _, err := ParseLabelMatcher(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
@@ -1,11 +1,34 @@
 // Package models содержит модели данных для системы метрик.
 package models
 
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
 const (
 	// Counter - тип метрики "счетчик"
 	Counter = "counter"
 	// Gauge - тип метрики "измеритель"
 	Gauge   = "gauge"
+	// Histogram - тип метрики "гистограмма"
+	Histogram = "histogram"
+	// Summary - тип метрики "сводка" (квантили)
+	Summary = "summary"
+)
+
+// ReservedPrefix - префикс имен собственных метрик сервера.
+// Метрики с таким именем от клиентов не принимаются.
+const ReservedPrefix = "metrics_server_"
+
+const (
+	// MaxIDLength - максимальная длина имени метрики в символах (колонка metrics.id).
+	MaxIDLength = 255
+	// MaxKeyLength - максимальная длина ключа ряда метрики в символах: имени вместе
+	// с метками (колонки metrics.key и metrics_history.id).
+	MaxKeyLength = 1024
 )
 
 // Metrics представляет метрику системы.
@@ -13,9 +36,86 @@ const (
 // чтобы отличать значение "0" от не заданного значения
 // и соответственно не кодировать в структуру.
 type Metrics struct {
-	ID    string   `json:"id"`              // имя метрики
-	MType string   `json:"type"`            // тип метрики (counter или gauge)
-	Delta *int64   `json:"delta,omitempty"` // значение для счетчика (опционально)
-	Value *float64 `json:"value,omitempty"` // значение для измерителя (опционально)
-	Hash  string   `json:"hash,omitempty"`  // хеш для проверки целостности (опционально)
-}
\ No newline at end of file
+	ID     string   `json:"id"`               // имя метрики
+	MType  string   `json:"type"`             // тип метрики (counter или gauge)
+	Delta  *int64   `json:"delta,omitempty"`  // значение для счетчика (опционально)
+	Value  *float64 `json:"value,omitempty"`  // значение для измерителя (опционально)
+	Hash   string   `json:"hash,omitempty"`   // хеш для проверки целостности (опционально)
+	Labels Labels   `json:"labels,omitempty"` // метки метрики (опционально)
+
+	Histogram *HistogramValue `json:"histogram,omitempty"` // значение для гистограммы (опционально)
+	Summary   *SummaryValue   `json:"summary,omitempty"`   // значение для сводки (опционально)
+}
+
+// Validate проверяет метрику, принятую от клиента: тип, имя и метки ряда
+// и значение, соответствующее типу.
+func (m *Metrics) Validate() error {
+	if err := m.ValidateSeries(); err != nil {
+		return err
+	}
+
+	switch m.MType {
+	case Gauge:
+		if m.Value == nil {
+			return fmt.Errorf("value is required for gauge metric")
+		}
+		if math.IsNaN(*m.Value) || math.IsInf(*m.Value, 0) {
+			return fmt.Errorf("value must be finite for gauge metric")
+		}
+	case Counter:
+		if m.Delta == nil {
+			return fmt.Errorf("delta is required for counter metric")
+		}
+		if *m.Delta < 0 {
+			return fmt.Errorf("delta cannot be negative for counter metric")
+		}
+	case Histogram:
+		if m.Histogram == nil {
+			return fmt.Errorf("histogram is required for histogram metric")
+		}
+		if err := m.Histogram.Validate(); err != nil {
+			return err
+		}
+	case Summary:
+		if m.Summary == nil {
+			return fmt.Errorf("summary is required for summary metric")
+		}
+		if err := m.Summary.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateSeries проверяет тип, имя и метки ряда метрики без значения.
+// Используется приемниками, которые накапливают значения до сброса в хранилище.
+func (m *Metrics) ValidateSeries() error {
+	switch m.MType {
+	case Gauge, Counter, Histogram, Summary:
+	default:
+		return fmt.Errorf("invalid metric type: %s", m.MType)
+	}
+
+	if m.ID == "" {
+		return fmt.Errorf("metric ID is required")
+	}
+
+	if strings.HasPrefix(m.ID, ReservedPrefix) {
+		return fmt.Errorf("metric name prefix %s is reserved", ReservedPrefix)
+	}
+
+	if utf8.RuneCountInString(m.ID) > MaxIDLength {
+		return fmt.Errorf("metric ID is longer than %d characters", MaxIDLength)
+	}
+
+	if err := m.Labels.Validate(); err != nil {
+		return err
+	}
+
+	if utf8.RuneCountInString(m.Key()) > MaxKeyLength {
+		return fmt.Errorf("metric key with labels is longer than %d characters", MaxKeyLength)
+	}
+
+	return nil
+}
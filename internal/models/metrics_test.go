@@ -0,0 +1,42 @@
+package models
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMetrics_Validate(t *testing.T) {
+	value, nan, delta, negative := 1.5, math.NaN(), int64(3), int64(-1)
+	longID := strings.Repeat("м", MaxIDLength)
+
+	tests := []struct {
+		name    string
+		metric  Metrics
+		wantErr bool
+	}{
+		{"gauge", Metrics{ID: "Alloc", MType: Gauge, Value: &value}, false},
+		{"counter with labels", Metrics{ID: "PollCount", MType: Counter, Delta: &delta, Labels: Labels{"host": "web1"}}, false},
+		{"histogram", Metrics{ID: "Latency", MType: Histogram, Histogram: &HistogramValue{Buckets: []float64{1}, Counts: []uint64{1, 0}, Sum: 0.5, Count: 1}}, false},
+		{"unknown type", Metrics{ID: "Alloc", MType: "set", Value: &value}, true},
+		{"empty ID", Metrics{MType: Gauge, Value: &value}, true},
+		{"reserved name", Metrics{ID: ReservedPrefix + "x", MType: Gauge, Value: &value}, true},
+		{"invalid label", Metrics{ID: "Alloc", MType: Gauge, Value: &value, Labels: Labels{"le": "1"}}, true},
+		{"missing value", Metrics{ID: "Alloc", MType: Gauge}, true},
+		{"NaN gauge", Metrics{ID: "Alloc", MType: Gauge, Value: &nan}, true},
+		{"negative delta", Metrics{ID: "PollCount", MType: Counter, Delta: &negative}, true},
+		{"missing histogram", Metrics{ID: "Latency", MType: Histogram}, true},
+		{"missing summary", Metrics{ID: "RTT", MType: Summary}, true},
+		{"longest ID", Metrics{ID: longID, MType: Gauge, Value: &value}, false},
+		{"too long ID", Metrics{ID: longID + "x", MType: Gauge, Value: &value}, true},
+		{"too long key", Metrics{ID: "Alloc", MType: Gauge, Value: &value, Labels: Labels{"host": strings.Repeat("h", MaxKeyLength)}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.metric.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
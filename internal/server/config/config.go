@@ -2,38 +2,157 @@
 package config
 
 import (
+	"crypto/rsa"
 	"fmt"
+	"net"
+	"os"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/Ko4etov/go-metrics/internal/server/config/db"
+	graphiteserver "github.com/Ko4etov/go-metrics/internal/server/graphite_server"
+	"github.com/Ko4etov/go-metrics/internal/server/middlewares"
+	"github.com/Ko4etov/go-metrics/internal/server/repository/history"
+	"github.com/Ko4etov/go-metrics/internal/server/service/alerting"
 	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+	configloader "github.com/Ko4etov/go-metrics/internal/service/config_loader"
+	hybridcrypto "github.com/Ko4etov/go-metrics/internal/service/hybrid_crypto"
 )
 
 // ServerConfig содержит все параметры конфигурации сервера.
 type ServerConfig struct {
-	ServerAddress          string        // адрес сервера
-	StoreMetricsInterval   int           // интервал сохранения метрик в секундах
-	FileStorageMetricsPath string        // путь к файлу хранения метрик
-	RestoreMetrics         bool          // восстанавливать ли метрики при старте
-	ConnectionPool         *pgxpool.Pool // пул подключений к базе данных
-	HashKey                string        // ключ для хеширования
-	AuditFile              string        // файл для аудита
-	AuditURL               string        // URL для отправки аудита
-	ProfilingEnable        bool          // включить профилирование
-	ProfileServerAddress   string        // адрес сервера профилирования
-	ProfilingDir           string        // директория для сохранения профилей
+	ServerAddress          string                  // адрес сервера
+	GRPCAddress            string                  // адрес gRPC-сервера (опционально)
+	StatsDAddress          string                  // адрес UDP-приемника StatsD (опционально)
+	StatsDFlushInterval    time.Duration           // интервал сброса метрик StatsD
+	GraphiteAddress        string                  // адрес TCP-приемника Graphite (опционально)
+	GraphiteMapping        *graphiteserver.Mapping // правила преобразования путей Graphite (опционально)
+	GraphiteMaxConnections int                     // максимальное количество соединений Graphite
+	StoreMetricsInterval   int                     // интервал сохранения метрик в секундах
+	FileStorageMetricsPath string                  // путь к файлу хранения метрик
+	RestoreMetrics         bool                    // восстанавливать ли метрики при старте
+	ConnectionPool         *pgxpool.Pool           // пул подключений к базе данных
+	HashKey                string                  // ключ для хеширования
+	HashMode               string                  // режим проверки подписи запросов
+	CryptoKey              *rsa.PrivateKey         // закрытый ключ для расшифровки запросов (опционально)
+	CryptoMode             string                  // режим проверки шифрования запросов
+	TrustedSubnet          *net.IPNet              // доверенная подсеть агентов (опционально)
+	IdempotencyTTL         time.Duration           // время хранения идентификаторов примененных батчей
+	AuditFile              string                  // файл для аудита
+	AuditURL               string                  // URL для отправки аудита
+	ProfilingEnable        bool                    // включить профилирование
+	ProfileServerAddress   string                  // адрес сервера профилирования
+	ProfilingDir           string                  // директория для сохранения профилей
+	HistoryRetention       []history.RetentionRule // правила хранения истории метрик
+	HistoryMaxPoints       int                     // количество сырых точек истории на метрику в памяти
+	AlertRules             *alerting.RuleSet       // правила оповещений (опционально)
+	AlertInterval          time.Duration           // интервал проверки правил оповещений
+	LogSampleRate          float64                 // доля запросов, попадающих в журнал доступа
+	LogSlowThreshold       time.Duration           // порог медленного запроса
+	SelfMetricsInterval    time.Duration           // интервал записи собственных метрик сервера, 0 - отключены
+	ShutdownTimeout        time.Duration           // время на корректную остановку сервера
+	ShutdownDrain          time.Duration           // задержка остановки после снятия готовности
 }
 
-// New создает новую конфигурацию сервера.
+// New создает новую конфигурацию сервера из аргументов командной строки, переменных
+// окружения и файла конфигурации. Если запрошена справка, возвращается flag.ErrHelp.
 func New() (*ServerConfig, error) {
 	var poll *pgxpool.Pool
 
-	if err := logger.Initialize("info"); err != nil {
+	// Логгер нужен уже при разборе параметров, поэтому сначала он создается
+	// с уровнем по умолчанию, а затем пересоздается с заданным уровнем.
+	if err := logger.Initialize(logLevel); err != nil {
 		return nil, fmt.Errorf("logger initialization error: %s", err)
 	}
 
-	serverParameters := parseServerParameters()
+	serverParameters, err := parseServerParameters(os.Args[1:], configloader.New(os.Args[0]))
+	if err != nil {
+		return nil, fmt.Errorf("parameters error: %w", err)
+	}
+
+	if err := logger.Initialize(serverParameters.LogLevel); err != nil {
+		return nil, fmt.Errorf("logger initialization error: %s", err)
+	}
+
+	if serverParameters.LogSampleRate < 0 || serverParameters.LogSampleRate > 1 {
+		return nil, fmt.Errorf("log sample rate must be between 0 and 1: %v", serverParameters.LogSampleRate)
+	}
+
+	if serverParameters.LogSlowThreshold < 0 {
+		return nil, fmt.Errorf("log slow threshold must not be negative: %s", serverParameters.LogSlowThreshold)
+	}
+
+	if serverParameters.StoreMetricsInterval < 0 || serverParameters.StoreMetricsInterval%time.Second != 0 {
+		return nil, fmt.Errorf("store interval must be a non-negative whole number of seconds: %s",
+			serverParameters.StoreMetricsInterval)
+	}
+
+	if err := middlewares.ValidateHashMode(serverParameters.HashMode); err != nil {
+		return nil, fmt.Errorf("hash mode error: %v", err)
+	}
+
+	if err := middlewares.ValidateCryptoMode(serverParameters.CryptoMode); err != nil {
+		return nil, fmt.Errorf("crypto mode error: %v", err)
+	}
+
+	if serverParameters.HistoryMaxPoints <= 0 {
+		return nil, fmt.Errorf("history max points must be positive: %d", serverParameters.HistoryMaxPoints)
+	}
+
+	historyRetention, err := history.ParseRetention(serverParameters.HistoryRetention)
+	if err != nil {
+		return nil, fmt.Errorf("history retention error: %v", err)
+	}
+
+	var cryptoKey *rsa.PrivateKey
+	if serverParameters.CryptoKeyPath != "" {
+		cryptoKey, err = hybridcrypto.LoadPrivateKey(serverParameters.CryptoKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("crypto key error: %v", err)
+		}
+	}
+
+	trustedSubnet, err := middlewares.ParseTrustedSubnet(serverParameters.TrustedSubnet)
+	if err != nil {
+		return nil, fmt.Errorf("trusted subnet error: %v", err)
+	}
+
+	if serverParameters.SelfMetricsInterval < 0 {
+		return nil, fmt.Errorf("self metrics interval must not be negative: %s", serverParameters.SelfMetricsInterval)
+	}
+
+	if serverParameters.ShutdownTimeout <= 0 {
+		return nil, fmt.Errorf("shutdown timeout must be positive: %s", serverParameters.ShutdownTimeout)
+	}
+
+	if serverParameters.ShutdownDrain < 0 {
+		return nil, fmt.Errorf("shutdown drain must not be negative: %s", serverParameters.ShutdownDrain)
+	}
+
+	if serverParameters.StatsDAddress != "" && serverParameters.StatsDFlushInterval <= 0 {
+		return nil, fmt.Errorf("statsd flush interval must be positive: %s", serverParameters.StatsDFlushInterval)
+	}
+
+	var graphiteMapping *graphiteserver.Mapping
+	if serverParameters.GraphiteMappingPath != "" {
+		graphiteMapping, err = graphiteserver.LoadMapping(serverParameters.GraphiteMappingPath)
+		if err != nil {
+			return nil, fmt.Errorf("graphite mapping error: %v", err)
+		}
+	}
+
+	var alertRules *alerting.RuleSet
+	if serverParameters.AlertRulesPath != "" {
+		if serverParameters.AlertInterval <= 0 {
+			return nil, fmt.Errorf("alert interval must be positive: %s", serverParameters.AlertInterval)
+		}
+
+		alertRules, err = alerting.LoadRules(serverParameters.AlertRulesPath)
+		if err != nil {
+			return nil, fmt.Errorf("alerting rules error: %v", err)
+		}
+	}
 
 	if serverParameters.DBAddress != "" {
 		if _, err := pgxpool.ParseConfig(serverParameters.DBAddress); err == nil {
@@ -54,15 +173,35 @@ func New() (*ServerConfig, error) {
 
 	return &ServerConfig{
 		ServerAddress:          serverParameters.Address,
-		StoreMetricsInterval:   serverParameters.StoreMetricsInterval,
+		GRPCAddress:            serverParameters.GRPCAddress,
+		StatsDAddress:          serverParameters.StatsDAddress,
+		StatsDFlushInterval:    serverParameters.StatsDFlushInterval,
+		GraphiteAddress:        serverParameters.GraphiteAddress,
+		GraphiteMapping:        graphiteMapping,
+		GraphiteMaxConnections: serverParameters.GraphiteMaxConnections,
+		StoreMetricsInterval:   int(serverParameters.StoreMetricsInterval / time.Second),
 		FileStorageMetricsPath: serverParameters.FileStorageMetricsPath,
 		RestoreMetrics:         serverParameters.RestoreMetrics,
 		ConnectionPool:         poll,
 		HashKey:                serverParameters.HashKey,
+		HashMode:               serverParameters.HashMode,
+		CryptoKey:              cryptoKey,
+		CryptoMode:             serverParameters.CryptoMode,
+		TrustedSubnet:          trustedSubnet,
+		IdempotencyTTL:         serverParameters.IdempotencyTTL,
 		AuditFile:              serverParameters.AuditFile,
 		AuditURL:               serverParameters.AuditURL,
 		ProfilingEnable:        serverParameters.ProfilingEnable,
 		ProfileServerAddress:   serverParameters.ProfileServerAddress,
 		ProfilingDir:           serverParameters.ProfilingDir,
+		HistoryRetention:       historyRetention,
+		HistoryMaxPoints:       serverParameters.HistoryMaxPoints,
+		AlertRules:             alertRules,
+		AlertInterval:          serverParameters.AlertInterval,
+		LogSampleRate:          serverParameters.LogSampleRate,
+		LogSlowThreshold:       serverParameters.LogSlowThreshold,
+		SelfMetricsInterval:    serverParameters.SelfMetricsInterval,
+		ShutdownTimeout:        serverParameters.ShutdownTimeout,
+		ShutdownDrain:          serverParameters.ShutdownDrain,
 	}, nil
-}
\ No newline at end of file
+}
@@ -18,4 +18,4 @@ func NewDBConnection(address string) (*pgxpool.Pool, error) {
 	}
 
 	return pool, nil
-}
\ No newline at end of file
+}
@@ -35,4 +35,4 @@ func RunMigrations(pool *pgxpool.Pool) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
@@ -2,221 +2,214 @@
 package config
 
 import (
-	"flag"
-	"os"
-	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 
+	"github.com/Ko4etov/go-metrics/internal/server/repository/history"
 	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+	configloader "github.com/Ko4etov/go-metrics/internal/service/config_loader"
 )
 
 const (
-	address                 = ":8080"           // Адрес сервера по умолчанию
-	storeMetricsInterval    = 300               // Интервал сохранения метрик по умолчанию
-	fileStorageMetricsPath  = "metrics.json"    // Путь к файлу метрик по умолчанию
-	restoreMetrics          = true              // Восстанавливать метрики по умолчанию
-	profilingEnable         = false             // Профилирование отключено по умолчанию
+	address                = ":8080"                  // Адрес сервера по умолчанию
+	storeMetricsInterval   = 300 * time.Second        // Интервал сохранения метрик по умолчанию
+	fileStorageMetricsPath = "metrics.json"           // Путь к файлу метрик по умолчанию
+	restoreMetrics         = true                     // Восстанавливать метрики по умолчанию
+	profilingEnable        = false                    // Профилирование отключено по умолчанию
+	historyRetention       = "raw:24h,1m:30d,1h:365d" // Правила хранения истории по умолчанию
+	alertInterval          = 10 * time.Second         // Интервал проверки правил оповещений по умолчанию
+	hashMode               = "log"                    // Режим проверки подписи запросов по умолчанию
+	cryptoMode             = "require"                // Режим проверки шифрования запросов по умолчанию
+	idempotencyTTL         = 24 * time.Hour           // Время хранения идентификаторов батчей по умолчанию
+	statsDFlushInterval    = time.Second              // Интервал сброса метрик StatsD по умолчанию
+	graphiteMaxConnections = 100                      // Максимальное количество соединений Graphite по умолчанию
+	logLevel               = "info"                   // Уровень логирования по умолчанию
+	logSampleRate          = 1.0                      // Доля запросов, попадающих в журнал доступа, по умолчанию
+	logSlowThreshold       = 500 * time.Millisecond   // Порог медленного запроса по умолчанию
+	selfMetricsInterval    = 10 * time.Second         // Интервал записи собственных метрик сервера по умолчанию
+	shutdownTimeout        = 10 * time.Second         // Время на корректную остановку сервера по умолчанию
+	shutdownDrain          = 0 * time.Second          // Задержка остановки после снятия готовности по умолчанию
 )
 
 // ServerParameters содержит все параметры конфигурации сервера.
 type ServerParameters struct {
-	Address                string // Адрес сервера
-	StoreMetricsInterval   int    // Интервал сохранения метрик в секундах
-	FileStorageMetricsPath string // Путь к файлу хранения метрик
-	RestoreMetrics         bool   // Восстанавливать ли метрики при старте
-	DBAddress              string // Адрес базы данных
-	HashKey                string // Ключ для хеширования
-	AuditFile              string // Файл для аудита
-	AuditURL               string // URL для отправки аудита
-	ProfilingEnable        bool   // Включить профилирование
-	ProfileServerAddress   string // Адрес сервера профилирования
-	ProfilingDir           string // Директория для сохранения профилей
+	Address                string        // Адрес сервера
+	GRPCAddress            string        // Адрес gRPC-сервера
+	StatsDAddress          string        // Адрес UDP-приемника StatsD
+	StatsDFlushInterval    time.Duration // Интервал сброса метрик StatsD
+	GraphiteAddress        string        // Адрес TCP-приемника Graphite
+	GraphiteMappingPath    string        // Путь к файлу правил преобразования путей Graphite
+	GraphiteMaxConnections int           // Максимальное количество соединений Graphite
+	StoreMetricsInterval   time.Duration // Интервал сохранения метрик
+	FileStorageMetricsPath string        // Путь к файлу хранения метрик
+	RestoreMetrics         bool          // Восстанавливать ли метрики при старте
+	DBAddress              string        // Адрес базы данных
+	HashKey                string        // Ключ для хеширования
+	HashMode               string        // Режим проверки подписи запросов
+	CryptoKeyPath          string        // Путь к закрытому ключу для расшифровки запросов
+	CryptoMode             string        // Режим проверки шифрования запросов
+	TrustedSubnet          string        // Доверенная подсеть агентов в нотации CIDR
+	IdempotencyTTL         time.Duration // Время хранения идентификаторов примененных батчей
+	AuditFile              string        // Файл для аудита
+	AuditURL               string        // URL для отправки аудита
+	ProfilingEnable        bool          // Включить профилирование
+	ProfileServerAddress   string        // Адрес сервера профилирования
+	ProfilingDir           string        // Директория для сохранения профилей
+	HistoryRetention       string        // Правила хранения истории метрик
+	HistoryMaxPoints       int           // Количество сырых точек истории на метрику в памяти
+	AlertRulesPath         string        // Путь к файлу правил оповещений
+	AlertInterval          time.Duration // Интервал проверки правил оповещений
+	LogLevel               string        // Уровень логирования
+	LogSampleRate          float64       // Доля запросов, попадающих в журнал доступа
+	LogSlowThreshold       time.Duration // Порог медленного запроса
+	SelfMetricsInterval    time.Duration // Интервал записи собственных метрик сервера
+	ShutdownTimeout        time.Duration // Время на корректную остановку сервера
+	ShutdownDrain          time.Duration // Задержка остановки после снятия готовности
 }
 
-// parseServerParameters парсит параметры сервера из переменных окружения и флагов.
-func parseServerParameters() *ServerParameters {
+// parseServerParameters разбирает параметры сервера из аргументов командной строки args,
+// переменных окружения и файла конфигурации. Приоритет источников: флаги,
+// переменные окружения, файл конфигурации, значения по умолчанию.
+func parseServerParameters(args []string, loader *configloader.Loader) (*ServerParameters, error) {
 	if err := godotenv.Load(); err != nil {
-		logger.Logger.Info(".env file not loaded: %v", err)
+		logger.Logger.Infof(".env file not loaded: %v", err)
 	}
 
-	addressParameter := addressParameter()
-	storeMetricsIntervalParameter := storeMetricsIntervalParameter()
-	fileStorageMetricsPathParameter := fileStorageMetricsPathParameter()
-	restoreMetricsParameter := restoreMetricsParameter()
-	dbAddressParameter := dbAddressParameter()
-	hashKeyParameter := hashKeyParameter()
-	auditFileParameter := auditFileParameter()
-	AuditURLParameter := auditURLParameter()
-	profilingEnableParameter := profilingEnableParameter()
-	profileServerParameter := profileServerAddressParameter()
-	profileDirParameter := profileDirParameter()
-
-	flag.Parse()
-
-	return &ServerParameters{
-		Address:                addressParameter,
-		StoreMetricsInterval:   storeMetricsIntervalParameter,
-		FileStorageMetricsPath: fileStorageMetricsPathParameter,
-		RestoreMetrics:         restoreMetricsParameter,
-		DBAddress:              dbAddressParameter,
-		HashKey:                hashKeyParameter,
-		AuditFile:              auditFileParameter,
-		AuditURL:               AuditURLParameter,
-		ProfilingEnable:        profilingEnableParameter,
-		ProfileServerAddress:   profileServerParameter,
-		ProfilingDir:           profileDirParameter,
-	}
-}
-
-// hashKeyParameter возвращает ключ для хеширования из переменных окружения или флагов.
-func hashKeyParameter() string {
-	env, ok := os.LookupEnv("KEY")
-
-	if !ok {
-		os.Exit(2)
-	}
-
-	flag.StringVar(&env, "k", env, "Hash key")
-
-	return env
-}
-
-// dbAddressParameter возвращает адрес базы данных из переменных окружения или флагов.
-func dbAddressParameter() string {
-	dbAddress := ""
-
-	if env, ok := os.LookupEnv("DATABASE_DSN"); ok {
-		dbAddress = env
-	}
-
-	flag.StringVar(&dbAddress, "d", dbAddress, "DB address")
-
-	return dbAddress
-}
-
-// addressParameter возвращает адрес сервера из переменных окружения или флагов.
-func addressParameter() string {
-	address := address
-
-	if env, ok := os.LookupEnv("ADDRESS"); ok {
-		address = env
-	}
-	flag.StringVar(&address, "a", address, "Server address")
-
-	return address
-}
-
-// storeMetricsIntervalParameter возвращает интервал сохранения метрик.
-func storeMetricsIntervalParameter() int {
-	storeMetricsInterval := storeMetricsInterval
-
-	if storeMetricsIntervalEnv, ok := os.LookupEnv("STORE_INTERVAL"); ok {
-		if val, err := strconv.Atoi(storeMetricsIntervalEnv); err == nil {
-			storeMetricsInterval = val
-		}
-	}
-	flag.IntVar(&storeMetricsInterval, "i", storeMetricsInterval, "store metrics interval in seconds")
-
-	return storeMetricsInterval
-}
-
-// fileStorageMetricsPathParameter возвращает путь к файлу хранения метрик.
-func fileStorageMetricsPathParameter() string {
-	fileStorageMetricsPath := fileStorageMetricsPath
-
-	if fileStorageMetricsPathEnv, ok := os.LookupEnv("FILE_STORAGE_PATH"); ok {
-		fileStorageMetricsPath = fileStorageMetricsPathEnv
-		return fileStorageMetricsPath
-	}
-
-	flag.StringVar(&fileStorageMetricsPath, "f", fileStorageMetricsPath, "file storage path")
-
-	return fileStorageMetricsPath
-}
-
-// restoreMetricsParameter возвращает флаг восстановления метрик.
-func restoreMetricsParameter() bool {
-	restoreMetrics := restoreMetrics
-
-	restoreMetricsEnv, ok := os.LookupEnv("RESTORE")
-
-	if ok {
-		if val, err := strconv.ParseBool(restoreMetricsEnv); err == nil {
-			restoreMetrics = val
-		}
+	p := &ServerParameters{}
+
+	loader.String(&p.Address, configloader.Param{
+		Key: "address", Env: "ADDRESS", Flag: "a",
+		Usage: "Server address",
+	}, address)
+	loader.String(&p.GRPCAddress, configloader.Param{
+		Key: "grpc_address", Env: "GRPC_ADDRESS", Flag: "grpc-address",
+		Usage: "gRPC server address",
+	}, "")
+	loader.String(&p.StatsDAddress, configloader.Param{
+		Key: "statsd_address", Env: "STATSD_ADDRESS", Flag: "statsd-addr",
+		Usage: "StatsD UDP listener address",
+	}, "")
+	loader.Duration(&p.StatsDFlushInterval, configloader.Param{
+		Key: "statsd_flush_interval", Env: "STATSD_FLUSH_INTERVAL", Flag: "statsd-flush-interval",
+		Usage: "StatsD flush interval, e.g. 1s (a bare number means seconds)",
+	}, statsDFlushInterval, time.Second)
+	loader.String(&p.GraphiteAddress, configloader.Param{
+		Key: "graphite_address", Env: "GRAPHITE_ADDRESS", Flag: "graphite-addr",
+		Usage: "Graphite plaintext TCP listener address",
+	}, "")
+	loader.String(&p.GraphiteMappingPath, configloader.Param{
+		Key: "graphite_mapping", Env: "GRAPHITE_MAPPING", Flag: "graphite-mapping",
+		Usage: "Path to Graphite mapping rules file (YAML or JSON)",
+	}, "")
+	loader.Int(&p.GraphiteMaxConnections, configloader.Param{
+		Key: "graphite_max_connections", Env: "GRAPHITE_MAX_CONNECTIONS", Flag: "graphite-max-conns",
+		Usage: "Maximum concurrent Graphite connections",
+	}, graphiteMaxConnections)
+	loader.Duration(&p.StoreMetricsInterval, configloader.Param{
+		Key: "store_interval", Env: "STORE_INTERVAL", Flag: "i",
+		Usage: "Store metrics interval, e.g. 300s (a bare number means seconds), 0 saves synchronously",
+	}, storeMetricsInterval, time.Second)
+	loader.String(&p.FileStorageMetricsPath, configloader.Param{
+		Key: "store_file", Env: "FILE_STORAGE_PATH", Flag: "f",
+		Usage: "File storage path",
+	}, fileStorageMetricsPath)
+	loader.Bool(&p.RestoreMetrics, configloader.Param{
+		Key: "restore", Env: "RESTORE", Flag: "r",
+		Usage: "Restore metrics on start",
+	}, restoreMetrics)
+	loader.String(&p.DBAddress, configloader.Param{
+		Key: "database_dsn", Env: "DATABASE_DSN", Flag: "d",
+		Usage: "DB address",
+	}, "")
+	loader.String(&p.HashKey, configloader.Param{
+		Key: "key", Env: "KEY", Flag: "k",
+		Usage: "Hash key",
+	}, "")
+	loader.String(&p.HashMode, configloader.Param{
+		Key: "hash_mode", Env: "HASH_MODE", Flag: "hash-mode",
+		Usage: "Request signature verification mode: off, log or enforce",
+	}, hashMode)
+	loader.String(&p.CryptoKeyPath, configloader.Param{
+		Key: "crypto_key", Env: "CRYPTO_KEY", Flag: "crypto-key",
+		Usage: "Path to RSA private key for request decryption",
+	}, "")
+	loader.String(&p.CryptoMode, configloader.Param{
+		Key: "crypto_mode", Env: "CRYPTO_MODE", Flag: "crypto-mode",
+		Usage: "Unencrypted write requests handling with crypto key: optional or require",
+	}, cryptoMode)
+	loader.String(&p.TrustedSubnet, configloader.Param{
+		Key: "trusted_subnet", Env: "TRUSTED_SUBNET", Flag: "t",
+		Usage: "Trusted agent subnet in CIDR notation",
+	}, "")
+	loader.Duration(&p.IdempotencyTTL, configloader.Param{
+		Key: "idempotency_ttl", Env: "IDEMPOTENCY_TTL", Flag: "idempotency-ttl",
+		Usage: "Applied batch IDs retention, e.g. 24h (a bare number means seconds)",
+	}, idempotencyTTL, time.Second)
+	loader.String(&p.AuditFile, configloader.Param{
+		Key: "audit_file", Env: "AUDIT_FILE", Flag: "audit-file",
+		Usage: "Path to audit log file",
+	}, "")
+	loader.String(&p.AuditURL, configloader.Param{
+		Key: "audit_url", Env: "AUDIT_URL", Flag: "audit-url",
+		Usage: "URL for audit log sending",
+	}, "")
+	loader.Bool(&p.ProfilingEnable, configloader.Param{
+		Key: "profile", Env: "PROFILE", Flag: "profile",
+		Usage: "Enable profiling",
+	}, profilingEnable)
+	loader.String(&p.ProfileServerAddress, configloader.Param{
+		Key: "profile_addr", Env: "PROFILE_ADDR", Flag: "profile-addr",
+		Usage: "Address for pprof server",
+	}, "")
+	loader.String(&p.ProfilingDir, configloader.Param{
+		Key: "profile_dir", Env: "PROFILE_DIR", Flag: "profile-dir",
+		Usage: "Directory for saved profiles",
+	}, "")
+	loader.String(&p.HistoryRetention, configloader.Param{
+		Key: "history_retention", Env: "HISTORY_RETENTION", Flag: "history-retention",
+		Usage: "History retention rules, e.g. raw:24h,1m:30d,1h:365d",
+	}, historyRetention)
+	loader.Int(&p.HistoryMaxPoints, configloader.Param{
+		Key: "history_max_points", Env: "HISTORY_MAX_POINTS", Flag: "history-max-points",
+		Usage: "Raw history points kept in memory per metric; size it as raw retention divided by the update interval",
+	}, history.DefaultMaxPoints)
+	loader.String(&p.AlertRulesPath, configloader.Param{
+		Key: "alert_rules", Env: "ALERT_RULES", Flag: "alert-rules",
+		Usage: "Path to alerting rules file (YAML or JSON)",
+	}, "")
+	loader.Duration(&p.AlertInterval, configloader.Param{
+		Key: "alert_interval", Env: "ALERT_INTERVAL", Flag: "alert-interval",
+		Usage: "Alerting rules evaluation interval, e.g. 10s (a bare number means seconds)",
+	}, alertInterval, time.Second)
+	loader.String(&p.LogLevel, configloader.Param{
+		Key: "log_level", Env: "LOG_LEVEL", Flag: "log-level",
+		Usage: "Log level: debug, info, warn or error",
+	}, logLevel)
+	loader.Float64(&p.LogSampleRate, configloader.Param{
+		Key: "log_sample_rate", Env: "LOG_SAMPLE_RATE", Flag: "log-sample-rate",
+		Usage: "Fraction of requests written to the access log, from 0 to 1",
+	}, logSampleRate)
+	loader.Duration(&p.LogSlowThreshold, configloader.Param{
+		Key: "log_slow_threshold", Env: "LOG_SLOW_THRESHOLD", Flag: "log-slow-threshold",
+		Usage: "Slow request threshold, e.g. 500ms (a bare number means milliseconds), 0 disables",
+	}, logSlowThreshold, time.Millisecond)
+	loader.Duration(&p.SelfMetricsInterval, configloader.Param{
+		Key: "self_metrics_interval", Env: "SELF_METRICS_INTERVAL", Flag: "self-metrics-interval",
+		Usage: "Server self-metrics publish interval, e.g. 10s (a bare number means seconds), 0 disables",
+	}, selfMetricsInterval, time.Second)
+	loader.Duration(&p.ShutdownTimeout, configloader.Param{
+		Key: "shutdown_timeout", Env: "SHUTDOWN_TIMEOUT", Flag: "shutdown-timeout",
+		Usage: "Graceful shutdown timeout, e.g. 10s (a bare number means seconds)",
+	}, shutdownTimeout, time.Second)
+	loader.Duration(&p.ShutdownDrain, configloader.Param{
+		Key: "shutdown_drain", Env: "SHUTDOWN_DRAIN", Flag: "shutdown-drain",
+		Usage: "Delay between reporting not ready and stopping the listeners, e.g. 5s (a bare number means seconds)",
+	}, shutdownDrain, time.Second)
+
+	if err := loader.Load(args); err != nil {
+		return nil, err
 	}
 
-	flag.BoolVar(&restoreMetrics, "r", restoreMetrics, "restore metrics")
-
-	return restoreMetrics
-}
-
-// auditFileParameter возвращает путь к файлу аудита.
-func auditFileParameter() string {
-	auditFile := ""
-
-	if auditFileEnv, ok := os.LookupEnv("AUDIT_FILE"); ok {
-		return auditFileEnv
-	}
-
-	flag.StringVar(&auditFile, "audit-file", auditFile, "Path to audit log file")
-
-	return auditFile
+	return p, nil
 }
-
-// auditURLParameter возвращает URL для отправки аудита.
-func auditURLParameter() string {
-	AuditURL := ""
-
-	if AuditURLEnv, ok := os.LookupEnv("AUDIT_URL"); ok {
-		return AuditURLEnv
-	}
-
-	flag.StringVar(&AuditURL, "audit-url", AuditURL, "URL for audit log sending")
-
-	return AuditURL
-}
-
-// profilingEnableParameter возвращает флаг включения профилирования.
-func profilingEnableParameter() bool {
-	profilingEnable := profilingEnable
-
-	profilingEnableEnv, ok := os.LookupEnv("PROFILE")
-
-	if ok {
-		if val, err := strconv.ParseBool(profilingEnableEnv); err == nil {
-			profilingEnable = val
-		}
-	}
-
-	flag.BoolVar(&profilingEnable, "profile", profilingEnable, "Enable profiling")
-
-	return profilingEnable
-}
-
-// profileServerAddressParameter возвращает адрес сервера профилирования.
-func profileServerAddressParameter() string {
-	profileServer := ""
-
-	if profileServerEnv, ok := os.LookupEnv("PROFILE_ADDR"); ok {
-		return profileServerEnv
-	}
-
-	flag.StringVar(&profileServer, "profile-addr", profileServer, "Address for pprof server")
-
-	return profileServer
-}
-
-// profileDirParameter возвращает директорию для сохранения профилей.
-func profileDirParameter() string {
-	profileDir := ""
-
-	if ProfileDirEnv, ok := os.LookupEnv("PROFILE_DIR"); ok {
-		return ProfileDirEnv
-	}
-
-	flag.StringVar(&profileDir, "profile-dir", profileDir, "Address for pprof server")
-
-	return profileDir
-}
\ No newline at end of file
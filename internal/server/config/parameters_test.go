@@ -0,0 +1,97 @@
+package config
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	configloader "github.com/Ko4etov/go-metrics/internal/service/config_loader"
+)
+
+// newTestLoader создает загрузчик с переменными окружения env.
+func newTestLoader(env map[string]string) *configloader.Loader {
+	loader := configloader.New("server")
+	loader.SetOutput(io.Discard)
+	loader.LookupEnv = func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+
+	return loader
+}
+
+func TestParseServerParameters_Defaults(t *testing.T) {
+	params, err := parseServerParameters(nil, newTestLoader(nil))
+	if err != nil {
+		t.Fatalf("parseServerParameters() error = %v", err)
+	}
+
+	if params.Address != address || params.StoreMetricsInterval != storeMetricsInterval ||
+		params.FileStorageMetricsPath != fileStorageMetricsPath || !params.RestoreMetrics {
+		t.Errorf("parseServerParameters() = %+v, want defaults", params)
+	}
+
+	if params.HashKey != "" {
+		t.Errorf("HashKey = %q, want empty key when KEY is not set", params.HashKey)
+	}
+}
+
+func TestParseServerParameters_ConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.json")
+	content := `{
+		"address": "localhost:9090",
+		"restore": false,
+		"store_interval": "1s",
+		"store_file": "/tmp/metrics.db",
+		"database_dsn": "",
+		"crypto_key": "/path/to/key.pem",
+		"log_slow_threshold": 250,
+		"shutdown_timeout": "30s",
+		"shutdown_drain": "5s"
+	}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	env := map[string]string{"STORE_INTERVAL": "5"}
+	args := []string{"-c", path, "-f", "/var/lib/metrics.json"}
+
+	params, err := parseServerParameters(args, newTestLoader(env))
+	if err != nil {
+		t.Fatalf("parseServerParameters() error = %v", err)
+	}
+
+	if params.Address != "localhost:9090" {
+		t.Errorf("Address = %q, want value from config file", params.Address)
+	}
+	if params.RestoreMetrics {
+		t.Error("RestoreMetrics = true, want value from config file")
+	}
+	if params.StoreMetricsInterval != 5*time.Second {
+		t.Errorf("StoreMetricsInterval = %s, want value from environment", params.StoreMetricsInterval)
+	}
+	if params.FileStorageMetricsPath != "/var/lib/metrics.json" {
+		t.Errorf("FileStorageMetricsPath = %q, want value from flag", params.FileStorageMetricsPath)
+	}
+	if params.CryptoKeyPath != "/path/to/key.pem" {
+		t.Errorf("CryptoKeyPath = %q, want value from config file", params.CryptoKeyPath)
+	}
+	if params.LogSlowThreshold != 250*time.Millisecond {
+		t.Errorf("LogSlowThreshold = %s, want 250ms", params.LogSlowThreshold)
+	}
+	if params.ShutdownTimeout != 30*time.Second {
+		t.Errorf("ShutdownTimeout = %s, want 30s", params.ShutdownTimeout)
+	}
+	if params.ShutdownDrain != 5*time.Second {
+		t.Errorf("ShutdownDrain = %s, want 5s", params.ShutdownDrain)
+	}
+}
+
+func TestParseServerParameters_InvalidValue(t *testing.T) {
+	_, err := parseServerParameters(nil, newTestLoader(map[string]string{"RESTORE": "maybe"}))
+	if err == nil {
+		t.Fatal("parseServerParameters() error = nil, want error for invalid RESTORE")
+	}
+}
@@ -0,0 +1,294 @@
+// Package graphiteserver предоставляет TCP-приемник метрик в текстовом протоколе Graphite.
+//
+// Каждая строка протокола имеет вид "path value timestamp". Путь преобразуется в имя
+// и метки метрики по правилам Mapping, значение записывается в хранилище как измеритель.
+// Строки одного соединения применяются батчами: батч сбрасывается, когда прочитаны
+// все полученные данные или накоплено maxBatchSize метрик.
+package graphiteserver
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/interfaces"
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+)
+
+const (
+	defaultMaxConnections = 100             // максимальное количество соединений по умолчанию
+	defaultMaxLineLength  = 4096            // максимальная длина строки по умолчанию
+	maxBatchSize          = 500             // максимальный размер батча метрик соединения
+	idleTimeout           = 5 * time.Minute // время бездействия, после которого соединение закрывается
+)
+
+// errLineTooLong - ошибка превышения максимальной длины строки.
+var errLineTooLong = errors.New("line too long")
+
+// GraphiteServerConfig содержит конфигурацию приемника Graphite.
+type GraphiteServerConfig struct {
+	Storage        interfaces.Storage // хранилище метрик
+	Mapping        *Mapping           // правила преобразования путей (опционально)
+	TrustedSubnet  *net.IPNet         // доверенная подсеть отправителей (опционально)
+	MaxConnections int                // максимальное количество одновременных соединений (по умолчанию 100)
+	MaxLineLength  int                // максимальная длина строки в байтах (по умолчанию 4096)
+}
+
+// Server принимает метрики Graphite по TCP и записывает их в хранилище.
+type Server struct {
+	storage       interfaces.Storage    // хранилище метрик
+	mapping       *Mapping              // правила преобразования путей
+	trustedSubnet *net.IPNet            // доверенная подсеть отправителей
+	maxLineLength int                   // максимальная длина строки
+	slots         chan struct{}         // семафор одновременных соединений
+	mu            sync.Mutex            // мьютекс для безопасного доступа к соединениям
+	listener      net.Listener          // слушатель TCP-соединений
+	conns         map[net.Conn]struct{} // активные соединения
+	stopped       bool                  // флаг остановки приемника
+	wg            sync.WaitGroup        // группа ожидания обработчиков соединений
+}
+
+// New создает приемник Graphite.
+func New(config *GraphiteServerConfig) *Server {
+	maxConnections := config.MaxConnections
+	if maxConnections <= 0 {
+		maxConnections = defaultMaxConnections
+	}
+
+	maxLineLength := config.MaxLineLength
+	if maxLineLength <= 0 {
+		maxLineLength = defaultMaxLineLength
+	}
+
+	return &Server{
+		storage:       config.Storage,
+		mapping:       config.Mapping,
+		trustedSubnet: config.TrustedSubnet,
+		maxLineLength: maxLineLength,
+		slots:         make(chan struct{}, maxConnections),
+		conns:         make(map[net.Conn]struct{}),
+	}
+}
+
+// Serve принимает соединения из listener до его закрытия методом Stop.
+// Соединения сверх лимита закрываются сразу после установления.
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return net.ErrClosed
+	}
+	s.listener = listener
+	s.mu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		if !s.trusted(conn.RemoteAddr()) {
+			logger.Logger.Warnf("[graphite] connection from untrusted address rejected: %s", conn.RemoteAddr())
+			conn.Close()
+			continue
+		}
+
+		select {
+		case s.slots <- struct{}{}:
+		default:
+			logger.Logger.Warnf("[graphite] connection limit reached, %s rejected", conn.RemoteAddr())
+			conn.Close()
+			continue
+		}
+
+		if !s.track(conn) {
+			<-s.slots
+			conn.Close()
+			return nil
+		}
+
+		go s.handleConn(conn)
+	}
+}
+
+// Stop перестает принимать соединения, дочитывает уже полученные строки
+// активных соединений, записывает их в хранилище и закрывает соединения.
+func (s *Server) Stop() {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	s.stopped = true
+
+	if s.listener != nil {
+		s.listener.Close()
+	}
+
+	// Прерываем ожидание новых данных: обработчики сбросят накопленные батчи и завершатся.
+	for conn := range s.conns {
+		conn.SetReadDeadline(time.Now())
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+// track регистрирует активное соединение. Возвращает false, если приемник остановлен.
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return false
+	}
+
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+
+	return true
+}
+
+// untrack снимает соединение с учета и освобождает слот.
+func (s *Server) untrack(conn net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+
+	<-s.slots
+	s.wg.Done()
+}
+
+// trusted проверяет, что адрес отправителя входит в доверенную подсеть.
+func (s *Server) trusted(addr net.Addr) bool {
+	if s.trustedSubnet == nil {
+		return true
+	}
+
+	tcpAddr, ok := addr.(*net.TCPAddr)
+
+	return ok && s.trustedSubnet.Contains(tcpAddr.IP)
+}
+
+// handleConn читает строки соединения и записывает метрики в хранилище батчами.
+func (s *Server) handleConn(conn net.Conn) {
+	defer s.untrack(conn)
+	defer conn.Close()
+
+	source := conn.RemoteAddr().String()
+	if tcpAddr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		source = tcpAddr.IP.String()
+	}
+
+	reader := bufio.NewReaderSize(conn, s.maxLineLength)
+	batch := make([]models.Metrics, 0, maxBatchSize)
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.storage.UpdateMetricsBatchFrom(source, batch); err != nil {
+			logger.Logger.Errorf("[graphite] failed to update metrics from %s: %v", source, err)
+		}
+		batch = batch[:0]
+	}
+	defer flush()
+
+	for {
+		s.extendDeadline(conn)
+
+		line, err := readLine(reader)
+		if err != nil {
+			switch {
+			case errors.Is(err, io.EOF), errors.Is(err, os.ErrDeadlineExceeded):
+			case errors.Is(err, errLineTooLong):
+				logger.Logger.Warnf("[graphite] %s: line exceeds %d bytes, closing connection", source, s.maxLineLength)
+			default:
+				logger.Logger.Warnf("[graphite] %s: read error: %v", source, err)
+			}
+			return
+		}
+
+		if line != "" {
+			metric, err := s.parseLine(line)
+			if err != nil {
+				logger.Logger.Warnf("[graphite] %s: %v", source, err)
+			} else {
+				batch = append(batch, metric)
+			}
+		}
+
+		if reader.Buffered() == 0 || len(batch) >= maxBatchSize {
+			flush()
+		}
+	}
+}
+
+// extendDeadline продлевает ожидание данных соединения, пока приемник не остановлен.
+// Выполняется под мьютексом, чтобы не перезаписать срок, выставленный в Stop.
+func (s *Server) extendDeadline(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.stopped {
+		conn.SetReadDeadline(time.Now().Add(idleTimeout))
+	}
+}
+
+// readLine читает строку без завершающих символов перевода строки.
+// Последняя строка без перевода строки перед закрытием соединения или остановкой
+// приемника также возвращается.
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadSlice('\n')
+	switch {
+	case errors.Is(err, bufio.ErrBufferFull):
+		return "", errLineTooLong
+	case (errors.Is(err, io.EOF) || errors.Is(err, os.ErrDeadlineExceeded)) && len(line) > 0:
+		err = nil
+	case err != nil:
+		return "", err
+	}
+
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+// parseLine разбирает строку вида "path value timestamp" в измеритель.
+// Временная метка проверяется, но не используется: хранилище содержит текущие значения.
+func (s *Server) parseLine(line string) (models.Metrics, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 3 {
+		return models.Metrics{}, fmt.Errorf("invalid line %q: expected path, value and timestamp", line)
+	}
+
+	value, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return models.Metrics{}, fmt.Errorf("invalid value in line %q", line)
+	}
+
+	if _, err := strconv.ParseFloat(fields[2], 64); err != nil {
+		return models.Metrics{}, fmt.Errorf("invalid timestamp in line %q", line)
+	}
+
+	id, labels := s.mapping.Map(fields[0])
+	if id == "" {
+		return models.Metrics{}, fmt.Errorf("invalid path in line %q", line)
+	}
+	metric := models.Metrics{ID: id, MType: models.Gauge, Value: &value, Labels: labels}
+	if err := metric.Validate(); err != nil {
+		return models.Metrics{}, fmt.Errorf("invalid metric in line %q: %w", line, err)
+	}
+
+	return metric, nil
+}
@@ -0,0 +1,201 @@
+package graphiteserver
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/repository/storage"
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+)
+
+func TestMapping_Map(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mapping.yaml")
+	content := `mappings:
+  - match: servers.*.cpu.*
+    name: cpu_$2
+    labels:
+      host: $1
+  - match: apps.*.requests
+    name: ${1}_requests_total
+    labels:
+      app: $1
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write mapping: %v", err)
+	}
+
+	mapping, err := LoadMapping(path)
+	if err != nil {
+		t.Fatalf("LoadMapping failed: %v", err)
+	}
+
+	tests := []struct {
+		path   string
+		name   string
+		labels string
+	}{
+		{"servers.web1.cpu.idle", "cpu_idle", `{host="web1"}`},
+		{"apps.billing.requests", "billing_requests_total", `{app="billing"}`},
+		{"servers.web1.mem.free", "servers_web1_mem_free", ""},
+		{"servers.web-1.cpu.idle.extra", "servers_web_1_cpu_idle_extra", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			name, labels := mapping.Map(tt.path)
+			if name != tt.name || labels.String() != tt.labels {
+				t.Errorf("Map(%q) = %s%s, want %s%s", tt.path, name, labels.String(), tt.name, tt.labels)
+			}
+		})
+	}
+
+	invalid := &Mapping{Rules: []MappingRule{{Match: "a.*", Labels: map[string]string{"__name__": "$1"}}}}
+	if err := invalid.Validate(); err == nil {
+		t.Error("expected error for reserved label name")
+	}
+}
+
+// startServer запускает приемник на свободном порту и возвращает его адрес.
+func startServer(t *testing.T, config *GraphiteServerConfig) (*Server, string, chan error) {
+	t.Helper()
+	logger.Initialize("error")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	server := New(config)
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(listener) }()
+
+	return server, listener.Addr().String(), served
+}
+
+// waitFor ожидает выполнения условия.
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition was not met in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServer_WritesGauges(t *testing.T) {
+	store := storage.New(&storage.MetricsStorageConfig{})
+	mapping := &Mapping{Rules: []MappingRule{{Match: "servers.*.load", Name: "load", Labels: map[string]string{"host": "$1"}}}}
+	if err := mapping.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	server, addr, served := startServer(t, &GraphiteServerConfig{Storage: store, Mapping: mapping})
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	conn.Write([]byte("servers.web1.load 1.5 1700000000\r\nbad line\nqueue.size 7 -1\n"))
+
+	waitFor(t, func() bool {
+		_, ok := store.Metric("queue_size")
+		return ok
+	})
+
+	metric, ok := store.Metric(`load{host="web1"}`)
+	if !ok || metric.MType != models.Gauge || *metric.Value != 1.5 {
+		t.Errorf("expected gauge load{host=\"web1\"} 1.5, got %+v", metric)
+	}
+
+	// Строка без перевода строки записывается при остановке приемника.
+	conn.Write([]byte("queue.size 9 1700000000"))
+	time.Sleep(50 * time.Millisecond)
+
+	server.Stop()
+	if err := <-served; err != nil {
+		t.Fatalf("Serve returned error: %v", err)
+	}
+
+	if metric, _ := store.Metric("queue_size"); *metric.Value != 9 {
+		t.Errorf("expected queue_size 9 after Stop, got %v", *metric.Value)
+	}
+
+	if _, err := net.Dial("tcp", addr); err == nil {
+		t.Error("expected listener to be closed after Stop")
+	}
+}
+
+func TestServer_StopFlushesPendingLines(t *testing.T) {
+	store := storage.New(&storage.MetricsStorageConfig{})
+	server, addr, _ := startServer(t, &GraphiteServerConfig{Storage: store})
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	conn.Write([]byte("pending.value 3 1700000000"))
+	waitFor(t, func() bool {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		return len(server.conns) == 1
+	})
+	time.Sleep(50 * time.Millisecond)
+
+	server.Stop()
+
+	if metric, ok := store.Metric("pending_value"); !ok || *metric.Value != 3 {
+		t.Errorf("expected pending line to be written on Stop, got %+v", metric)
+	}
+}
+
+func TestServer_Limits(t *testing.T) {
+	store := storage.New(&storage.MetricsStorageConfig{})
+	server, addr, _ := startServer(t, &GraphiteServerConfig{Storage: store, MaxConnections: 1, MaxLineLength: 64})
+	defer server.Stop()
+
+	first, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer first.Close()
+
+	waitFor(t, func() bool {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		return len(server.conns) == 1
+	})
+
+	second, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer second.Close()
+
+	second.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := bufio.NewReader(second).ReadByte(); err == nil {
+		t.Error("expected connection over the limit to be closed")
+	}
+
+	first.Write([]byte(strings.Repeat("a", 100) + " 1 1700000000\n"))
+	first.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := bufio.NewReader(first).ReadByte(); err == nil {
+		t.Error("expected connection with a too long line to be closed")
+	}
+
+	if len(store.Metrics()) != 0 {
+		t.Errorf("expected no metrics to be stored, got %d", len(store.Metrics()))
+	}
+}
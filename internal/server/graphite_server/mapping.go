@@ -0,0 +1,139 @@
+package graphiteserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+)
+
+// MappingRule описывает преобразование пути Graphite в имя и метки метрики.
+//
+// Шаблон Match состоит из сегментов пути, разделенных точками; сегмент * совпадает
+// с любым одним сегментом. Значения сегментов, совпавших с *, подставляются в имя
+// и метки как $1, $2 и т. д.; если за номером следуют буквы, цифры или подчеркивание,
+// используется форма ${1}. Например, правило
+//
+//	match: servers.*.cpu.*
+//	name: cpu_$2
+//	labels: {host: $1}
+//
+// преобразует путь servers.web1.cpu.idle в метрику cpu_idle{host="web1"}.
+type MappingRule struct {
+	Match  string            `json:"match" yaml:"match"`                       // шаблон пути
+	Name   string            `json:"name,omitempty" yaml:"name,omitempty"`     // шаблон имени метрики
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"` // шаблоны значений меток
+
+	re *regexp.Regexp // скомпилированный шаблон пути
+}
+
+// Mapping содержит правила преобразования путей Graphite в метрики.
+// Применяется первое совпавшее правило; путь без совпадений становится
+// именем метрики с заменой точек на подчеркивания.
+type Mapping struct {
+	Rules []MappingRule `json:"mappings" yaml:"mappings"` // правила преобразования
+}
+
+// LoadMapping загружает правила преобразования из YAML- или JSON-файла.
+// Формат определяется по расширению файла: .yaml и .yml - YAML, остальные - JSON.
+func LoadMapping(path string) (*Mapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mapping file: %w", err)
+	}
+
+	var mapping Mapping
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &mapping)
+	default:
+		err = json.Unmarshal(data, &mapping)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mapping file %s: %w", path, err)
+	}
+
+	if err := mapping.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid mapping file %s: %w", path, err)
+	}
+
+	return &mapping, nil
+}
+
+// Validate проверяет правила и компилирует шаблоны путей.
+func (m *Mapping) Validate() error {
+	for i := range m.Rules {
+		rule := &m.Rules[i]
+
+		if rule.Match == "" {
+			return fmt.Errorf("mapping %d: match is required", i+1)
+		}
+
+		segments := strings.Split(rule.Match, ".")
+		for j, segment := range segments {
+			switch {
+			case segment == "":
+				return fmt.Errorf("mapping %s: empty path segment", rule.Match)
+			case segment == "*":
+				segments[j] = `([^.]+)`
+			default:
+				segments[j] = regexp.QuoteMeta(segment)
+			}
+		}
+		rule.re = regexp.MustCompile(`^` + strings.Join(segments, `\.`) + `$`)
+
+		labels := make(models.Labels, len(rule.Labels))
+		for name := range rule.Labels {
+			labels[name] = "x"
+		}
+		if err := labels.Validate(); err != nil {
+			return fmt.Errorf("mapping %s: %w", rule.Match, err)
+		}
+	}
+
+	return nil
+}
+
+// Map возвращает имя и метки метрики для пути Graphite.
+// Метки, значение которых после подстановки оказалось пустым, пропускаются.
+func (m *Mapping) Map(path string) (string, models.Labels) {
+	if m != nil {
+		for i := range m.Rules {
+			rule := &m.Rules[i]
+
+			match := rule.re.FindStringSubmatchIndex(path)
+			if match == nil {
+				continue
+			}
+
+			name := path
+			if rule.Name != "" {
+				name = string(rule.re.ExpandString(nil, rule.Name, path, match))
+			}
+
+			var labels models.Labels
+			for label, template := range rule.Labels {
+				value := string(rule.re.ExpandString(nil, template, path, match))
+				if value == "" {
+					continue
+				}
+				if labels == nil {
+					labels = make(models.Labels, len(rule.Labels))
+				}
+				labels[label] = value
+			}
+
+			return models.SanitizeName(name), labels
+		}
+	}
+
+	return models.SanitizeName(path), nil
+}
@@ -0,0 +1,221 @@
+// Package grpcserver предоставляет gRPC-сервис приема и чтения метрик.
+//
+// Сервис работает с тем же хранилищем, что и HTTP API, и повторяет его проверки:
+// подпись батчей, доверенную подсеть агентов и логирование запросов.
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sort"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/Ko4etov/go-metrics/api/proto"
+	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/interfaces"
+	"github.com/Ko4etov/go-metrics/internal/server/middlewares"
+	"github.com/Ko4etov/go-metrics/internal/server/repository/idempotency"
+	"github.com/Ko4etov/go-metrics/internal/server/service/selfmetrics"
+)
+
+// MetricsServerConfig содержит конфигурацию gRPC-сервиса метрик.
+type MetricsServerConfig struct {
+	Storage       interfaces.Storage    // хранилище метрик
+	HashKey       string                // ключ для проверки подписи батчей
+	HashMode      string                // режим проверки подписи батчей
+	TrustedSubnet *net.IPNet            // доверенная подсеть агентов (опционально)
+	SelfMetrics   *selfmetrics.Registry // собственные метрики сервера (опционально)
+}
+
+// MetricsServer реализует gRPC-сервис метрик.
+type MetricsServer struct {
+	pb.UnimplementedMetricsServer
+
+	storage interfaces.Storage // хранилище метрик
+}
+
+// New создает gRPC-сервер с зарегистрированным сервисом метрик и перехватчиками.
+func New(config *MetricsServerConfig) *grpc.Server {
+	hashConfig := &middlewares.HashConfig{
+		SecretKey:   config.HashKey,
+		Mode:        config.HashMode,
+		SelfMetrics: config.SelfMetrics,
+	}
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			unaryLogging,
+			unaryTrustedSubnet(config.TrustedSubnet),
+			unaryHashing(hashConfig),
+		),
+		grpc.ChainStreamInterceptor(
+			streamLogging,
+			streamTrustedSubnet(config.TrustedSubnet),
+			streamHashing(hashConfig),
+		),
+	)
+
+	pb.RegisterMetricsServer(server, &MetricsServer{storage: config.Storage})
+
+	return server
+}
+
+// UpdateMetrics применяет один батч метрик.
+func (s *MetricsServer) UpdateMetrics(ctx context.Context, req *pb.UpdateMetricsRequest) (*pb.UpdateMetricsResponse, error) {
+	resp := &pb.UpdateMetricsResponse{}
+
+	if err := s.applyBatch(ctx, req, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// StreamMetrics применяет поток батчей метрик.
+// Батчи применяются по мере получения, итог возвращается после закрытия потока клиентом.
+func (s *MetricsServer) StreamMetrics(stream grpc.ClientStreamingServer[pb.UpdateMetricsRequest, pb.UpdateMetricsResponse]) error {
+	resp := &pb.UpdateMetricsResponse{}
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(resp)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := s.applyBatch(stream.Context(), req, resp); err != nil {
+			return err
+		}
+	}
+}
+
+// GetMetric возвращает значение метрики.
+func (s *MetricsServer) GetMetric(ctx context.Context, req *pb.GetMetricRequest) (*pb.GetMetricResponse, error) {
+	mType := pb.ModelType(req.GetType())
+	if req.GetId() == "" || mType == "" {
+		return nil, status.Error(codes.InvalidArgument, "metric id and type are required")
+	}
+
+	labels := models.Labels(req.GetLabels())
+	if err := labels.Validate(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid labels: %v", err)
+	}
+
+	key := models.MetricKey(req.GetId(), labels)
+
+	var metric *models.Metrics
+	var err error
+
+	switch mType {
+	case models.Gauge:
+		metric, err = s.storage.GaugeMetricModel(key)
+	case models.Counter:
+		metric, err = s.storage.CounterMetricModel(key)
+	case models.Histogram, models.Summary:
+		stored, ok := s.storage.Metric(key)
+		if !ok || stored.MType != mType {
+			return nil, status.Error(codes.NotFound, "metric not found")
+		}
+		metric = &stored
+	}
+
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "metric not found")
+	}
+
+	return &pb.GetMetricResponse{Metric: pb.FromModel(*metric)}, nil
+}
+
+// ListMetrics возвращает метрики, удовлетворяющие условиям отбора по меткам,
+// упорядоченные по ключу ряда.
+func (s *MetricsServer) ListMetrics(ctx context.Context, req *pb.ListMetricsRequest) (*pb.ListMetricsResponse, error) {
+	matchers, err := models.ParseLabelMatchers(req.GetMatch())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid label matcher: %v", err)
+	}
+
+	metrics := make([]models.Metrics, 0, len(s.storage.Metrics()))
+	for _, metric := range s.storage.Metrics() {
+		if models.MatchLabels(metric.Labels, matchers) {
+			metrics = append(metrics, metric)
+		}
+	}
+
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].Key() < metrics[j].Key()
+	})
+
+	resp := &pb.ListMetricsResponse{Metrics: make([]*pb.Metric, 0, len(metrics))}
+	for _, metric := range metrics {
+		resp.Metrics = append(resp.Metrics, pb.FromModel(metric))
+	}
+
+	return resp, nil
+}
+
+// applyBatch проверяет и применяет батч метрик, обновляя итог resp.
+func (s *MetricsServer) applyBatch(ctx context.Context, req *pb.UpdateMetricsRequest, resp *pb.UpdateMetricsResponse) error {
+	if len(req.GetMetrics()) == 0 {
+		return status.Error(codes.InvalidArgument, "empty metrics batch")
+	}
+
+	if err := idempotency.ValidateKey(req.GetBatchId()); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid batch ID: %v", err)
+	}
+
+	metrics := make([]models.Metrics, 0, len(req.GetMetrics()))
+
+	for _, m := range req.GetMetrics() {
+		metric, err := pb.ToModel(m)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid metric: %v", err)
+		}
+
+		if err := metric.Validate(); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid metric: %v", err)
+		}
+
+		metrics = append(metrics, metric)
+	}
+
+	applied, err := s.storage.UpdateMetricsBatchOnce(clientAddress(ctx), req.GetBatchId(), metrics)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to update metrics: %v", err)
+	}
+
+	if applied {
+		resp.Applied++
+	} else {
+		resp.Replayed++
+	}
+
+	return nil
+}
+
+// clientAddress возвращает IP-адрес агента: проверенный перехватчиком доверенной
+// подсети или адрес соединения.
+func clientAddress(ctx context.Context) string {
+	if ip, ok := middlewares.ClientIP(ctx); ok {
+		return ip
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
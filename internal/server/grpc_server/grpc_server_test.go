@@ -0,0 +1,341 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "github.com/Ko4etov/go-metrics/api/proto"
+	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/middlewares"
+	"github.com/Ko4etov/go-metrics/internal/server/repository/idempotency"
+	"github.com/Ko4etov/go-metrics/internal/server/repository/storage"
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+)
+
+// newTestClient запускает gRPC-сервер в памяти и возвращает клиента к нему.
+func newTestClient(t *testing.T, config *MetricsServerConfig) pb.MetricsClient {
+	t.Helper()
+	logger.Initialize("error")
+
+	if config.Storage == nil {
+		config.Storage = storage.New(&storage.MetricsStorageConfig{
+			Idempotency: idempotency.New(&idempotency.AppliedBatchesConfig{}),
+		})
+	}
+
+	lis := bufconn.Listen(1024 * 1024)
+	server := New(config)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewMetricsClient(conn)
+}
+
+func hmacHex(data []byte, key string) string {
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func testBatch(batchID string) *pb.UpdateMetricsRequest {
+	return &pb.UpdateMetricsRequest{
+		BatchId: batchID,
+		Metrics: []*pb.Metric{
+			{Id: "Alloc", Type: pb.MetricType_GAUGE, Value: 12.5},
+			{Id: "PollCount", Type: pb.MetricType_COUNTER, Delta: 3},
+		},
+	}
+}
+
+func TestMetricsServer_UpdateAndQuery(t *testing.T) {
+	client := newTestClient(t, &MetricsServerConfig{})
+	ctx := context.Background()
+
+	resp, err := client.UpdateMetrics(ctx, testBatch("batch-1"))
+	if err != nil {
+		t.Fatalf("UpdateMetrics failed: %v", err)
+	}
+	if resp.GetApplied() != 1 {
+		t.Errorf("Expected 1 applied batch, got %d", resp.GetApplied())
+	}
+
+	// Повтор батча с тем же идентификатором не применяется.
+	resp, err = client.UpdateMetrics(ctx, testBatch("batch-1"))
+	if err != nil {
+		t.Fatalf("repeated UpdateMetrics failed: %v", err)
+	}
+	if resp.GetReplayed() != 1 {
+		t.Errorf("Expected replayed batch, got %+v", resp)
+	}
+
+	got, err := client.GetMetric(ctx, &pb.GetMetricRequest{Id: "PollCount", Type: pb.MetricType_COUNTER})
+	if err != nil {
+		t.Fatalf("GetMetric failed: %v", err)
+	}
+	if got.GetMetric().GetDelta() != 3 {
+		t.Errorf("Expected counter 3, got %d", got.GetMetric().GetDelta())
+	}
+
+	_, err = client.GetMetric(ctx, &pb.GetMetricRequest{Id: "Alloc", Type: pb.MetricType_COUNTER})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for type mismatch, got %v", err)
+	}
+
+	list, err := client.ListMetrics(ctx, &pb.ListMetricsRequest{})
+	if err != nil {
+		t.Fatalf("ListMetrics failed: %v", err)
+	}
+	if len(list.GetMetrics()) != 2 || list.GetMetrics()[0].GetId() != "Alloc" {
+		t.Errorf("Unexpected metrics list: %v", list.GetMetrics())
+	}
+}
+
+func TestMetricsServer_StreamMetrics(t *testing.T) {
+	client := newTestClient(t, &MetricsServerConfig{})
+	ctx := context.Background()
+
+	stream, err := client.StreamMetrics(ctx)
+	if err != nil {
+		t.Fatalf("StreamMetrics failed: %v", err)
+	}
+
+	for _, id := range []string{"a", "b", "a"} {
+		if err := stream.Send(testBatch(id)); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("CloseAndRecv failed: %v", err)
+	}
+	if resp.GetApplied() != 2 || resp.GetReplayed() != 1 {
+		t.Errorf("Expected 2 applied and 1 replayed, got %+v", resp)
+	}
+
+	got, err := client.GetMetric(ctx, &pb.GetMetricRequest{Id: "PollCount", Type: pb.MetricType_COUNTER})
+	if err != nil {
+		t.Fatalf("GetMetric failed: %v", err)
+	}
+	if got.GetMetric().GetDelta() != 6 {
+		t.Errorf("Expected counter 6, got %d", got.GetMetric().GetDelta())
+	}
+}
+
+func TestMetricsServer_InvalidBatch(t *testing.T) {
+	client := newTestClient(t, &MetricsServerConfig{})
+
+	tests := []struct {
+		name string
+		req  *pb.UpdateMetricsRequest
+	}{
+		{"empty batch", &pb.UpdateMetricsRequest{}},
+		{"unknown type", &pb.UpdateMetricsRequest{Metrics: []*pb.Metric{{Id: "x"}}}},
+		{"empty id", &pb.UpdateMetricsRequest{Metrics: []*pb.Metric{{Type: pb.MetricType_GAUGE}}}},
+		{"negative delta", &pb.UpdateMetricsRequest{Metrics: []*pb.Metric{{Id: "x", Type: pb.MetricType_COUNTER, Delta: -1}}}},
+		{"long batch ID", testBatch(strings.Repeat("k", idempotency.MaxKeyLength+1))},
+		{"non-ASCII batch ID", testBatch("батч")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.UpdateMetrics(context.Background(), tt.req)
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("Expected InvalidArgument, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMetricsServer_Hashing(t *testing.T) {
+	const key = "secret"
+
+	sign := func(req *pb.UpdateMetricsRequest, key string) *pb.UpdateMetricsRequest {
+		data, _ := req.SignedData()
+		req.Hash = hmacHex(data, key)
+		return req
+	}
+
+	tests := []struct {
+		name     string
+		mode     string
+		req      *pb.UpdateMetricsRequest
+		wantCode codes.Code
+	}{
+		{"enforce valid", middlewares.HashModeEnforce, sign(testBatch("1"), key), codes.OK},
+		{"enforce unsigned", middlewares.HashModeEnforce, testBatch("2"), codes.InvalidArgument},
+		{"enforce tampered", middlewares.HashModeEnforce, sign(testBatch("3"), "other"), codes.InvalidArgument},
+		{"log tampered", middlewares.HashModeLog, sign(testBatch("4"), "other"), codes.OK},
+		{"off tampered", middlewares.HashModeOff, sign(testBatch("5"), "other"), codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, &MetricsServerConfig{HashKey: key, HashMode: tt.mode})
+
+			_, err := client.UpdateMetrics(context.Background(), tt.req)
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("Expected %v, got %v", tt.wantCode, err)
+			}
+
+			stream, err := client.StreamMetrics(context.Background())
+			if err != nil {
+				t.Fatalf("StreamMetrics failed: %v", err)
+			}
+			stream.Send(tt.req)
+			_, err = stream.CloseAndRecv()
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("Expected %v for stream, got %v", tt.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestMetricsServer_TrustedSubnet(t *testing.T) {
+	subnet, _ := middlewares.ParseTrustedSubnet("192.168.1.0/24")
+	client := newTestClient(t, &MetricsServerConfig{TrustedSubnet: subnet})
+
+	tests := []struct {
+		name     string
+		realIP   string
+		wantCode codes.Code
+	}{
+		{"inside subnet", "192.168.1.10", codes.OK},
+		{"outside subnet", "10.0.0.1", codes.PermissionDenied},
+		{"missing metadata", "", codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.realIP != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, RealIPMetadataKey, tt.realIP)
+			}
+
+			_, err := client.UpdateMetrics(ctx, testBatch(""))
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("Expected %v, got %v", tt.wantCode, err)
+			}
+		})
+	}
+
+	// Чтение метрик доступно без проверки подсети.
+	if _, err := client.ListMetrics(context.Background(), &pb.ListMetricsRequest{}); err != nil {
+		t.Errorf("Expected ListMetrics to be allowed, got %v", err)
+	}
+}
+
+func TestMetricsServer_Labels(t *testing.T) {
+	client := newTestClient(t, &MetricsServerConfig{})
+	ctx := context.Background()
+
+	_, err := client.UpdateMetrics(ctx, &pb.UpdateMetricsRequest{Metrics: []*pb.Metric{
+		{Id: "Alloc", Type: pb.MetricType_GAUGE, Value: 1, Labels: map[string]string{"host": "web1"}},
+		{Id: "Alloc", Type: pb.MetricType_GAUGE, Value: 2, Labels: map[string]string{"host": "web2"}},
+	}})
+	if err != nil {
+		t.Fatalf("UpdateMetrics failed: %v", err)
+	}
+
+	got, err := client.GetMetric(ctx, &pb.GetMetricRequest{
+		Id: "Alloc", Type: pb.MetricType_GAUGE, Labels: map[string]string{"host": "web2"},
+	})
+	if err != nil {
+		t.Fatalf("GetMetric failed: %v", err)
+	}
+	if got.GetMetric().GetValue() != 2 {
+		t.Errorf("Expected value 2 for host web2, got %v", got.GetMetric().GetValue())
+	}
+
+	list, err := client.ListMetrics(ctx, &pb.ListMetricsRequest{Match: []string{"host!=web2"}})
+	if err != nil {
+		t.Fatalf("ListMetrics failed: %v", err)
+	}
+	if len(list.GetMetrics()) != 1 || list.GetMetrics()[0].GetLabels()["host"] != "web1" {
+		t.Errorf("Unexpected metrics list: %v", list.GetMetrics())
+	}
+
+	_, err = client.ListMetrics(ctx, &pb.ListMetricsRequest{Match: []string{"host"}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for invalid matcher, got %v", err)
+	}
+}
+
+func TestMetricsServer_Distributions(t *testing.T) {
+	client := newTestClient(t, &MetricsServerConfig{})
+	ctx := context.Background()
+
+	value := 12.5
+	histogram := models.Metrics{ID: "PollDuration", MType: models.Histogram, Histogram: &models.HistogramValue{
+		Buckets: []float64{0.1, 1}, Counts: []uint64{2, 1, 0}, Sum: 0.7, Count: 3,
+	}}
+	summary := models.Metrics{ID: "RTT", MType: models.Summary, Summary: &models.SummaryValue{
+		Quantiles: []models.Quantile{{Quantile: 0.5, Value: 10}, {Quantile: 0.99, Value: 40}}, Sum: 55, Count: 4,
+	}}
+
+	// Батч формируется так же, как в агенте: гистограмма отправляется вместе с измерителями.
+	req := &pb.UpdateMetricsRequest{BatchId: "batch-1"}
+	for _, metric := range []models.Metrics{{ID: "Alloc", MType: models.Gauge, Value: &value}, histogram, summary} {
+		req.Metrics = append(req.Metrics, pb.FromModel(metric))
+	}
+
+	if _, err := client.UpdateMetrics(ctx, req); err != nil {
+		t.Fatalf("UpdateMetrics failed: %v", err)
+	}
+
+	for _, want := range []models.Metrics{histogram, summary} {
+		got, err := client.GetMetric(ctx, &pb.GetMetricRequest{Id: want.ID, Type: pb.FromModel(want).GetType()})
+		if err != nil {
+			t.Fatalf("GetMetric(%s) failed: %v", want.ID, err)
+		}
+
+		metric, err := pb.ToModel(got.GetMetric())
+		if err != nil {
+			t.Fatalf("ToModel(%s) failed: %v", want.ID, err)
+		}
+		if !reflect.DeepEqual(metric, want) {
+			t.Errorf("GetMetric(%s) = %+v, want %+v", want.ID, metric, want)
+		}
+	}
+
+	list, err := client.ListMetrics(ctx, &pb.ListMetricsRequest{})
+	if err != nil {
+		t.Fatalf("ListMetrics failed: %v", err)
+	}
+	for _, m := range list.GetMetrics() {
+		if _, err := pb.ToModel(m); err != nil {
+			t.Errorf("ListMetrics returned invalid metric %s: %v", m.GetId(), err)
+		}
+	}
+
+	_, err = client.UpdateMetrics(ctx, &pb.UpdateMetricsRequest{Metrics: []*pb.Metric{
+		{Id: "Broken", Type: pb.MetricType_HISTOGRAM, Histogram: &pb.Histogram{Buckets: []float64{1}, Counts: []uint64{1}}},
+	}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for inconsistent histogram, got %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/Ko4etov/go-metrics/api/proto"
+	"github.com/Ko4etov/go-metrics/internal/server/middlewares"
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+)
+
+// RealIPMetadataKey - ключ метаданных с IP-адресом агента, аналог заголовка X-Real-IP.
+const RealIPMetadataKey = "x-real-ip"
+
+// writeMethods - методы записи метрик, доступные только агентам из доверенной подсети.
+var writeMethods = map[string]bool{
+	pb.Metrics_UpdateMetrics_FullMethodName: true,
+	pb.Metrics_StreamMetrics_FullMethodName: true,
+}
+
+// unaryLogging логирует унарные вызовы.
+func unaryLogging(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+
+	resp, err := handler(ctx, req)
+
+	logCall(info.FullMethod, start, err)
+
+	return resp, err
+}
+
+// streamLogging логирует потоковые вызовы.
+func streamLogging(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+
+	err := handler(srv, ss)
+
+	logCall(info.FullMethod, start, err)
+
+	return err
+}
+
+// logCall записывает в лог метод, код ответа и длительность вызова.
+func logCall(method string, start time.Time, err error) {
+	logger.Logger.Infof("gRPC %s %s %s", method, status.Code(err), time.Since(start))
+}
+
+// checkTrustedSubnet проверяет, что агент из метаданных вызова входит в доверенную подсеть.
+// Возвращает контекст с проверенным IP-адресом агента.
+func checkTrustedSubnet(ctx context.Context, subnet *net.IPNet, method string) (context.Context, error) {
+	if subnet == nil || !writeMethods[method] {
+		return ctx, nil
+	}
+
+	var realIP string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(RealIPMetadataKey); len(values) > 0 {
+			realIP = values[0]
+		}
+	}
+
+	ip := net.ParseIP(realIP)
+	if ip == nil || !subnet.Contains(ip) {
+		logger.Logger.Warnf("Request from untrusted address rejected: %s (x-real-ip %q, remote %s)",
+			method, realIP, clientAddress(ctx))
+		return ctx, status.Error(codes.PermissionDenied, "forbidden")
+	}
+
+	return middlewares.WithClientIP(ctx, ip.String()), nil
+}
+
+// unaryTrustedSubnet возвращает перехватчик доверенной подсети для унарных вызовов.
+func unaryTrustedSubnet(subnet *net.IPNet) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := checkTrustedSubnet(ctx, subnet, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// streamTrustedSubnet возвращает перехватчик доверенной подсети для потоковых вызовов.
+func streamTrustedSubnet(subnet *net.IPNet) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := checkTrustedSubnet(ss.Context(), subnet, info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// contextStream подменяет контекст потокового вызова.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context возвращает контекст вызова.
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// verifyHash проверяет подпись батча метрик. Сообщения других типов не подписываются.
+func verifyHash(config *middlewares.HashConfig, msg any, method string) error {
+	req, ok := msg.(*pb.UpdateMetricsRequest)
+	if !ok {
+		return nil
+	}
+
+	data, err := req.SignedData()
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "failed to serialize request: %v", err)
+	}
+
+	if err := config.Verify(data, req.GetHash(), method); err != nil {
+		return status.Error(codes.InvalidArgument, "invalid request signature")
+	}
+
+	return nil
+}
+
+// unaryHashing возвращает перехватчик проверки подписи для унарных вызовов.
+func unaryHashing(config *middlewares.HashConfig) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := verifyHash(config, req, info.FullMethod); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// streamHashing возвращает перехватчик проверки подписи для потоковых вызовов.
+// Подпись проверяется для каждого сообщения потока.
+func streamHashing(config *middlewares.HashConfig) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &hashingStream{ServerStream: ss, config: config, method: info.FullMethod})
+	}
+}
+
+// hashingStream проверяет подпись каждого полученного сообщения.
+type hashingStream struct {
+	grpc.ServerStream
+	config *middlewares.HashConfig
+	method string
+}
+
+// RecvMsg получает сообщение и проверяет его подпись.
+func (s *hashingStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	return verifyHash(s.config, m, s.method)
+}
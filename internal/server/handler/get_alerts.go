@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Ko4etov/go-metrics/internal/server/service/alerting"
+)
+
+// GetAlerts возвращает обработчик для получения активных оповещений в формате JSON.
+func (h *Handler) GetAlerts(engine *alerting.Engine) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Type", "application/json")
+		res.WriteHeader(http.StatusOK)
+
+		if err := json.NewEncoder(res).Encode(engine.Alerts()); err != nil {
+			http.Error(res, "Error encoding JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
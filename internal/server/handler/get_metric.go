@@ -5,9 +5,12 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
 )
 
 // GetMetric возвращает значение метрики в текстовом формате.
+// Ряд метрики с метками выбирается параметрами запроса: /value/gauge/Alloc?host=web1.
 func (h *Handler) GetMetric(w http.ResponseWriter, r *http.Request) {
 	metricType := chi.URLParam(r, "metricType")
 	metricName := chi.URLParam(r, "metricName")
@@ -17,14 +20,21 @@ func (h *Handler) GetMetric(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	labels, err := labelsFromQuery(r)
+	if err != nil {
+		http.Error(w, "Invalid labels: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := models.MetricKey(metricName, labels)
+
 	var value string
-	var err error
 
 	switch metricType {
 	case "gauge":
-		value, err = h.storage.GaugeMetric(metricName)
+		value, err = h.storage.GaugeMetric(key)
 	case "counter":
-		value, err = h.storage.CounterMetric(metricName)
+		value, err = h.storage.CounterMetric(key)
 	default:
 		http.Error(w, "Invalid metric type", http.StatusBadRequest)
 		return
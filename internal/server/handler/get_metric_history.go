@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/interfaces"
+)
+
+// defaultHistoryRange - интервал истории по умолчанию, если параметр from не задан.
+const defaultHistoryRange = 24 * time.Hour
+
+// GetMetricHistory возвращает обработчик для получения истории метрики в формате JSON.
+//
+// Поддерживаемые параметры запроса:
+//   - from: начало интервала (RFC3339 или Unix-время в секундах), по умолчанию to-24h
+//   - to: конец интервала (RFC3339 или Unix-время в секундах), по умолчанию текущее время
+//   - step: шаг агрегации точек (например, "1m"), по умолчанию шаг выбранного уровня хранения
+//   - labels: метки ряда метрики в формате name=value,name=value
+//
+// Для длинных интервалов история автоматически отдается с шагом того уровня хранения,
+// срок которого покрывает начало интервала.
+func (h *Handler) GetMetricHistory(hist interfaces.History) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		metricType := chi.URLParam(req, "metricType")
+		metricName := chi.URLParam(req, "metricName")
+
+		if metricType != models.Gauge && metricType != models.Counter {
+			http.Error(res, "Invalid metric type", http.StatusBadRequest)
+			return
+		}
+
+		if metricName == "" {
+			http.Error(res, "Invalid parameters", http.StatusBadRequest)
+			return
+		}
+
+		query := req.URL.Query()
+
+		to := time.Now().UTC()
+		if raw := query.Get("to"); raw != "" {
+			parsed, err := parseHistoryTime(raw)
+			if err != nil {
+				http.Error(res, "Invalid to parameter: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			to = parsed
+		}
+
+		from := to.Add(-defaultHistoryRange)
+		if raw := query.Get("from"); raw != "" {
+			parsed, err := parseHistoryTime(raw)
+			if err != nil {
+				http.Error(res, "Invalid from parameter: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			from = parsed
+		}
+
+		if from.After(to) {
+			http.Error(res, "from must not be after to", http.StatusBadRequest)
+			return
+		}
+
+		var step time.Duration
+		if raw := query.Get("step"); raw != "" {
+			parsed, err := time.ParseDuration(raw)
+			if err != nil || parsed <= 0 {
+				http.Error(res, "Invalid step parameter", http.StatusBadRequest)
+				return
+			}
+			step = parsed
+		}
+
+		labels, err := models.ParseLabels(query.Get("labels"))
+		if err != nil {
+			http.Error(res, "Invalid labels parameter: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		history, err := hist.Query(metricType, models.MetricKey(metricName, labels), from, to, step)
+		if err != nil {
+			http.Error(res, "Failed to query history: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		res.Header().Set("Content-Type", "application/json")
+		res.WriteHeader(http.StatusOK)
+
+		if err := json.NewEncoder(res).Encode(history); err != nil {
+			http.Error(res, "Error encoding JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// parseHistoryTime разбирает время в формате RFC3339 или Unix-время в секундах.
+func parseHistoryTime(raw string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC3339 or unix seconds: %q", raw)
+	}
+
+	return parsed.UTC(), nil
+}
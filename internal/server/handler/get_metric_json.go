@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 
@@ -43,13 +44,24 @@ func (h *Handler) GetMetricJSON(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if err := inputMetric.Labels.Validate(); err != nil {
+		http.Error(res, "Invalid labels", http.StatusBadRequest)
+		return
+	}
+
 	var outputMetric *models.Metrics
 
 	switch inputMetric.MType {
 	case "gauge":
-		outputMetric, err = h.storage.GaugeMetricModel(inputMetric.ID)
+		outputMetric, err = h.storage.GaugeMetricModel(inputMetric.Key())
 	case "counter":
-		outputMetric, err = h.storage.CounterMetricModel(inputMetric.ID)
+		outputMetric, err = h.storage.CounterMetricModel(inputMetric.Key())
+	case models.Histogram, models.Summary:
+		metric, ok := h.storage.Metric(inputMetric.Key())
+		if !ok || metric.MType != inputMetric.MType {
+			err = fmt.Errorf("%s metric not found", inputMetric.MType)
+		}
+		outputMetric = &metric
 	default:
 		http.Error(res, "Invalid metric type", http.StatusBadRequest)
 		return
@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"sort"
 	"text/template"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
 )
 
 // ViewData содержит данные для отображения страницы с метриками.
@@ -21,7 +23,14 @@ type MetricsRecource struct {
 }
 
 // GetMetrics возвращает HTML-страницу со списком всех метрик.
+// Параметры запроса match отбирают ряды по меткам.
 func (h *Handler) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	matchers, err := labelMatchersFromQuery(r)
+	if err != nil {
+		http.Error(w, "Invalid label matcher: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	metrics := h.storage.Metrics()
 
 	w.Header().Set("Content-Type", "text/html")
@@ -29,21 +38,39 @@ func (h *Handler) GetMetrics(w http.ResponseWriter, r *http.Request) {
 	var MetricsSlice []MetricsRecource
 
 	for _, metric := range metrics {
+		if !models.MatchLabels(metric.Labels, matchers) {
+			continue
+		}
+
 		switch metric.MType {
 		case "gauge":
 			if metric.Value != nil {
 				MetricsSlice = append(MetricsSlice, MetricsRecource{
-					Name:  metric.ID,
+					Name:  metric.Key(),
 					Value: fmt.Sprintf("%.2f", *metric.Value),
 				})
 			}
 		case "counter":
 			if metric.Delta != nil {
 				MetricsSlice = append(MetricsSlice, MetricsRecource{
-					Name:  metric.ID,
+					Name:  metric.Key(),
 					Value: fmt.Sprintf("%d", *metric.Delta),
 				})
 			}
+		case models.Histogram:
+			if metric.Histogram != nil {
+				MetricsSlice = append(MetricsSlice, MetricsRecource{
+					Name:  metric.Key(),
+					Value: fmt.Sprintf("count=%d sum=%.2f", metric.Histogram.Count, metric.Histogram.Sum),
+				})
+			}
+		case models.Summary:
+			if metric.Summary != nil {
+				MetricsSlice = append(MetricsSlice, MetricsRecource{
+					Name:  metric.Key(),
+					Value: formatSummary(metric.Summary),
+				})
+			}
 		}
 	}
 
@@ -59,4 +86,15 @@ func (h *Handler) GetMetrics(w http.ResponseWriter, r *http.Request) {
     }
     
     tmpl.Execute(w, MetricsSlice)
-}
\ No newline at end of file
+}
+
+// formatSummary форматирует сводку для отображения: количество, сумма и квантили.
+func formatSummary(summary *models.SummaryValue) string {
+	value := fmt.Sprintf("count=%d sum=%.2f", summary.Count, summary.Sum)
+
+	for _, q := range summary.Quantiles {
+		value += fmt.Sprintf(" q%g=%.2f", q.Quantile, q.Value)
+	}
+
+	return value
+}
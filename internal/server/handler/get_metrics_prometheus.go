@@ -0,0 +1,319 @@
+package handler
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+)
+
+const (
+	// prometheusContentType - тип содержимого текстового формата Prometheus.
+	prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+	// openMetricsContentType - тип содержимого формата OpenMetrics.
+	openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// metricFamily - ряды метрик с одним именем в формате Prometheus.
+type metricFamily struct {
+	name    string           // имя семейства
+	metrics []models.Metrics // ряды семейства
+}
+
+// GetMetricsPrometheus возвращает все метрики в текстовом формате Prometheus,
+// либо в формате OpenMetrics, если он запрошен через заголовок Accept.
+// Параметры запроса match отбирают ряды по меткам.
+func (h *Handler) GetMetricsPrometheus(res http.ResponseWriter, req *http.Request) {
+	openMetrics := acceptsOpenMetrics(req)
+
+	matchers, err := labelMatchersFromQuery(req)
+	if err != nil {
+		http.Error(res, "Invalid label matcher: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	metrics := make([]models.Metrics, 0, len(h.storage.Metrics()))
+	for _, metric := range h.storage.Metrics() {
+		if models.MatchLabels(metric.Labels, matchers) {
+			metrics = append(metrics, metric)
+		}
+	}
+
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].ID != metrics[j].ID {
+			return metrics[i].ID < metrics[j].ID
+		}
+		return metrics[i].Key() < metrics[j].Key()
+	})
+
+	// Ряды группируются в семейства по имени в порядке первого появления.
+	var families []*metricFamily
+	index := make(map[string]*metricFamily)
+	names := prometheusNames(metrics, openMetrics)
+
+	for _, metric := range metrics {
+		name := names[metric.ID]
+
+		family, ok := index[name]
+		if !ok {
+			family = &metricFamily{name: name}
+			index[name] = family
+			families = append(families, family)
+		}
+		family.metrics = append(family.metrics, metric)
+	}
+
+	if openMetrics {
+		res.Header().Set("Content-Type", openMetricsContentType)
+	} else {
+		res.Header().Set("Content-Type", prometheusContentType)
+	}
+	res.WriteHeader(http.StatusOK)
+
+	w := bufio.NewWriter(res)
+	defer w.Flush()
+
+	for _, family := range families {
+		writeMetricFamily(w, family, openMetrics)
+	}
+
+	if openMetrics {
+		w.WriteString("# EOF\n")
+	}
+}
+
+// prometheusNames возвращает имена семейств Prometheus по именам метрик.
+// Разные имена могут дать одно имя семейства, например a.b и a_b. Тогда имя
+// сохраняет метрика, имя которой уже допустимо (при нескольких - первая по порядку),
+// а к именам остальных добавляется хеш исходного имени, чтобы их ряды
+// не сливались в одно семейство.
+func prometheusNames(metrics []models.Metrics, openMetrics bool) map[string]string {
+	names := make(map[string]string)
+	owners := make(map[string]string) // метрика, сохраняющая имя семейства
+	exact := make(map[string]bool)    // имя метрики уже допустимо
+
+	for _, metric := range metrics {
+		if _, ok := names[metric.ID]; ok {
+			continue
+		}
+
+		name := sanitizeMetricName(metric.ID)
+		exact[metric.ID] = name == metric.ID
+		if openMetrics && metric.MType == models.Counter {
+			name = strings.TrimSuffix(name, "_total")
+		}
+		names[metric.ID] = name
+
+		owner, ok := owners[name]
+		if !ok || (exact[metric.ID] && !exact[owner]) {
+			owners[name] = metric.ID
+		}
+	}
+
+	for id, name := range names {
+		if owners[name] != id {
+			hash := fnv.New32a()
+			hash.Write([]byte(id))
+			names[id] = fmt.Sprintf("%s_%08x", name, hash.Sum32())
+		}
+	}
+
+	return names
+}
+
+// writeMetricFamily записывает семейство метрики с комментариями HELP и TYPE.
+// Тип семейства определяется первым рядом, ряды другого типа и ряды
+// без значения пропускаются.
+func writeMetricFamily(w *bufio.Writer, family *metricFamily, openMetrics bool) {
+	headerWritten := false
+	mType := ""
+
+	for _, metric := range family.metrics {
+		if mType != "" && metric.MType != mType {
+			continue
+		}
+
+		promType, samples := prometheusSamples(family.name, metric, openMetrics)
+		if promType == "" {
+			continue
+		}
+
+		if !headerWritten {
+			w.WriteString("# HELP " + family.name + " " + escapeHelp(metric.MType+" metric "+metric.ID) + "\n")
+			w.WriteString("# TYPE " + family.name + " " + promType + "\n")
+			headerWritten = true
+			mType = metric.MType
+		}
+
+		for _, sample := range samples {
+			w.WriteString(sample + "\n")
+		}
+	}
+}
+
+// prometheusSamples возвращает тип Prometheus и строки отсчетов ряда метрики.
+// Для ряда, который не может быть представлен, возвращает пустой тип.
+func prometheusSamples(name string, metric models.Metrics, openMetrics bool) (string, []string) {
+	labels := formatPrometheusLabels(metric.Labels)
+
+	switch metric.MType {
+	case models.Gauge:
+		if metric.Value == nil {
+			return "", nil
+		}
+		return "gauge", []string{name + labels + " " + formatPrometheusFloat(*metric.Value)}
+
+	case models.Counter:
+		if metric.Delta == nil {
+			return "", nil
+		}
+		sample := name
+		if openMetrics {
+			sample = name + "_total"
+		}
+		return "counter", []string{sample + labels + " " + strconv.FormatInt(*metric.Delta, 10)}
+
+	case models.Histogram:
+		h := metric.Histogram
+		if h == nil || len(h.Counts) != len(h.Buckets)+1 {
+			return "", nil
+		}
+
+		samples := make([]string, 0, len(h.Counts)+2)
+		var cumulative uint64
+		for i, count := range h.Counts {
+			cumulative += count
+			bound := "+Inf"
+			if i < len(h.Buckets) {
+				bound = formatPrometheusFloat(h.Buckets[i])
+			}
+			samples = append(samples, name+"_bucket"+formatPrometheusLabels(metric.Labels, "le", bound)+
+				" "+strconv.FormatUint(cumulative, 10))
+		}
+		samples = append(samples,
+			name+"_sum"+labels+" "+formatPrometheusFloat(h.Sum),
+			name+"_count"+labels+" "+strconv.FormatUint(h.Count, 10))
+		return "histogram", samples
+
+	case models.Summary:
+		s := metric.Summary
+		if s == nil {
+			return "", nil
+		}
+
+		samples := make([]string, 0, len(s.Quantiles)+2)
+		for _, q := range s.Quantiles {
+			samples = append(samples, name+formatPrometheusLabels(metric.Labels, "quantile", formatPrometheusFloat(q.Quantile))+
+				" "+formatPrometheusFloat(q.Value))
+		}
+		samples = append(samples,
+			name+"_sum"+labels+" "+formatPrometheusFloat(s.Sum),
+			name+"_count"+labels+" "+strconv.FormatUint(s.Count, 10))
+		return "summary", samples
+	}
+
+	return "", nil
+}
+
+// formatPrometheusLabels форматирует метки ряда: {name="value",...}.
+// Дополнительная метка extra (имя и значение) записывается последней,
+// например le для корзин гистограммы.
+func formatPrometheusLabels(labels models.Labels, extra ...string) string {
+	if len(labels) == 0 && len(extra) < 2 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+
+	written := 0
+	for _, name := range labels.Names() {
+		// Метки le и quantile запрещены при приеме, но могли сохраниться раньше.
+		if len(extra) >= 2 && name == extra[0] {
+			continue
+		}
+		if written > 0 {
+			b.WriteByte(',')
+		}
+		written++
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(labels[name]))
+		b.WriteByte('"')
+	}
+
+	if len(extra) >= 2 {
+		if written > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(extra[0])
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(extra[1]))
+		b.WriteByte('"')
+	}
+
+	b.WriteByte('}')
+
+	return b.String()
+}
+
+// escapeLabelValue экранирует значение метки.
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// acceptsOpenMetrics проверяет, запрошен ли формат OpenMetrics.
+func acceptsOpenMetrics(req *http.Request) bool {
+	return strings.Contains(req.Header.Get("Accept"), "application/openmetrics-text")
+}
+
+// sanitizeMetricName приводит имя метрики к виду [a-zA-Z_:][a-zA-Z0-9_:]*.
+func sanitizeMetricName(id string) string {
+	var b strings.Builder
+	b.Grow(len(id) + 1)
+
+	for i, r := range id {
+		switch {
+		case r == '_' || r == ':' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+
+	if b.Len() == 0 {
+		return "_"
+	}
+
+	return b.String()
+}
+
+// escapeHelp экранирует текст комментария HELP.
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+// formatPrometheusFloat форматирует число с плавающей точкой для Prometheus.
+func formatPrometheusFloat(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
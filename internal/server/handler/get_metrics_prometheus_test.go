@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/repository/storage"
+)
+
+func TestGetMetricsPrometheus(t *testing.T) {
+	storage := storage.New(&storage.MetricsStorageConfig{})
+	var poll *pgxpool.Pool
+	metricHandler := New(storage, poll)
+
+	value := 12.5
+	delta := int64(7)
+	storage.UpdateMetricsBatch([]models.Metrics{
+		{ID: "HeapAlloc", MType: models.Gauge, Value: &value},
+		{ID: "PollCount", MType: models.Counter, Delta: &delta},
+		{ID: "1st.metric-name", MType: models.Gauge, Value: &value},
+	})
+
+	tests := []struct {
+		name                string
+		accept              string
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "prometheus text format",
+			expectedContentType: prometheusContentType,
+			expectedBody: "# HELP _1st_metric_name gauge metric 1st.metric-name\n" +
+				"# TYPE _1st_metric_name gauge\n" +
+				"_1st_metric_name 12.5\n" +
+				"# HELP HeapAlloc gauge metric HeapAlloc\n" +
+				"# TYPE HeapAlloc gauge\n" +
+				"HeapAlloc 12.5\n" +
+				"# HELP PollCount counter metric PollCount\n" +
+				"# TYPE PollCount counter\n" +
+				"PollCount 7\n",
+		},
+		{
+			name:                "openmetrics format",
+			accept:              "application/openmetrics-text; version=1.0.0",
+			expectedContentType: openMetricsContentType,
+			expectedBody: "# HELP _1st_metric_name gauge metric 1st.metric-name\n" +
+				"# TYPE _1st_metric_name gauge\n" +
+				"_1st_metric_name 12.5\n" +
+				"# HELP HeapAlloc gauge metric HeapAlloc\n" +
+				"# TYPE HeapAlloc gauge\n" +
+				"HeapAlloc 12.5\n" +
+				"# HELP PollCount counter metric PollCount\n" +
+				"# TYPE PollCount counter\n" +
+				"PollCount_total 7\n" +
+				"# EOF\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+
+			rr := httptest.NewRecorder()
+			metricHandler.GetMetricsPrometheus(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+			}
+
+			if contentType := rr.Header().Get("Content-Type"); contentType != tt.expectedContentType {
+				t.Errorf("unexpected Content-Type: got %q want %q", contentType, tt.expectedContentType)
+			}
+
+			if body := rr.Body.String(); body != tt.expectedBody {
+				t.Errorf("handler returned unexpected body:\n%s\nwant:\n%s", body, tt.expectedBody)
+			}
+		})
+	}
+}
+
+func TestGetMetricsPrometheus_Labels(t *testing.T) {
+	storage := storage.New(&storage.MetricsStorageConfig{})
+	var poll *pgxpool.Pool
+	metricHandler := New(storage, poll)
+
+	web1, web2, plain := 1.5, 2.5, 3.0
+	storage.UpdateMetricsBatch([]models.Metrics{
+		{ID: "HeapAlloc", MType: models.Gauge, Value: &web2, Labels: models.Labels{"host": "web2", "env": "prod"}},
+		{ID: "HeapAlloc", MType: models.Gauge, Value: &web1, Labels: models.Labels{"host": "web1"}},
+		{ID: "HeapAlloc2", MType: models.Gauge, Value: &plain},
+		{ID: "HeapAlloc", MType: models.Gauge, Value: &plain},
+	})
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "all series",
+			expectedCode: http.StatusOK,
+			expectedBody: "# HELP HeapAlloc gauge metric HeapAlloc\n" +
+				"# TYPE HeapAlloc gauge\n" +
+				"HeapAlloc 3\n" +
+				"HeapAlloc{env=\"prod\",host=\"web2\"} 2.5\n" +
+				"HeapAlloc{host=\"web1\"} 1.5\n" +
+				"# HELP HeapAlloc2 gauge metric HeapAlloc2\n" +
+				"# TYPE HeapAlloc2 gauge\n" +
+				"HeapAlloc2 3\n",
+		},
+		{
+			name:         "label matchers",
+			query:        "?match=host=~web.*&match=env!=prod",
+			expectedCode: http.StatusOK,
+			expectedBody: "# HELP HeapAlloc gauge metric HeapAlloc\n" +
+				"# TYPE HeapAlloc gauge\n" +
+				"HeapAlloc{host=\"web1\"} 1.5\n",
+		},
+		{
+			name:         "invalid matcher",
+			query:        "?match=host",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			metricHandler.GetMetricsPrometheus(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedCode)
+			}
+
+			if tt.expectedBody != "" && rr.Body.String() != tt.expectedBody {
+				t.Errorf("handler returned unexpected body:\n%s\nwant:\n%s", rr.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
+
+func TestGetMetricsPrometheus_Distributions(t *testing.T) {
+	storage := storage.New(&storage.MetricsStorageConfig{})
+	var poll *pgxpool.Pool
+	metricHandler := New(storage, poll)
+
+	for _, counts := range [][]uint64{{1, 1, 0}, {0, 1, 1}} {
+		err := storage.UpdateMetricsBatch([]models.Metrics{
+			{ID: "Latency", MType: models.Histogram, Histogram: &models.HistogramValue{
+				Buckets: []float64{0.1, 1}, Counts: counts, Sum: 1.5, Count: 2,
+			}},
+			{ID: "RTT", MType: models.Summary, Summary: &models.SummaryValue{
+				Quantiles: []models.Quantile{{Quantile: 0.5, Value: 0.2}, {Quantile: 0.99, Value: 0.9}}, Sum: 1, Count: 4,
+			}},
+		})
+		if err != nil {
+			t.Fatalf("UpdateMetricsBatch failed: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rr := httptest.NewRecorder()
+	metricHandler.GetMetricsPrometheus(rr, req)
+
+	expectedBody := "# HELP Latency histogram metric Latency\n" +
+		"# TYPE Latency histogram\n" +
+		"Latency_bucket{le=\"0.1\"} 1\n" +
+		"Latency_bucket{le=\"1\"} 3\n" +
+		"Latency_bucket{le=\"+Inf\"} 4\n" +
+		"Latency_sum 3\n" +
+		"Latency_count 4\n" +
+		"# HELP RTT summary metric RTT\n" +
+		"# TYPE RTT summary\n" +
+		"RTT{quantile=\"0.5\"} 0.2\n" +
+		"RTT{quantile=\"0.99\"} 0.9\n" +
+		"RTT_sum 2\n" +
+		"RTT_count 8\n"
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	if rr.Body.String() != expectedBody {
+		t.Errorf("handler returned unexpected body:\n%s\nwant:\n%s", rr.Body.String(), expectedBody)
+	}
+}
+
+func TestGetMetricsPrometheus_NameCollisions(t *testing.T) {
+	storage := storage.New(&storage.MetricsStorageConfig{})
+	var poll *pgxpool.Pool
+	metricHandler := New(storage, poll)
+
+	dotted, plain := 1.0, 2.0
+	storage.UpdateMetricsBatch([]models.Metrics{
+		{ID: "a.b", MType: models.Gauge, Value: &dotted},
+		{ID: "a_b", MType: models.Gauge, Value: &plain},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rr := httptest.NewRecorder()
+	metricHandler.GetMetricsPrometheus(rr, req)
+
+	// Допустимое имя сохраняется, к приведенному добавляется хеш исходного имени.
+	expectedBody := "# HELP a_b_108bf50c gauge metric a.b\n" +
+		"# TYPE a_b_108bf50c gauge\n" +
+		"a_b_108bf50c 1\n" +
+		"# HELP a_b gauge metric a_b\n" +
+		"# TYPE a_b gauge\n" +
+		"a_b 2\n"
+
+	if rr.Body.String() != expectedBody {
+		t.Errorf("handler returned unexpected body:\n%s\nwant:\n%s", rr.Body.String(), expectedBody)
+	}
+}
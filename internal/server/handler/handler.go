@@ -1,7 +1,7 @@
 package handler
 
 import (
-	"fmt"
+	"net/http"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 
@@ -23,29 +23,27 @@ func New(s interfaces.Storage, pgx *pgxpool.Pool) *Handler {
 	}
 }
 
-// validateMetric проверяет валидность метрики.
-func (h *Handler) validateMetric(metric *models.Metrics) error {
-	if metric.MType != models.Gauge && metric.MType != models.Counter {
-		return fmt.Errorf("invalid metric type: %s", metric.MType)
+// labelsFromQuery возвращает метки метрики из параметров запроса вида name=value.
+func labelsFromQuery(req *http.Request) (models.Labels, error) {
+	query := req.URL.Query()
+	if len(query) == 0 {
+		return nil, nil
 	}
 
-	if metric.ID == "" {
-		return fmt.Errorf("metric ID is required")
+	labels := make(models.Labels, len(query))
+	for name := range query {
+		labels[name] = query.Get(name)
 	}
 
-	switch metric.MType {
-	case models.Gauge:
-		if metric.Value == nil {
-			return fmt.Errorf("value is required for gauge metric")
-		}
-	case models.Counter:
-		if metric.Delta == nil {
-			return fmt.Errorf("delta is required for counter metric")
-		}
-		if *metric.Delta < 0 {
-			return fmt.Errorf("delta cannot be negative for counter metric")
-		}
+	if err := labels.Validate(); err != nil {
+		return nil, err
 	}
 
-	return nil
-}
\ No newline at end of file
+	return labels, nil
+}
+
+// labelMatchersFromQuery возвращает условия отбора по меткам из параметров запроса match,
+// например ?match=host=web1&match=env!=prod.
+func labelMatchersFromQuery(req *http.Request) ([]models.LabelMatcher, error) {
+	return models.ParseLabelMatchers(req.URL.Query()["match"])
+}
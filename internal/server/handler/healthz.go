@@ -0,0 +1,12 @@
+package handler
+
+import (
+	"net/http"
+)
+
+// Healthz сообщает, что процесс сервера работает. Зависимости не проверяются.
+func (h *Handler) Healthz(res http.ResponseWriter, req *http.Request) {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	res.Write([]byte(`{"status":"ok"}`))
+}
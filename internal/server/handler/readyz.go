@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Ko4etov/go-metrics/internal/server/service/health"
+)
+
+// Readyz возвращает обработчик проверки готовности сервера.
+// Отчет по каждой зависимости отдается в формате JSON; если сервер не готов
+// или останавливается, возвращается статус 503.
+func (h *Handler) Readyz(checker *health.Checker) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		report := checker.Check(req.Context())
+
+		statusCode := http.StatusOK
+		if !report.Ready() {
+			statusCode = http.StatusServiceUnavailable
+		}
+
+		res.Header().Set("Content-Type", "application/json")
+		res.Header().Set("Cache-Control", "no-store")
+		res.WriteHeader(statusCode)
+
+		if err := json.NewEncoder(res).Encode(report); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Ko4etov/go-metrics/internal/server/repository/storage"
+	"github.com/Ko4etov/go-metrics/internal/server/service/health"
+)
+
+func TestReadyz(t *testing.T) {
+	store := storage.New(&storage.MetricsStorageConfig{})
+	metricHandler := New(store, nil)
+
+	checker := health.NewChecker(0)
+	checker.Add(health.RestoreCheck(store.RestoreStatus))
+	checker.Add(health.Check{
+		Name: "optional",
+		Run: func(ctx context.Context) health.CheckResult {
+			return health.CheckResult{Status: health.StatusFail, Error: "unavailable"}
+		},
+	})
+
+	handler := metricHandler.Readyz(checker)
+
+	check := func(wantStatus int, wantReport string) health.Report {
+		t.Helper()
+
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		if rec.Code != wantStatus {
+			t.Errorf("status code = %d, want %d", rec.Code, wantStatus)
+		}
+
+		var report health.Report
+		if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if report.Status != wantReport {
+			t.Errorf("report status = %s, want %s", report.Status, wantReport)
+		}
+		return report
+	}
+
+	report := check(http.StatusOK, health.StatusDegraded)
+	if report.Checks["restore"].Status != health.StatusOK || report.Checks["optional"].Error != "unavailable" {
+		t.Errorf("unexpected checks: %+v", report.Checks)
+	}
+
+	checker.SetShuttingDown()
+	check(http.StatusServiceUnavailable, health.StatusFail)
+}
+
+func TestHealthz(t *testing.T) {
+	rec := httptest.NewRecorder()
+	New(nil, nil).Healthz(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"status":"ok"}` {
+		t.Errorf("Healthz() = %d %q", rec.Code, rec.Body.String())
+	}
+}
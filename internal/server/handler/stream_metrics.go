@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/interfaces"
+)
+
+const (
+	streamBufferSize        = 256              // размер буфера изменений на подписчика
+	streamHeartbeatInterval = 15 * time.Second // интервал комментариев для поддержания соединения
+)
+
+// StreamMetrics возвращает обработчик, передающий изменения метрик в формате Server-Sent Events.
+//
+// Каждое изменение отправляется событием metric с JSON-представлением нового значения.
+// Поддерживаемые параметры запроса:
+//   - type: тип метрики (gauge, counter, histogram или summary)
+//   - prefix: префикс имени метрики
+//   - match: условие на метки ряда (name=value, name!=value, name=~regex, name!~regex), может повторяться
+//
+// Если клиент не успевает читать изменения и буфер подписки переполняется,
+// отправляется событие overflow и соединение закрывается.
+func (h *Handler) StreamMetrics(updates interfaces.Updates) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+
+		metricType := query.Get("type")
+		switch metricType {
+		case "", models.Gauge, models.Counter, models.Histogram, models.Summary:
+		default:
+			http.Error(res, "Invalid metric type", http.StatusBadRequest)
+			return
+		}
+
+		matchers, err := labelMatchersFromQuery(req)
+		if err != nil {
+			http.Error(res, "Invalid match parameter: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		flusher, ok := res.(http.Flusher)
+		if !ok {
+			http.Error(res, "Streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		prefix := query.Get("prefix")
+		filter := func(metric models.Metrics) bool {
+			return (metricType == "" || metric.MType == metricType) &&
+				strings.HasPrefix(metric.ID, prefix) &&
+				models.MatchLabels(metric.Labels, matchers)
+		}
+
+		stream, unsubscribe := updates.Subscribe(filter, streamBufferSize)
+		defer unsubscribe()
+
+		res.Header().Set("Content-Type", "text/event-stream")
+		res.Header().Set("Cache-Control", "no-cache")
+		res.Header().Set("Connection", "keep-alive")
+		res.Header().Set("X-Accel-Buffering", "no")
+		res.WriteHeader(http.StatusOK)
+
+		fmt.Fprint(res, ": connected\n\n")
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(streamHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-req.Context().Done():
+				return
+			case <-heartbeat.C:
+				fmt.Fprint(res, ": ping\n\n")
+			case metric, ok := <-stream:
+				if !ok {
+					fmt.Fprint(res, "event: overflow\ndata: subscriber buffer overflow\n\n")
+					flusher.Flush()
+					return
+				}
+
+				data, err := json.Marshal(metric)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(res, "event: metric\ndata: %s\n\n", data)
+			}
+
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/middlewares"
+	"github.com/Ko4etov/go-metrics/internal/server/repository/storage"
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+)
+
+func TestStreamMetrics(t *testing.T) {
+	logger.Initialize("error")
+	store := storage.New(&storage.MetricsStorageConfig{})
+	metricHandler := New(store, nil)
+
+	// Поток должен проходить через middleware без задержки.
+	handler := middlewares.WithLogging(&middlewares.LoggingConfig{SampleRate: 1})(
+		middlewares.WithCompression(
+			middlewares.WithHashing(&middlewares.HashConfig{SecretKey: "secret", Mode: middlewares.HashModeLog})(
+				metricHandler.StreamMetrics(store),
+			),
+		),
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/stream?type=counter&prefix=Poll", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected Content-Type %q", ct)
+	}
+
+	events := make(chan string, 10)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			events <- scanner.Text()
+		}
+		close(events)
+	}()
+
+	next := func() string {
+		select {
+		case line := <-events:
+			return line
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for event")
+			return ""
+		}
+	}
+
+	if line := next(); line != ": connected" {
+		t.Fatalf("expected connection comment, got %q", line)
+	}
+	next()
+
+	value := 1.0
+	delta := int64(3)
+	store.UpdateMetricsBatch([]models.Metrics{
+		{ID: "Alloc", MType: models.Gauge, Value: &value},
+		{ID: "PollInterval", MType: models.Gauge, Value: &value},
+		{ID: "PollCount", MType: models.Counter, Delta: &delta},
+	})
+
+	if line := next(); line != "event: metric" {
+		t.Fatalf("expected metric event, got %q", line)
+	}
+	if line := next(); !strings.HasPrefix(line, "data: ") || !strings.Contains(line, `"id":"PollCount"`) || !strings.Contains(line, `"delta":3`) {
+		t.Errorf("unexpected event data %q", line)
+	}
+}
+
+func TestStreamMetrics_InvalidParameters(t *testing.T) {
+	store := storage.New(&storage.MetricsStorageConfig{})
+	metricHandler := New(store, nil)
+
+	for _, query := range []string{"?type=unknown", "?match=host"} {
+		req := httptest.NewRequest(http.MethodGet, "/stream"+query, nil)
+		rr := httptest.NewRecorder()
+		metricHandler.StreamMetrics(store)(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", query, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -8,9 +8,11 @@ import (
 	"github.com/go-chi/chi/v5"
 
 	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/middlewares"
 )
 
 // UpdateMetric обновляет метрику из URL-параметров.
+// Метки метрики передаются параметрами запроса: /update/gauge/Alloc/1?host=web1.
 func (h *Handler) UpdateMetric(res http.ResponseWriter, req *http.Request) {
 
 	metricType := chi.URLParam(req, "metricType")
@@ -27,6 +29,12 @@ func (h *Handler) UpdateMetric(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	labels, err := labelsFromQuery(req)
+	if err != nil {
+		http.Error(res, "Invalid labels: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	var metric models.Metrics
 
 	switch metricType {
@@ -37,9 +45,10 @@ func (h *Handler) UpdateMetric(res http.ResponseWriter, req *http.Request) {
 			return
 		}
 		metric = models.Metrics{
-			ID:    metricName,
-			MType: models.Gauge,
-			Value: &value,
+			ID:     metricName,
+			MType:  models.Gauge,
+			Value:  &value,
+			Labels: labels,
 		}
 
 	case models.Counter:
@@ -49,9 +58,10 @@ func (h *Handler) UpdateMetric(res http.ResponseWriter, req *http.Request) {
 			return
 		}
 		metric = models.Metrics{
-			ID:    metricName,
-			MType: models.Counter,
-			Delta: &value,
+			ID:     metricName,
+			MType:  models.Counter,
+			Delta:  &value,
+			Labels: labels,
 		}
 
 	default:
@@ -59,10 +69,18 @@ func (h *Handler) UpdateMetric(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	if err := h.storage.UpdateMetric(metric); err != nil {
+	if err := metric.Validate(); err != nil {
+		// Ответы текстового API начинаются с заглавной буквы.
+		message := err.Error()
+		http.Error(res, strings.ToUpper(message[:1])+message[1:], http.StatusBadRequest)
+		return
+	}
+
+	if err := h.storage.UpdateMetricFrom(getIPAddress(req), metric); err != nil {
 		http.Error(res, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	middlewares.SetAcceptedMetrics(req.Context(), 1)
 	res.WriteHeader(http.StatusOK)
-}
\ No newline at end of file
+}
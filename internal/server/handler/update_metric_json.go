@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/middlewares"
 )
 
 // UpdateMetricJSON обновляет метрику из JSON-запроса.
@@ -20,19 +21,21 @@ func (h *Handler) UpdateMetricJSON(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	if err := h.validateMetric(&metric); err != nil {
+	if err := metric.Validate(); err != nil {
 		http.Error(res, "Invalid metric: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	if err := h.storage.UpdateMetric(metric); err != nil {
+	if err := h.storage.UpdateMetricFrom(getIPAddress(req), metric); err != nil {
 		http.Error(res, "Failed to update metric: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	middlewares.SetAcceptedMetrics(req.Context(), 1)
+
 	res.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(res).Encode(metric); err != nil {
 		http.Error(res, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
-}
\ No newline at end of file
+}
@@ -97,6 +97,15 @@ func TestUpdateMetric(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   "Invalid counter value\n",
 		},
+		{
+			name:           "reserved metric name",
+			method:         http.MethodPost,
+			metricType:     "gauge",
+			metricName:     "metrics_server_http_requests_in_flight",
+			metricValue:    "1.0",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Metric name prefix metrics_server_ is reserved\n",
+		},
 	}
 
 	for _, tt := range tests {
@@ -165,3 +174,56 @@ func verifyMetricStored(t *testing.T, storage interfaces.Storage, metricType, me
 		}
 	}
 }
+
+func TestUpdateMetric_Labels(t *testing.T) {
+	storage := storage.New(&storage.MetricsStorageConfig{})
+	var poll *pgxpool.Pool
+	metricHandler := New(storage, poll)
+
+	r := chi.NewRouter()
+	r.Post("/update/{metricType}/{metricName}/{metricValue}", metricHandler.UpdateMetric)
+	r.Get("/value/{metricType}/{metricName}", metricHandler.GetMetric)
+
+	for _, target := range []string{
+		"/update/counter/PollCount/1?host=web1",
+		"/update/counter/PollCount/2?host=web2",
+		"/update/counter/PollCount/3?host=web1",
+		"/update/counter/PollCount/10",
+	} {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, target, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("update %s returned %d", target, rr.Code)
+		}
+	}
+
+	tests := []struct {
+		target         string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"/value/counter/PollCount?host=web1", http.StatusOK, "4"},
+		{"/value/counter/PollCount?host=web2", http.StatusOK, "2"},
+		{"/value/counter/PollCount", http.StatusOK, "10"},
+		{"/value/counter/PollCount?host=web3", http.StatusNotFound, ""},
+		{"/value/counter/PollCount?__name__=x", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+		if rr.Code != tt.expectedStatus {
+			t.Errorf("%s: expected status %d, got %d", tt.target, tt.expectedStatus, rr.Code)
+		}
+		if tt.expectedBody != "" && rr.Body.String() != tt.expectedBody {
+			t.Errorf("%s: expected body %q, got %q", tt.target, tt.expectedBody, rr.Body.String())
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/update/gauge/Alloc/1?bad-name=x", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid label name, got %d", rr.Code)
+	}
+}
@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"net"
@@ -9,14 +8,17 @@ import (
 	"time"
 
 	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/middlewares"
+	"github.com/Ko4etov/go-metrics/internal/server/repository/idempotency"
 	"github.com/Ko4etov/go-metrics/internal/server/service/audit"
-	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
 )
 
 // getIPAddress извлекает IP-адрес клиента из HTTP-запроса.
+// Заголовкам запроса доверяет только адрес, проверенный middleware доверенной подсети,
+// в остальных случаях используется адрес соединения.
 func getIPAddress(req *http.Request) string {
-	if forwarded := req.Header.Get("X-Forwarded-For"); forwarded != "" {
-		return forwarded
+	if ip, ok := middlewares.ClientIP(req.Context()); ok {
+		return ip
 	}
 
 	ip, _, err := net.SplitHostPort(req.RemoteAddr)
@@ -47,11 +49,16 @@ func (h *Handler) processMetricsBatchInternal(
 	}
 
 	for i := range metrics {
-		if err := h.validateMetric(&metrics[i]); err != nil {
+		if err := metrics[i].Validate(); err != nil {
 			return nil, http.StatusBadRequest, fmt.Errorf("invalid metric: %w", err)
 		}
 	}
 
+	batchKey := req.Header.Get("Idempotency-Key")
+	if err := idempotency.ValidateKey(batchKey); err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid Idempotency-Key: %w", err)
+	}
+
 	var metricNames []string
 	if auditSvc != nil {
 		metricNames = make([]string, 0, len(metrics))
@@ -61,11 +68,21 @@ func (h *Handler) processMetricsBatchInternal(
 		}
 	}
 
-	if err := h.storage.UpdateMetricsBatch(metrics); err != nil {
+	applied, err := h.storage.UpdateMetricsBatchOnce(getIPAddress(req), batchKey, metrics)
+	if err != nil {
 		return metricNames, http.StatusInternalServerError,
 			fmt.Errorf("failed to update metrics: %w", err)
 	}
 
+	// Повторно присланный батч подтверждается без применения и аудита.
+	if !applied {
+		res.Header().Set("Idempotent-Replayed", "true")
+		middlewares.SetAcceptedMetrics(req.Context(), 0)
+		return nil, http.StatusOK, nil
+	}
+
+	middlewares.SetAcceptedMetrics(req.Context(), len(metrics))
+
 	return metricNames, http.StatusOK, nil
 }
 
@@ -93,7 +110,7 @@ func (h *Handler) UpdateMetricsBatchWithAudit(auditSvc *audit.AuditService) http
 		}
 
 		if auditSvc != nil && statusCode == http.StatusOK && len(metricNames) > 0 {
-			go h.sendAuditEvent(req, metricNames, auditSvc)
+			h.sendAuditEvent(req, metricNames, auditSvc)
 		}
 
 		res.Header().Set("Content-Type", "application/json")
@@ -103,16 +120,11 @@ func (h *Handler) UpdateMetricsBatchWithAudit(auditSvc *audit.AuditService) http
 
 // sendAuditEvent отправляет событие аудита асинхронно.
 func (h *Handler) sendAuditEvent(req *http.Request, metricNames []string, auditSvc *audit.AuditService) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
 	event := audit.AuditEvent{
 		TS:        time.Now().Unix(),
 		Metrics:   metricNames,
 		IPAddress: getIPAddress(req),
 	}
 
-	if err := auditSvc.Notify(ctx, event); err != nil {
-		logger.Logger.Infof("[audit] Failed to send audit event: %v\n", err)
-	}
-}
\ No newline at end of file
+	auditSvc.NotifyAsync(event, 5*time.Second)
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Ko4etov/go-metrics/internal/server/repository/idempotency"
+	"github.com/Ko4etov/go-metrics/internal/server/repository/storage"
+)
+
+func TestUpdateMetricsBatch_Idempotency(t *testing.T) {
+	store := storage.New(&storage.MetricsStorageConfig{
+		Idempotency: idempotency.New(&idempotency.AppliedBatchesConfig{}),
+	})
+	metricHandler := New(store, nil)
+
+	send := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/updates/",
+			strings.NewReader(`[{"id":"PollCount","type":"counter","delta":5}]`))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+
+		rr := httptest.NewRecorder()
+		metricHandler.UpdateMetricsBatch(rr, req)
+		return rr
+	}
+
+	if rr := send("batch-1"); rr.Code != http.StatusOK || rr.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("Expected first batch to be applied, got status %d", rr.Code)
+	}
+
+	rr := send("batch-1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected duplicate batch to be acknowledged, got status %d", rr.Code)
+	}
+	if rr.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("Expected duplicate batch to be marked as replayed")
+	}
+
+	verifyMetricStored(t, store, "counter", "PollCount", "5")
+
+	send("batch-2")
+	send("")
+	verifyMetricStored(t, store, "counter", "PollCount", "15")
+
+	for _, key := range []string{strings.Repeat("k", idempotency.MaxKeyLength+1), "batch\x01"} {
+		if rr := send(key); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected invalid key %q to be rejected, got status %d", key, rr.Code)
+		}
+	}
+	verifyMetricStored(t, store, "counter", "PollCount", "15")
+}
+
+func TestUpdateMetricsBatch_Distributions(t *testing.T) {
+	metricHandler := New(storage.New(&storage.MetricsStorageConfig{}), nil)
+
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+	}{
+		{
+			name:         "valid histogram",
+			body:         `[{"id":"Latency","type":"histogram","histogram":{"buckets":[0.1,1],"counts":[1,0,2],"sum":4.2,"count":3}}]`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "valid summary",
+			body:         `[{"id":"RTT","type":"summary","summary":{"quantiles":[{"quantile":0.5,"value":0.2}],"sum":1,"count":4}}]`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "histogram without value",
+			body:         `[{"id":"Latency","type":"histogram"}]`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "histogram with mismatched counts",
+			body:         `[{"id":"Latency","type":"histogram","histogram":{"buckets":[0.1,1],"counts":[1,0],"sum":1,"count":1}}]`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "summary with invalid quantile",
+			body:         `[{"id":"RTT","type":"summary","summary":{"quantiles":[{"quantile":2,"value":0.2}],"sum":1,"count":4}}]`,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/updates/", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+			metricHandler.UpdateMetricsBatch(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Errorf("handler returned wrong status code: got %v want %v, body %q", rr.Code, tt.expectedCode, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/middlewares"
+	"github.com/Ko4etov/go-metrics/internal/server/service/influx"
+)
+
+// WriteInflux принимает метрики в строковом протоколе InfluxDB (например, от Telegraf).
+//
+// Каждое поле точки становится метрикой-измерителем с именем measurement_field и метками
+// из тегов. Целые поля (1i, 1u) тоже записываются как измерители: Telegraf передает в них
+// текущие и накопленные значения, а не приращения. Логические и строковые поля пропускаются.
+// Временные метки проверяются, но не используются: хранилище содержит текущие значения метрик.
+// Все метрики запроса применяются одним батчем, при успехе возвращается 204 No Content.
+func (h *Handler) WriteInflux(res http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(res, "Failed to read body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	points, err := influx.Parse(body)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	metrics := make([]models.Metrics, 0, len(points))
+	for _, point := range points {
+		pointMetrics := influxPointMetrics(point)
+		for i := range pointMetrics {
+			if err := pointMetrics[i].Validate(); err != nil {
+				http.Error(res, "Invalid metric: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		metrics = append(metrics, pointMetrics...)
+	}
+
+	if len(metrics) > 0 {
+		if err := h.storage.UpdateMetricsBatchFrom(getIPAddress(req), metrics); err != nil {
+			http.Error(res, "Failed to update metrics: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	middlewares.SetAcceptedMetrics(req.Context(), len(metrics))
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// influxPointMetrics преобразует числовые поля точки в метрики.
+func influxPointMetrics(point influx.Point) []models.Metrics {
+	var labels models.Labels
+	if len(point.Tags) > 0 {
+		labels = make(models.Labels, len(point.Tags))
+		for name, value := range point.Tags {
+			labels[models.SanitizeName(name)] = value
+		}
+	}
+
+	fields := make([]string, 0, len(point.Fields))
+	for field := range point.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	metrics := make([]models.Metrics, 0, len(fields))
+	for _, field := range fields {
+		var value float64
+		switch fieldValue := point.Fields[field].(type) {
+		case float64:
+			value = fieldValue
+		case int64:
+			value = float64(fieldValue)
+		case uint64:
+			value = float64(fieldValue)
+		default:
+			continue
+		}
+
+		metrics = append(metrics, models.Metrics{
+			ID:     models.SanitizeName(point.Measurement + "_" + field),
+			MType:  models.Gauge,
+			Value:  &value,
+			Labels: labels,
+		})
+	}
+
+	return metrics
+}
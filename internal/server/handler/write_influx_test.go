@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Ko4etov/go-metrics/internal/server/middlewares"
+	"github.com/Ko4etov/go-metrics/internal/server/repository/storage"
+)
+
+func TestWriteInflux(t *testing.T) {
+	store := storage.New(&storage.MetricsStorageConfig{})
+	metricHandler := New(store, nil)
+	handler := middlewares.WithCompression(http.HandlerFunc(metricHandler.WriteInflux))
+
+	body := "cpu,host=web1 usage_idle=97.5,procs=12i,up=true 1700000000000000000\n" +
+		"net,host=web1 bytes_recv=100u\n" +
+		"cpu,host=web1 procs=3i\n"
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(body))
+	gz.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/write?db=telegraf", &compressed)
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("Content-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("handler returned wrong status code: got %v want %v, body %q", rr.Code, http.StatusNoContent, rr.Body.String())
+	}
+
+	if metric, ok := store.Metric(`cpu_usage_idle{host="web1"}`); !ok || metric.Value == nil || *metric.Value != 97.5 {
+		t.Errorf("expected gauge cpu_usage_idle 97.5, got %+v", metric)
+	}
+	if metric, ok := store.Metric(`cpu_procs{host="web1"}`); !ok || metric.Value == nil || *metric.Value != 3 {
+		t.Errorf("expected gauge cpu_procs 3, got %+v", metric)
+	}
+	if metric, ok := store.Metric(`net_bytes_recv{host="web1"}`); !ok || metric.Value == nil || *metric.Value != 100 {
+		t.Errorf("expected gauge net_bytes_recv 100, got %+v", metric)
+	}
+	if _, ok := store.Metric(`cpu_up{host="web1"}`); ok {
+		t.Error("expected boolean field to be skipped")
+	}
+}
+
+func TestWriteInflux_NegativeInteger(t *testing.T) {
+	store := storage.New(&storage.MetricsStorageConfig{})
+	metricHandler := New(store, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/write", strings.NewReader("disk,path=/ free=-1i"))
+	rr := httptest.NewRecorder()
+	metricHandler.WriteInflux(rr, req)
+
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+	}
+	if metric, ok := store.Metric(`disk_free{path="/"}`); !ok || metric.Value == nil || *metric.Value != -1 {
+		t.Errorf("expected gauge disk_free -1, got %+v", metric)
+	}
+}
+
+func TestWriteInflux_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "malformed line", body: "cpu usage_idle=97.5\ncpu"},
+		{name: "non-finite field", body: "cpu usage_idle=97.5\ncpu usage_idle=NaN"},
+		{name: "reserved tag", body: "cpu,__name__=x usage_idle=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := storage.New(&storage.MetricsStorageConfig{})
+			metricHandler := New(store, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/write", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			metricHandler.WriteInflux(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+			}
+			if len(store.Metrics()) != 0 {
+				t.Errorf("expected batch to be rejected as a whole, got %d metrics", len(store.Metrics()))
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package interfaces
+
+import (
+	"time"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+)
+
+// History определяет интерфейс хранилища истории обновлений метрик.
+type History interface {
+	Record(source string, metrics []models.Metrics) error
+	Query(mType, id string, from, to time.Time, step time.Duration) (*models.MetricHistory, error)
+}
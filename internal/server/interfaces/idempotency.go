@@ -0,0 +1,7 @@
+package interfaces
+
+// Idempotency определяет интерфейс хранилища идентификаторов примененных батчей.
+type Idempotency interface {
+	Seen(key string) (bool, error)
+	Remember(key string) error
+}
@@ -13,5 +13,8 @@ type Storage interface {
 	UpdateMetric(metric models.Metrics) error
 	Metric(id string) (models.Metrics, bool)
 	UpdateMetricsBatch(metrics []models.Metrics) error
+	UpdateMetricFrom(source string, metric models.Metrics) error
+	UpdateMetricsBatchFrom(source string, metrics []models.Metrics) error
+	UpdateMetricsBatchOnce(source, key string, metrics []models.Metrics) (bool, error)
 	ResetAll()
-}
\ No newline at end of file
+}
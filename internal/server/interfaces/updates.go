@@ -0,0 +1,8 @@
+package interfaces
+
+import "github.com/Ko4etov/go-metrics/internal/models"
+
+// Updates определяет интерфейс подписки на изменения метрик хранилища.
+type Updates interface {
+	Subscribe(filter func(models.Metrics) bool, buffer int) (<-chan models.Metrics, func())
+}
@@ -2,142 +2,220 @@
 package middlewares
 
 import (
-	"bytes"
 	"compress/gzip"
-	"fmt"
 	"io"
 	"net/http"
-	"strconv"
 	"strings"
+	"sync"
 )
 
-// compressionWriter оборачивает ResponseWriter для буферизации ответа.
+// compressionThreshold - минимальный размер тела ответа в байтах, начиная с которого
+// ответ сжимается. Более короткие ответы отправляются как есть: заголовок и служебные
+// данные gzip сделали бы их только длиннее.
+const compressionThreshold = 512
+
+// gzipWriterPool переиспользует gzip-компрессоры между ответами.
+var gzipWriterPool = sync.Pool{
+	New: func() any {
+		return gzip.NewWriter(io.Discard)
+	},
+}
+
+// gzipReaderPool переиспользует gzip-декомпрессоры между запросами.
+var gzipReaderPool = sync.Pool{
+	New: func() any {
+		return new(gzip.Reader)
+	},
+}
+
+// compressionWriter сжимает ответ на лету.
+//
+// Начало тела накапливается, пока не станет известно, превышает ли ответ порог
+// compressionThreshold. После этого заголовки отправляются клиенту, а дальнейшие
+// записи передаются в ответ напрямую или через gzip-компрессор.
 type compressionWriter struct {
 	http.ResponseWriter
-	buffer      *bytes.Buffer
-	header      http.Header
-	statusCode  int
-	wroteHeader bool
+	acceptsGzip bool         // клиент принимает ответы, сжатые gzip
+	statusCode  int          // статус код ответа
+	buffer      []byte       // начало тела до принятия решения о сжатии
+	committed   bool         // заголовки отправлены, решение о сжатии принято
+	gzWriter    *gzip.Writer // компрессор сжимаемого ответа
 }
 
 // newCompressionWriter создает новый compressionWriter.
-func newCompressionWriter(w http.ResponseWriter) *compressionWriter {
+func newCompressionWriter(w http.ResponseWriter, acceptsGzip bool) *compressionWriter {
 	return &compressionWriter{
 		ResponseWriter: w,
-		buffer:         &bytes.Buffer{},
-		header:         make(http.Header),
+		acceptsGzip:    acceptsGzip,
 		statusCode:     http.StatusOK,
 	}
 }
 
-// Header возвращает заголовки ответа.
-func (w *compressionWriter) Header() http.Header {
-	return w.header
+// WriteHeader запоминает статус код ответа до отправки заголовков.
+func (w *compressionWriter) WriteHeader(statusCode int) {
+	if !w.committed {
+		w.statusCode = statusCode
+	}
 }
 
-// Write записывает данные в буфер.
+// Write сжимает данные или передает их в ответ, накапливая начало тела до порога сжатия.
 func (w *compressionWriter) Write(data []byte) (int, error) {
-	return w.buffer.Write(data)
-}
+	if !w.committed {
+		if !w.shouldCompress() {
+			if err := w.commit(false); err != nil {
+				return 0, err
+			}
+		} else if len(w.buffer)+len(data) < compressionThreshold {
+			w.buffer = append(w.buffer, data...)
+			return len(data), nil
+		} else if err := w.commit(true); err != nil {
+			return 0, err
+		}
+	}
 
-// WriteHeader устанавливает статус код ответа.
-func (w *compressionWriter) WriteHeader(statusCode int) {
-	w.statusCode = statusCode
-	w.wroteHeader = true
+	if w.gzWriter != nil {
+		return w.gzWriter.Write(data)
+	}
+
+	return w.ResponseWriter.Write(data)
 }
 
-// shouldDecompressRequest проверяет, нужно ли распаковывать тело запроса.
-func shouldDecompressRequest(req *http.Request) bool {
-	return req.Header.Get("Content-Encoding") == "gzip" &&
-		(req.Header.Get("Content-Type") == "application/json" ||
-			req.Header.Get("Content-Type") == "text/html")
+// Flush отправляет накопленные данные клиенту. Если решение о сжатии еще не принято,
+// ответ сжимается при выполнении остальных условий независимо от порога.
+func (w *compressionWriter) Flush() {
+	if !w.committed {
+		w.commit(w.shouldCompress())
+	} else if w.gzWriter != nil {
+		w.gzWriter.Flush()
+	}
+
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
 }
 
-// shouldCompressResponse проверяет, нужно ли сжимать тело ответа.
-func shouldCompressResponse(req *http.Request, responseContentType string) bool {
-	acceptEncoding := req.Header.Get("Accept-Encoding")
+// Unwrap возвращает исходный ResponseWriter для http.ResponseController.
+func (w *compressionWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
 
-	return strings.Contains(acceptEncoding, "gzip") &&
-		(responseContentType == "application/json" || responseContentType == "text/html")
+// shouldCompress проверяет, можно ли сжимать ответ.
+func (w *compressionWriter) shouldCompress() bool {
+	return w.acceptsGzip &&
+		bodyAllowedForStatus(w.statusCode) &&
+		compressibleContentType(w.Header().Get("Content-Type"))
 }
 
-// decompressRequestBody распаковывает сжатое тело запроса.
-func decompressRequestBody(req *http.Request) error {
-	gzReader, err := gzip.NewReader(req.Body)
-	if err != nil {
-		return err
+// commit отправляет заголовки ответа и накопленное начало тела.
+func (w *compressionWriter) commit(compress bool) error {
+	w.committed = true
+
+	if compress {
+		header := w.Header()
+		header.Set("Content-Encoding", "gzip")
+		header.Add("Vary", "Accept-Encoding")
+		header.Del("Content-Length")
+
+		w.gzWriter = gzipWriterPool.Get().(*gzip.Writer)
+		w.gzWriter.Reset(w.ResponseWriter)
 	}
-	defer gzReader.Close()
 
-	decompressedBody, err := io.ReadAll(gzReader)
-	if err != nil {
-		return fmt.Errorf("error decompressing request: %w", err)
+	w.ResponseWriter.WriteHeader(w.statusCode)
+
+	if len(w.buffer) == 0 {
+		return nil
 	}
 
-	req.Body = io.NopCloser(bytes.NewBuffer(decompressedBody))
-	req.ContentLength = int64(len(decompressedBody))
-	req.Header.Del("Content-Encoding")
+	buffer := w.buffer
+	w.buffer = nil
+
+	if w.gzWriter != nil {
+		_, err := w.gzWriter.Write(buffer)
+		return err
+	}
 
-	return nil
+	_, err := w.ResponseWriter.Write(buffer)
+	return err
 }
 
-// compressResponseBody сжимает тело ответа с использованием gzip.
-func compressResponseBody(data []byte) ([]byte, error) {
-	var compressedBuf bytes.Buffer
-	gzWriter := gzip.NewWriter(&compressedBuf)
+// close завершает ответ: отправляет несжатый ответ короче порога
+// или дописывает окончание gzip-потока и возвращает компрессор в пул.
+func (w *compressionWriter) close() error {
+	if !w.committed {
+		if err := w.commit(false); err != nil {
+			return err
+		}
+	}
 
-	if _, err := gzWriter.Write(data); err != nil {
-		return nil, err
+	if w.gzWriter == nil {
+		return nil
 	}
 
-	if err := gzWriter.Close(); err != nil {
+	err := w.gzWriter.Close()
+	w.gzWriter.Reset(io.Discard)
+	gzipWriterPool.Put(w.gzWriter)
+	w.gzWriter = nil
+
+	return err
+}
+
+// bodyAllowedForStatus проверяет, может ли ответ с данным статусом содержать тело.
+func bodyAllowedForStatus(statusCode int) bool {
+	return statusCode >= http.StatusOK &&
+		statusCode != http.StatusNoContent &&
+		statusCode != http.StatusNotModified
+}
+
+// compressibleContentType проверяет, сжимается ли ответ с данным типом содержимого.
+func compressibleContentType(contentType string) bool {
+	return contentType == "application/json" || contentType == "text/html"
+}
+
+// shouldDecompressRequest проверяет, нужно ли распаковывать тело запроса.
+// Текстовые тела (text/plain) присылают, например, клиенты протокола InfluxDB.
+func shouldDecompressRequest(req *http.Request) bool {
+	return req.Header.Get("Content-Encoding") == "gzip" &&
+		(req.Header.Get("Content-Type") == "application/json" ||
+			req.Header.Get("Content-Type") == "text/html" ||
+			strings.HasPrefix(req.Header.Get("Content-Type"), "text/plain"))
+}
+
+// acceptsGzip проверяет, принимает ли клиент ответы, сжатые gzip.
+func acceptsGzip(req *http.Request) bool {
+	return strings.Contains(req.Header.Get("Accept-Encoding"), "gzip")
+}
+
+// decompressRequestBody подменяет тело запроса потоком распаковки.
+// Возвращенный декомпрессор нужно вернуть в пул после обработки запроса.
+func decompressRequestBody(req *http.Request) (*gzip.Reader, error) {
+	gzReader := gzipReaderPool.Get().(*gzip.Reader)
+	if err := gzReader.Reset(req.Body); err != nil {
+		gzipReaderPool.Put(gzReader)
 		return nil, err
 	}
 
-	return compressedBuf.Bytes(), nil
+	req.Body = io.NopCloser(gzReader)
+	req.ContentLength = -1
+	req.Header.Del("Content-Encoding")
+	req.Header.Del("Content-Length")
+
+	return gzReader, nil
 }
 
-// WithCompression возвра middleware для сжатия ответов и распаковки запросов.
+// WithCompression возвращает middleware для сжатия ответов и распаковки запросов.
 func WithCompression(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
 		if shouldDecompressRequest(req) {
-			if err := decompressRequestBody(req); err != nil {
+			gzReader, err := decompressRequestBody(req)
+			if err != nil {
 				http.Error(res, err.Error(), http.StatusBadRequest)
 				return
 			}
+			defer gzipReaderPool.Put(gzReader)
 		}
 
-		compWriter := newCompressionWriter(res)
+		compWriter := newCompressionWriter(res, acceptsGzip(req))
 		next.ServeHTTP(compWriter, req)
-
-		responseContentType := compWriter.header.Get("Content-Type")
-		shouldCompress := shouldCompressResponse(req, responseContentType)
-
-		var finalBody []byte
-
-		if shouldCompress && compWriter.buffer.Len() > 0 {
-			compressedBody, err := compressResponseBody(compWriter.buffer.Bytes())
-			if err != nil {
-				http.Error(res, "Error compressing response: "+err.Error(), http.StatusInternalServerError)
-				return
-			}
-			finalBody = compressedBody
-			res.Header().Set("Content-Encoding", "gzip")
-			res.Header().Set("Vary", "Accept-Encoding")
-		} else {
-			finalBody = compWriter.buffer.Bytes()
-		}
-
-		for key, values := range compWriter.header {
-			res.Header()[key] = values
-		}
-
-		res.Header().Set("Content-Length", strconv.Itoa(len(finalBody)))
-
-		if compWriter.wroteHeader {
-			res.WriteHeader(compWriter.statusCode)
-		}
-
-		res.Write(finalBody)
+		compWriter.close()
 	})
-}
\ No newline at end of file
+}
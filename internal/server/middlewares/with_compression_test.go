@@ -0,0 +1,146 @@
+package middlewares
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithCompression_Response(t *testing.T) {
+	large := strings.Repeat("a", compressionThreshold)
+
+	tests := []struct {
+		name           string
+		contentType    string
+		acceptEncoding string
+		status         int
+		body           string
+		wantGzip       bool
+	}{
+		{"large json", "application/json", "gzip", http.StatusOK, large, true},
+		{"large html", "text/html", "gzip, deflate", http.StatusOK, large, true},
+		{"below threshold", "application/json", "gzip", http.StatusOK, large[1:], false},
+		{"gzip not accepted", "application/json", "", http.StatusOK, large, false},
+		{"plain text", "text/plain", "gzip", http.StatusOK, large, false},
+		{"error status", "application/json", "gzip", http.StatusBadRequest, large, true},
+		{"empty body", "application/json", "gzip", http.StatusNotFound, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := WithCompression(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(tt.status)
+				// Пишем частями, чтобы проверить накопление до порога.
+				for i := 0; i < len(tt.body); i += 100 {
+					w.Write([]byte(tt.body[i:min(i+100, len(tt.body))]))
+				}
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+
+			gotGzip := rec.Header().Get("Content-Encoding") == "gzip"
+			if gotGzip != tt.wantGzip {
+				t.Fatalf("compressed = %v, want %v", gotGzip, tt.wantGzip)
+			}
+
+			body := rec.Body.Bytes()
+			if gotGzip {
+				gzReader, err := gzip.NewReader(bytes.NewReader(body))
+				if err != nil {
+					t.Fatalf("invalid gzip response: %v", err)
+				}
+				if body, err = io.ReadAll(gzReader); err != nil {
+					t.Fatalf("decompress response: %v", err)
+				}
+			}
+
+			if string(body) != tt.body {
+				t.Errorf("body mismatch: got %d bytes, want %d", len(body), len(tt.body))
+			}
+		})
+	}
+}
+
+func TestWithCompression_Flush(t *testing.T) {
+	handler := WithCompression(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(": connected\n\n"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("event: metric\n\n"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/stream", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if !rec.Flushed {
+		t.Error("expected response to be flushed")
+	}
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("unexpected Content-Encoding %q", got)
+	}
+	if got, want := rec.Body.String(), ": connected\n\nevent: metric\n\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestWithCompression_Request(t *testing.T) {
+	body := `[{"id":"Alloc","type":"gauge","value":1}]`
+
+	var compressed bytes.Buffer
+	gzWriter := gzip.NewWriter(&compressed)
+	gzWriter.Write([]byte(body))
+	gzWriter.Close()
+
+	var received string
+	handler := WithCompression(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		received = string(data)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(compressed.Bytes()))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if received != body {
+		t.Errorf("body = %q, want %q", received, body)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/updates/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid gzip: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
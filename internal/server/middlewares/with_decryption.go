@@ -0,0 +1,104 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+	hybridcrypto "github.com/Ko4etov/go-metrics/internal/service/hybrid_crypto"
+)
+
+// Режимы проверки шифрования запросов записи метрик при заданном закрытом ключе.
+const (
+	CryptoModeOptional = "optional" // незашифрованные запросы принимаются
+	CryptoModeRequire  = "require"  // незашифрованные запросы отклоняются
+)
+
+// decryptedKey - ключ контекста для признака расшифрованного запроса.
+type decryptedKey struct{}
+
+// Decrypted сообщает, был ли запрос расшифрован middleware WithDecryption.
+func Decrypted(ctx context.Context) bool {
+	decrypted, _ := ctx.Value(decryptedKey{}).(bool)
+	return decrypted
+}
+
+// ValidateCryptoMode проверяет корректность режима проверки шифрования.
+func ValidateCryptoMode(mode string) error {
+	switch mode {
+	case "", CryptoModeOptional, CryptoModeRequire:
+		return nil
+	}
+
+	return fmt.Errorf("invalid crypto mode %q: expected %s or %s",
+		mode, CryptoModeOptional, CryptoModeRequire)
+}
+
+// decryptRequestBody расшифровывает тело запроса закрытым ключом.
+func decryptRequestBody(req *http.Request, privateKey *rsa.PrivateKey) error {
+	encryptedBody, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+
+	decryptedBody, err := hybridcrypto.Decrypt(privateKey, encryptedBody)
+	if err != nil {
+		return err
+	}
+
+	req.Body = io.NopCloser(bytes.NewBuffer(decryptedBody))
+	req.ContentLength = int64(len(decryptedBody))
+	req.Header.Del(hybridcrypto.Header)
+
+	return nil
+}
+
+// WithDecryption возвращает middleware для расшифровки тел запросов,
+// зашифрованных открытым ключом сервера. Должен выполняться до распаковки и проверки хеша.
+// Незашифрованные запросы передаются дальше без изменений, их отклоняет WithEncryptionCheck.
+func WithDecryption(privateKey *rsa.PrivateKey) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			scheme := req.Header.Get(hybridcrypto.Header)
+			if scheme == "" {
+				next.ServeHTTP(res, req)
+				return
+			}
+
+			if privateKey == nil || scheme != hybridcrypto.Scheme {
+				http.Error(res, "Unsupported request encryption", http.StatusBadRequest)
+				return
+			}
+
+			if err := decryptRequestBody(req, privateKey); err != nil {
+				logger.Logger.Warnf("Request decryption failed: %s %s: %v", req.Method, req.URL.Path, err)
+				http.Error(res, "Error decrypting request", http.StatusBadRequest)
+				return
+			}
+
+			ctx := context.WithValue(req.Context(), decryptedKey{}, true)
+			next.ServeHTTP(res, req.WithContext(ctx))
+		})
+	}
+}
+
+// WithEncryptionCheck возвращает middleware, отклоняющий незашифрованные запросы,
+// если закрытый ключ задан и режим mode не CryptoModeOptional. Подключается к маршрутам
+// записи метрик после WithDecryption.
+func WithEncryptionCheck(privateKey *rsa.PrivateKey, mode string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			if privateKey == nil || mode == CryptoModeOptional || Decrypted(req.Context()) {
+				next.ServeHTTP(res, req)
+				return
+			}
+
+			logger.Logger.Warnf("Unencrypted request rejected: %s %s", req.Method, req.URL.Path)
+			http.Error(res, "Request must be encrypted", http.StatusBadRequest)
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package middlewares
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/rsa"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+	hybridcrypto "github.com/Ko4etov/go-metrics/internal/service/hybrid_crypto"
+)
+
+func TestWithDecryption(t *testing.T) {
+	logger.Initialize("error")
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+
+	body := []byte(`[{"id":"Alloc","type":"gauge","value":1}]`)
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write(body)
+	gz.Close()
+
+	encrypted, err := hybridcrypto.Encrypt(&privateKey.PublicKey, compressed.Bytes())
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+
+	var received []byte
+	handler := WithDecryption(privateKey)(WithCompression(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	newRequest := func(data []byte, scheme string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "gzip")
+		if scheme != "" {
+			req.Header.Set(hybridcrypto.Header, scheme)
+		}
+		return req
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newRequest(encrypted, hybridcrypto.Scheme))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	if !bytes.Equal(received, body) {
+		t.Errorf("Expected decrypted body %s, got %s", body, received)
+	}
+
+	received = nil
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, newRequest(compressed.Bytes(), ""))
+
+	if rec.Code != http.StatusOK || !bytes.Equal(received, body) {
+		t.Errorf("Expected plain request to pass through, got status %d", rec.Code)
+	}
+
+	tampered := append([]byte(nil), encrypted...)
+	tampered[len(tampered)-1] ^= 0xff
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, newRequest(tampered, hybridcrypto.Scheme))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for tampered body, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	WithDecryption(nil)(http.NotFoundHandler()).ServeHTTP(rec, newRequest(encrypted, hybridcrypto.Scheme))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without private key, got %d", rec.Code)
+	}
+}
+
+func TestWithEncryptionCheck(t *testing.T) {
+	logger.Initialize("error")
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+
+	body := []byte(`{"id":"Alloc","type":"gauge","value":1}`)
+	encrypted, err := hybridcrypto.Encrypt(&privateKey.PublicKey, body)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		key        *rsa.PrivateKey
+		mode       string
+		encrypted  bool
+		wantStatus int
+	}{
+		{"require encrypted", privateKey, CryptoModeRequire, true, http.StatusOK},
+		{"require plain", privateKey, CryptoModeRequire, false, http.StatusBadRequest},
+		{"default plain", privateKey, "", false, http.StatusBadRequest},
+		{"optional plain", privateKey, CryptoModeOptional, false, http.StatusOK},
+		{"no key plain", nil, CryptoModeRequire, false, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := WithDecryption(tt.key)(WithEncryptionCheck(tt.key, tt.mode)(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusOK)
+				})))
+
+			req := httptest.NewRequest(http.MethodPost, "/update/", bytes.NewReader(body))
+			if tt.encrypted {
+				req = httptest.NewRequest(http.MethodPost, "/update/", bytes.NewReader(encrypted))
+				req.Header.Set(hybridcrypto.Header, hybridcrypto.Scheme)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestValidateCryptoMode(t *testing.T) {
+	for _, mode := range []string{"", CryptoModeOptional, CryptoModeRequire} {
+		if err := ValidateCryptoMode(mode); err != nil {
+			t.Errorf("ValidateCryptoMode(%q) error = %v", mode, err)
+		}
+	}
+
+	if err := ValidateCryptoMode("strict"); err == nil {
+		t.Error("Expected error for unknown crypto mode")
+	}
+}
@@ -5,52 +5,129 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
 	"io"
 	"net/http"
+	"sync/atomic"
 
 	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+	"github.com/Ko4etov/go-metrics/internal/server/service/selfmetrics"
 )
 
+// Режимы проверки подписи запросов.
+const (
+	HashModeOff     = "off"     // подпись запросов не проверяется
+	HashModeLog     = "log"     // неверная или отсутствующая подпись только логируется
+	HashModeEnforce = "enforce" // запросы с неверной или отсутствующей подписью отклоняются
+)
+
+// ErrInvalidSignature возвращается для запроса, отклоненного из-за неверной или отсутствующей подписи.
+var ErrInvalidSignature = errors.New("invalid request signature")
+
 // HashConfig содержит конфигурацию для хеширования.
 type HashConfig struct {
-	SecretKey string // секретный ключ для вычисления HMAC
+	SecretKey   string                // секретный ключ для вычисления HMAC
+	Mode        string                // режим проверки подписи запросов, по умолчанию HashModeLog
+	SelfMetrics *selfmetrics.Registry // собственные метрики сервера (опционально)
+	rejected    atomic.Int64          // количество отклоненных запросов
+}
+
+// RejectedRequests возвращает количество запросов, отклоненных из-за неверной подписи.
+func (c *HashConfig) RejectedRequests() int64 {
+	return c.rejected.Load()
+}
+
+// Verify проверяет подпись данных запроса в соответствии с режимом проверки.
+// В режиме HashModeEnforce возвращает ErrInvalidSignature для неподписанного запроса
+// или запроса с неверной подписью, в остальных режимах только логирует расхождение.
+// Описание запроса description используется в сообщениях лога.
+func (c *HashConfig) Verify(data []byte, receivedHash, description string) error {
+	if c.SecretKey == "" || c.Mode == HashModeOff {
+		return nil
+	}
+
+	var reason string
+
+	switch {
+	case receivedHash == "":
+		if c.Mode != HashModeEnforce {
+			return nil
+		}
+		reason = "missing signature"
+	default:
+		expectedHash := calculateHash(data, c.SecretKey)
+		if hmac.Equal([]byte(receivedHash), []byte(expectedHash)) {
+			return nil
+		}
+		reason = "signature mismatch"
+	}
+
+	if c.Mode != HashModeEnforce {
+		logger.Logger.Warnf("Hash validation failed - %s: %s", reason, description)
+		return nil
+	}
+
+	rejected := c.rejected.Add(1)
+	c.SelfMetrics.Add(selfmetrics.HashRejectedRequests, nil, 1)
+	logger.Logger.Warnf("Request rejected - %s: %s (total rejected: %d)",
+		reason, description, rejected)
+
+	return ErrInvalidSignature
+}
+
+// ValidateHashMode проверяет корректность режима проверки подписи.
+func ValidateHashMode(mode string) error {
+	switch mode {
+	case "", HashModeOff, HashModeLog, HashModeEnforce:
+		return nil
+	}
+
+	return fmt.Errorf("invalid hash mode %q: expected %s, %s or %s",
+		mode, HashModeOff, HashModeLog, HashModeEnforce)
 }
 
 // hashWriter оборачивает ResponseWriter для вычисления хеша ответа.
+// Хеш вычисляется по мере записи тела, само тело передается в ответ без буферизации.
 type hashWriter struct {
 	http.ResponseWriter
-	secretKey   string
-	buffer      *bytes.Buffer
-	header      http.Header
-	statusCode  int
-	wroteHeader bool
+	mac     hash.Hash // HMAC записанного тела
+	written bool      // в ответ записаны данные
 }
 
 // newHashWriter создает новый hashWriter.
 func newHashWriter(w http.ResponseWriter, secretKey string) *hashWriter {
 	return &hashWriter{
 		ResponseWriter: w,
-		secretKey:      secretKey,
-		buffer:         &bytes.Buffer{},
-		header:         make(http.Header),
-		statusCode:     http.StatusOK,
+		mac:            hmac.New(sha256.New, []byte(secretKey)),
 	}
 }
 
-// Header возвращает заголовки ответа.
-func (w *hashWriter) Header() http.Header {
-	return w.header
+// Write добавляет данные к хешу и передает их в ответ.
+func (w *hashWriter) Write(data []byte) (int, error) {
+	if len(data) > 0 {
+		w.written = true
+		w.mac.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
 }
 
-// Write записывает данные в буфер.
-func (w *hashWriter) Write(data []byte) (int, error) {
-	return w.buffer.Write(data)
+// Flush отправляет записанные данные клиенту.
+func (w *hashWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap возвращает исходный ResponseWriter для http.ResponseController.
+func (w *hashWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
 }
 
-// WriteHeader устанавливает статус код ответа.
-func (w *hashWriter) WriteHeader(statusCode int) {
-	w.statusCode = statusCode
-	w.wroteHeader = true
+// sum возвращает хеш записанного тела в шестнадцатеричном виде.
+func (w *hashWriter) sum() string {
+	return hex.EncodeToString(w.mac.Sum(nil))
 }
 
 // calculateHash вычисляет HMAC-SHA256 хеш для данных.
@@ -64,13 +141,62 @@ func calculateHash(data []byte, secretKey string) string {
 	return hex.EncodeToString(h.Sum(nil))
 }
 
-// shouldValidateHash проверяет, нужно ли валидировать хеш запроса.
-func shouldValidateHash(req *http.Request) bool {
-	return (req.Method == http.MethodPost || req.Method == http.MethodPut) &&
-		req.Body != nil && req.Body != http.NoBody
+// signedRequestData возвращает данные запроса, покрываемые подписью:
+// тело запроса, а для запросов без тела - путь запроса вместе с параметрами,
+// в которых передаются метки метрики.
+func signedRequestData(req *http.Request, body []byte) []byte {
+	if len(body) == 0 {
+		return []byte(req.URL.RequestURI())
+	}
+
+	return body
+}
+
+// verifyRequestHash проверяет подпись запроса.
+// Возвращает false, если запрос должен быть отклонен.
+func verifyRequestHash(res http.ResponseWriter, req *http.Request, config *HashConfig) bool {
+	var bodyBytes []byte
+
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		bodyBytes, err = io.ReadAll(req.Body)
+		if err != nil {
+			http.Error(res, "Error reading request body", http.StatusBadRequest)
+			return false
+		}
+
+		req.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+	}
+
+	err := config.Verify(signedRequestData(req, bodyBytes), req.Header.Get("HashSHA256"),
+		req.Method+" "+req.URL.Path)
+	if err != nil {
+		http.Error(res, "Invalid request signature", http.StatusBadRequest)
+		return false
+	}
+
+	return true
 }
 
-// WithHashing возвращает middleware для проверки и добавления хешей.
+// WithSignatureCheck возвращает middleware для проверки подписи запросов
+// в соответствии с режимом config.Mode. Подключается к маршрутам записи метрик:
+// запросы чтения, в том числе POST /value/, подписи не требуют.
+func WithSignatureCheck(config *HashConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			if config.SecretKey != "" && config.Mode != HashModeOff {
+				if !verifyRequestHash(res, req, config) {
+					return
+				}
+			}
+
+			next.ServeHTTP(res, req)
+		})
+	}
+}
+
+// WithHashing возвращает middleware для подписи ответов.
+// Подпись ответа с телом передается в трейлере HashSHA256.
 func WithHashing(config *HashConfig) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
@@ -79,39 +205,17 @@ func WithHashing(config *HashConfig) func(http.Handler) http.Handler {
 				return
 			}
 
-			if shouldValidateHash(req) {
-				bodyBytes, err := io.ReadAll(req.Body)
-				if err != nil {
-					http.Error(res, "Error reading request body", http.StatusBadRequest)
-					return
-				}
-
-				req.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-
-				receivedHash := req.Header.Get("HashSHA256")
-
-				if receivedHash != "" {
-					expectedHash := calculateHash(bodyBytes, config.SecretKey)
-
-					if !hmac.Equal([]byte(receivedHash), []byte(expectedHash)) {
-						logger.Logger.Warnln("Hash validation failed - JSON mismatch")
-					}
-				}
-			}
+			// Заголовки отправляются раньше, чем тело записано целиком, поэтому подпись
+			// передается трейлером. Трейлер объявляется заранее: иначе короткий ответ
+			// будет отправлен с Content-Length, без трейлеров.
+			res.Header().Add("Trailer", "HashSHA256")
 
 			hashWriter := newHashWriter(res, config.SecretKey)
 			next.ServeHTTP(hashWriter, req)
 
-			if hashWriter.buffer.Len() > 0 {
-				hash := calculateHash(hashWriter.buffer.Bytes(), config.SecretKey)
-				hashWriter.header.Set("HashSHA256", hash)
-			}
-
-			for key, values := range hashWriter.header {
-				res.Header()[key] = values
+			if hashWriter.written {
+				res.Header().Set("HashSHA256", hashWriter.sum())
 			}
-			res.WriteHeader(hashWriter.statusCode)
-			res.Write(hashWriter.buffer.Bytes())
 		})
 	}
 }
\ No newline at end of file
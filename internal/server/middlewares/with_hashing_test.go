@@ -0,0 +1,126 @@
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+)
+
+func TestWithSignatureCheck_Modes(t *testing.T) {
+	logger.Initialize("error")
+
+	const key = "secret"
+	body := `[{"id":"Alloc","type":"gauge","value":1}]`
+
+	tests := []struct {
+		name         string
+		mode         string
+		path         string
+		body         string
+		hash         string
+		wantStatus   int
+		wantHandled  bool
+		wantRejected int64
+	}{
+		{"enforce valid", HashModeEnforce, "/updates/", body, calculateHash([]byte(body), key), http.StatusOK, true, 0},
+		{"enforce missing", HashModeEnforce, "/updates/", body, "", http.StatusBadRequest, false, 1},
+		{"enforce mismatch", HashModeEnforce, "/updates/", body, calculateHash([]byte("tampered"), key), http.StatusBadRequest, false, 1},
+		{"enforce path signed", HashModeEnforce, "/update/gauge/Alloc/1", "", calculateHash([]byte("/update/gauge/Alloc/1"), key), http.StatusOK, true, 0},
+		{"enforce labels signed", HashModeEnforce, "/update/gauge/Alloc/1?host=web1", "", calculateHash([]byte("/update/gauge/Alloc/1?host=web1"), key), http.StatusOK, true, 0},
+		{"enforce labels unsigned", HashModeEnforce, "/update/gauge/Alloc/1?host=web1", "", calculateHash([]byte("/update/gauge/Alloc/1"), key), http.StatusBadRequest, false, 1},
+		{"enforce path unsigned", HashModeEnforce, "/update/gauge/Alloc/1", "", "", http.StatusBadRequest, false, 1},
+		{"log mismatch", HashModeLog, "/updates/", body, "bad", http.StatusOK, true, 0},
+		{"default missing", "", "/updates/", body, "", http.StatusOK, true, 0},
+		{"off mismatch", HashModeOff, "/updates/", body, "bad", http.StatusOK, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &HashConfig{SecretKey: key, Mode: tt.mode}
+
+			handled := false
+			handler := WithSignatureCheck(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handled = true
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.body == "" {
+				req.Body = http.NoBody
+			}
+			if tt.hash != "" {
+				req.Header.Set("HashSHA256", tt.hash)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if handled != tt.wantHandled {
+				t.Errorf("Expected handled %v, got %v", tt.wantHandled, handled)
+			}
+			if got := config.RejectedRequests(); got != tt.wantRejected {
+				t.Errorf("Expected %d rejected requests, got %d", tt.wantRejected, got)
+			}
+		})
+	}
+}
+
+func TestValidateHashMode(t *testing.T) {
+	for _, mode := range []string{"", HashModeOff, HashModeLog, HashModeEnforce} {
+		if err := ValidateHashMode(mode); err != nil {
+			t.Errorf("Expected mode %q to be valid: %v", mode, err)
+		}
+	}
+
+	if err := ValidateHashMode("strict"); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
+}
+
+func TestWithHashing_ResponseTrailer(t *testing.T) {
+	logger.Initialize("error")
+
+	const key = "secret"
+	body := strings.Repeat(`{"id":"Alloc","type":"gauge","value":1}`, 50)
+
+	handler := WithCompression(WithHashing(&HashConfig{SecretKey: key})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(body[:100]))
+			w.Write([]byte(body[100:]))
+		})))
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+
+	if string(data) != body {
+		t.Fatalf("body mismatch: got %d bytes, want %d", len(data), len(body))
+	}
+	if !resp.Uncompressed {
+		t.Error("expected gzip-compressed response")
+	}
+	if got := resp.Header.Get("HashSHA256"); got != "" {
+		t.Errorf("unexpected HashSHA256 header %q", got)
+	}
+	if got, want := resp.Trailer.Get("HashSHA256"), calculateHash([]byte(body), key); got != want {
+		t.Errorf("trailer HashSHA256 = %q, want %q", got, want)
+	}
+}
@@ -1,17 +1,58 @@
 package middlewares
 
 import (
-	"bytes"
+	"context"
+	cryptorand "crypto/rand"
+	"math/rand/v2"
+	"net"
 	"net/http"
+	"time"
+
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
 )
 
-// loggingWriter перехватывает ответ для логирования.
+// maxRequestIDLength - максимальная длина идентификатора запроса, принимаемого от клиента.
+const maxRequestIDLength = 128
+
+// LoggingConfig содержит конфигурацию журнала доступа.
+type LoggingConfig struct {
+	SampleRate    float64       // доля обычных запросов, попадающих в журнал, от 0 до 1
+	SlowThreshold time.Duration // порог медленного запроса, 0 - отключен
+}
+
+// accessLogKey - ключ контекста для сведений о запросе, собираемых для журнала доступа.
+type accessLogKey struct{}
+
+// accessLogEntry содержит сведения о запросе, которые заполняют обработчики.
+type accessLogEntry struct {
+	requestID       string // идентификатор запроса
+	acceptedMetrics int    // количество принятых метрик
+	hasMetrics      bool   // обработчик сообщил количество принятых метрик
+}
+
+// RequestID возвращает идентификатор текущего запроса.
+func RequestID(ctx context.Context) string {
+	if entry, ok := ctx.Value(accessLogKey{}).(*accessLogEntry); ok {
+		return entry.requestID
+	}
+	return ""
+}
+
+// SetAcceptedMetrics сообщает журналу доступа количество метрик, принятых обработчиком записи.
+func SetAcceptedMetrics(ctx context.Context, count int) {
+	if entry, ok := ctx.Value(accessLogKey{}).(*accessLogEntry); ok {
+		entry.acceptedMetrics = count
+		entry.hasMetrics = true
+	}
+}
+
+// loggingWriter перехватывает статус код и размер ответа для логирования.
 type loggingWriter struct {
 	http.ResponseWriter
-	statusCode int
-	size       int
-	buffer     *bytes.Buffer
-	header     http.Header
+	statusCode  int
+	size        int
+	wroteHeader bool
+	streamed    bool // ответ передавался потоком, например Server-Sent Events
 }
 
 // newLoggingWriter создает новый loggingWriter.
@@ -19,45 +60,131 @@ func newLoggingWriter(w http.ResponseWriter) *loggingWriter {
 	return &loggingWriter{
 		ResponseWriter: w,
 		statusCode:     http.StatusOK,
-		buffer:         &bytes.Buffer{},
-		header:         make(http.Header),
 	}
 }
 
-// Header возвращает заголовки ответа.
-func (w *loggingWriter) Header() http.Header {
-	return w.header
-}
-
-// Write записывает данные в буфер.
+// Write передает данные в ответ и учитывает их размер.
 func (w *loggingWriter) Write(data []byte) (int, error) {
-	size, err := w.buffer.Write(data)
+	w.wroteHeader = true
+	size, err := w.ResponseWriter.Write(data)
 	w.size += size
 	return size, err
 }
 
-// WriteHeader устанавливает статус код ответа.
+// Flush отправляет записанные данные клиенту.
+func (w *loggingWriter) Flush() {
+	w.wroteHeader = true
+	w.streamed = true
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap возвращает исходный ResponseWriter для http.ResponseController.
+func (w *loggingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// WriteHeader запоминает и отправляет статус код ответа.
 func (w *loggingWriter) WriteHeader(statusCode int) {
-	w.statusCode = statusCode
+	if !w.wroteHeader {
+		w.statusCode = statusCode
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// requestID возвращает идентификатор запроса из заголовка X-Request-ID
+// или создает новый, если заголовок отсутствует или некорректен.
+func requestID(req *http.Request) string {
+	if id := req.Header.Get("X-Request-ID"); id != "" && len(id) <= maxRequestIDLength && printableASCII(id) {
+		return id
+	}
+
+	return cryptorand.Text()
 }
 
-// WithLogging возвращает middleware для логирования запросов и ответов.
-func WithLogging(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+// printableASCII проверяет, что строка состоит только из печатных ASCII-символов.
+func printableASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x21 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// remoteIP возвращает IP-адрес клиента без порта.
+func remoteIP(req *http.Request) string {
+	ip, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return ip
+}
+
+// WithLogging возвращает middleware журнала доступа: одна запись на запрос с методом,
+// URI, статусом, размером ответа, длительностью, IP-адресом клиента, идентификатором
+// запроса и, для обработчиков записи, количеством принятых метрик.
+//
+// Медленные запросы (кроме потоковых) логируются с уровнем warn, ответы с ошибкой сервера - с уровнем error;
+// они попадают в журнал всегда. Остальные запросы логируются с уровнем info
+// с вероятностью config.SampleRate.
+//
+// Идентификатор запроса берется из заголовка X-Request-ID или создается,
+// возвращается клиенту в том же заголовке и доступен обработчикам через RequestID.
+func WithLogging(config *LoggingConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+
+			entry := &accessLogEntry{requestID: requestID(req)}
+			res.Header().Set("X-Request-ID", entry.requestID)
 
-		logWriter := newLoggingWriter(res)
+			logWriter := newLoggingWriter(res)
+			next.ServeHTTP(logWriter, req.WithContext(context.WithValue(req.Context(), accessLogKey{}, entry)))
 
-		next.ServeHTTP(logWriter, req)
+			duration := time.Since(start)
+			// Длительность потоковых ответов определяется клиентом, медленными они не считаются.
+			slow := config.SlowThreshold > 0 && duration >= config.SlowThreshold && !logWriter.streamed
+			failed := logWriter.statusCode >= http.StatusInternalServerError
 
-		for key, values := range logWriter.header {
-			for _, value := range values {
-				res.Header().Set(key, value)
+			if !slow && !failed && !sampled(config.SampleRate) {
+				return
 			}
-		}
 
-		res.WriteHeader(logWriter.statusCode)
-		if _, err := res.Write(logWriter.buffer.Bytes()); err != nil {
-			return
-		}
-	})
-}
\ No newline at end of file
+			fields := []any{
+				"method", req.Method,
+				"uri", req.RequestURI,
+				"status", logWriter.statusCode,
+				"size", logWriter.size,
+				"duration", duration,
+				"remote_ip", remoteIP(req),
+				"request_id", entry.requestID,
+			}
+			if entry.hasMetrics {
+				fields = append(fields, "metrics", entry.acceptedMetrics)
+			}
+
+			switch {
+			case failed:
+				logger.Logger.Errorw("request", fields...)
+			case slow:
+				logger.Logger.Warnw("slow request", fields...)
+			default:
+				logger.Logger.Infow("request", fields...)
+			}
+		})
+	}
+}
+
+// sampled решает, попадает ли обычный запрос в журнал при доле rate.
+func sampled(rate float64) bool {
+	switch {
+	case rate >= 1:
+		return true
+	case rate <= 0:
+		return false
+	}
+	return rand.Float64() < rate
+}
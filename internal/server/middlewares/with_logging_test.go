@@ -0,0 +1,120 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+)
+
+func TestWithLogging(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      LoggingConfig
+		requestID   string
+		status      int
+		delay       time.Duration
+		metrics     int
+		wantLogged  bool
+		wantLevel   zapcore.Level
+		wantMetrics bool
+	}{
+		{"write request", LoggingConfig{SampleRate: 1}, "", http.StatusOK, 0, 3, true, zapcore.InfoLevel, true},
+		{"client request id", LoggingConfig{SampleRate: 1}, "abc-123", http.StatusOK, 0, -1, true, zapcore.InfoLevel, false},
+		{"sampled out", LoggingConfig{SampleRate: 0}, "", http.StatusOK, 0, -1, false, 0, false},
+		{"server error", LoggingConfig{SampleRate: 0}, "", http.StatusInternalServerError, 0, -1, true, zapcore.ErrorLevel, false},
+		{"slow request", LoggingConfig{SampleRate: 0, SlowThreshold: time.Millisecond}, "", http.StatusOK, 5 * time.Millisecond, -1, true, zapcore.WarnLevel, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.InfoLevel)
+			previous := logger.Logger
+			logger.Logger = *zap.New(core).Sugar()
+			defer func() { logger.Logger = previous }()
+
+			var handlerRequestID string
+			handler := WithLogging(&tt.config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handlerRequestID = RequestID(r.Context())
+				time.Sleep(tt.delay)
+				if tt.metrics >= 0 {
+					SetAcceptedMetrics(r.Context(), tt.metrics)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte("ok"))
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/updates/?x=1", nil)
+			if tt.requestID != "" {
+				req.Header.Set("X-Request-ID", tt.requestID)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			responseID := rec.Header().Get("X-Request-ID")
+			if responseID == "" || responseID != handlerRequestID {
+				t.Errorf("X-Request-ID = %q, handler saw %q", responseID, handlerRequestID)
+			}
+			if tt.requestID != "" && responseID != tt.requestID {
+				t.Errorf("X-Request-ID = %q, want %q", responseID, tt.requestID)
+			}
+
+			entries := logs.All()
+			if !tt.wantLogged {
+				if len(entries) != 0 {
+					t.Fatalf("expected no log entries, got %d", len(entries))
+				}
+				return
+			}
+			if len(entries) != 1 {
+				t.Fatalf("expected 1 log entry, got %d", len(entries))
+			}
+
+			entry := entries[0]
+			if entry.Level != tt.wantLevel {
+				t.Errorf("level = %v, want %v", entry.Level, tt.wantLevel)
+			}
+
+			fields := entry.ContextMap()
+			if fields["method"] != http.MethodPost || fields["uri"] != "/updates/?x=1" {
+				t.Errorf("unexpected method/uri: %v %v", fields["method"], fields["uri"])
+			}
+			if fields["status"] != int64(tt.status) {
+				t.Errorf("status = %v, want %d", fields["status"], tt.status)
+			}
+			if fields["size"] != int64(2) {
+				t.Errorf("size = %v, want 2", fields["size"])
+			}
+			if fields["remote_ip"] != "192.0.2.1" {
+				t.Errorf("remote_ip = %v", fields["remote_ip"])
+			}
+			if fields["request_id"] != responseID {
+				t.Errorf("request_id = %v, want %q", fields["request_id"], responseID)
+			}
+			if _, ok := fields["duration"]; !ok {
+				t.Error("duration field missing")
+			}
+
+			metrics, ok := fields["metrics"]
+			if ok != tt.wantMetrics || (ok && metrics != int64(tt.metrics)) {
+				t.Errorf("metrics = %v (present %v), want %d (present %v)", metrics, ok, tt.metrics, tt.wantMetrics)
+			}
+		})
+	}
+}
+
+func TestRequestID_RejectsInvalidHeader(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "bad id\n")
+
+	if id := requestID(req); id == "bad id\n" || id == "" {
+		t.Errorf("requestID() = %q, want generated id", id)
+	}
+}
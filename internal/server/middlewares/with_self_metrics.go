@@ -0,0 +1,52 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/service/selfmetrics"
+)
+
+// unmatchedRoute - значение метки route для запросов, не совпавших ни с одним маршрутом.
+const unmatchedRoute = "unmatched"
+
+// WithSelfMetrics возвращает middleware, учитывающее HTTP-запросы в собственных
+// метриках сервера: количество по маршруту, методу и статусу, длительность по маршруту
+// и количество обрабатываемых запросов. Маршрут берется из шаблона chi, чтобы
+// параметры пути не порождали новых рядов.
+func WithSelfMetrics(registry *selfmetrics.Registry) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if registry == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+
+			registry.AddGauge(selfmetrics.HTTPRequestsInFlight, nil, 1)
+			defer registry.AddGauge(selfmetrics.HTTPRequestsInFlight, nil, -1)
+
+			// Статус ответа перехватывается так же, как для журнала доступа.
+			statusWriter := newLoggingWriter(res)
+			next.ServeHTTP(statusWriter, req)
+
+			route := unmatchedRoute
+			if routeContext := chi.RouteContext(req.Context()); routeContext != nil {
+				if pattern := routeContext.RoutePattern(); pattern != "" {
+					route = pattern
+				}
+			}
+
+			registry.Add(selfmetrics.HTTPRequestsTotal, models.Labels{
+				"route":  route,
+				"method": req.Method,
+				"code":   strconv.Itoa(statusWriter.statusCode),
+			}, 1)
+			registry.ObserveDuration(selfmetrics.HTTPRequestDuration, models.Labels{"route": route}, time.Since(start))
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/service/selfmetrics"
+)
+
+func TestWithSelfMetrics(t *testing.T) {
+	registry := selfmetrics.New()
+
+	r := chi.NewRouter()
+	r.Use(WithSelfMetrics(registry))
+	r.Get("/value/{metricType}/{metricName}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	for _, path := range []string{"/value/gauge/Alloc", "/value/gauge/HeapAlloc", "/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	metrics := make(map[string]models.Metrics)
+	for _, metric := range registry.Snapshot() {
+		metrics[metric.Key()] = metric
+	}
+
+	requests := models.Metrics{
+		ID:     selfmetrics.HTTPRequestsTotal,
+		Labels: models.Labels{"route": "/value/{metricType}/{metricName}", "method": http.MethodGet, "code": "404"},
+	}
+	if metric, ok := metrics[requests.Key()]; !ok || *metric.Delta != 2 {
+		t.Errorf("requests for route pattern = %+v, want delta 2", metric)
+	}
+
+	unmatched := models.Metrics{
+		ID:     selfmetrics.HTTPRequestsTotal,
+		Labels: models.Labels{"route": unmatchedRoute, "method": http.MethodGet, "code": "404"},
+	}
+	if _, ok := metrics[unmatched.Key()]; !ok {
+		t.Error("unmatched request not counted")
+	}
+
+	duration := models.Metrics{ID: selfmetrics.HTTPRequestDuration, Labels: models.Labels{"route": "/value/{metricType}/{metricName}"}}
+	if metric, ok := metrics[duration.Key()]; !ok || metric.Histogram.Count != 2 {
+		t.Errorf("duration histogram = %+v, want 2 observations", metric)
+	}
+
+	inFlight := models.Metrics{ID: selfmetrics.HTTPRequestsInFlight}
+	if metric, ok := metrics[inFlight.Key()]; !ok || *metric.Value != 0 {
+		t.Errorf("in-flight gauge = %+v, want 0", metric)
+	}
+}
@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+)
+
+// clientIPKey - ключ контекста для проверенного IP-адреса агента.
+type clientIPKey struct{}
+
+// ClientIP возвращает IP-адрес агента, проверенный middleware WithTrustedSubnet.
+func ClientIP(ctx context.Context) (string, bool) {
+	ip, ok := ctx.Value(clientIPKey{}).(string)
+	return ip, ok
+}
+
+// WithClientIP возвращает контекст с проверенным IP-адресом агента.
+// Используется обработчиками, проверяющими доверенную подсеть вне HTTP-middleware.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ParseTrustedSubnet разбирает доверенную подсеть в нотации CIDR.
+// Для пустой строки возвращает nil.
+func ParseTrustedSubnet(cidr string) (*net.IPNet, error) {
+	if cidr == "" {
+		return nil, nil
+	}
+
+	_, subnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+
+	return subnet, nil
+}
+
+// WithTrustedSubnet возвращает middleware, пропускающий только запросы агентов
+// из доверенной подсети. IP-адрес агента берется из заголовка X-Real-IP.
+// Если подсеть не задана, запросы пропускаются без проверки.
+func WithTrustedSubnet(subnet *net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			if subnet == nil {
+				next.ServeHTTP(res, req)
+				return
+			}
+
+			realIP := req.Header.Get("X-Real-IP")
+			ip := net.ParseIP(realIP)
+
+			if ip == nil || !subnet.Contains(ip) {
+				logger.Logger.Warnf("Request from untrusted address rejected: %s %s (X-Real-IP %q, remote %s)",
+					req.Method, req.URL.Path, realIP, req.RemoteAddr)
+				http.Error(res, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			ctx := WithClientIP(req.Context(), ip.String())
+			next.ServeHTTP(res, req.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+)
+
+func TestWithTrustedSubnet(t *testing.T) {
+	logger.Initialize("error")
+
+	subnet, err := ParseTrustedSubnet("192.168.1.0/24")
+	if err != nil {
+		t.Fatalf("ParseTrustedSubnet failed: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		subnet     bool
+		realIP     string
+		wantStatus int
+		wantIP     string
+	}{
+		{"inside subnet", true, "192.168.1.10", http.StatusOK, "192.168.1.10"},
+		{"outside subnet", true, "10.0.0.1", http.StatusForbidden, ""},
+		{"missing header", true, "", http.StatusForbidden, ""},
+		{"invalid header", true, "not-an-ip", http.StatusForbidden, ""},
+		{"no subnet configured", false, "10.0.0.1", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var trusted = subnet
+			if !tt.subnet {
+				trusted = nil
+			}
+
+			var gotIP string
+			handler := WithTrustedSubnet(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotIP, _ = ClientIP(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/updates/", nil)
+			req.Header.Set("X-Forwarded-For", "192.168.1.20")
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if gotIP != tt.wantIP {
+				t.Errorf("Expected client IP %q, got %q", tt.wantIP, gotIP)
+			}
+		})
+	}
+}
+
+func TestParseTrustedSubnet(t *testing.T) {
+	if subnet, err := ParseTrustedSubnet(""); err != nil || subnet != nil {
+		t.Errorf("Expected nil subnet for empty string, got %v, %v", subnet, err)
+	}
+
+	if _, err := ParseTrustedSubnet("192.168.1.0"); err == nil {
+		t.Error("Expected error for address without mask")
+	}
+}
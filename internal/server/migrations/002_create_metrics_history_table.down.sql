@@ -0,0 +1,2 @@
+-- Удаление таблицы истории метрик
+DROP TABLE IF EXISTS metrics_history;
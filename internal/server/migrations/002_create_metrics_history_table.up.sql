@@ -0,0 +1,21 @@
+-- Создание таблицы для хранения истории обновлений метрик
+CREATE TABLE IF NOT EXISTS metrics_history (
+    id VARCHAR(255) NOT NULL,
+    type VARCHAR(50) NOT NULL CHECK (type IN ('gauge', 'counter')),
+    delta BIGINT,
+    value DOUBLE PRECISION,
+    source VARCHAR(255),
+    ts TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Индекс для выборки истории метрики за интервал времени
+CREATE INDEX IF NOT EXISTS idx_metrics_history_id_type_ts ON metrics_history(id, type, ts);
+
+-- Комментарии к таблице и колонкам
+COMMENT ON TABLE metrics_history IS 'Таблица для хранения истории обновлений метрик';
+COMMENT ON COLUMN metrics_history.id IS 'Идентификатор метрики';
+COMMENT ON COLUMN metrics_history.type IS 'Тип метрики: gauge или counter';
+COMMENT ON COLUMN metrics_history.delta IS 'Принятое приращение counter метрики';
+COMMENT ON COLUMN metrics_history.value IS 'Принятое значение gauge метрики';
+COMMENT ON COLUMN metrics_history.source IS 'Источник обновления';
+COMMENT ON COLUMN metrics_history.ts IS 'Время принятия обновления';
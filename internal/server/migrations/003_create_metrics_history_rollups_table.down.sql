@@ -0,0 +1,3 @@
+-- Удаление таблицы агрегированной истории метрик
+DROP INDEX IF EXISTS idx_metrics_history_ts;
+DROP TABLE IF EXISTS metrics_history_rollups;
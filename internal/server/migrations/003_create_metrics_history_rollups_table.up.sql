@@ -0,0 +1,36 @@
+-- Создание таблицы для хранения агрегированной истории метрик
+CREATE TABLE IF NOT EXISTS metrics_history_rollups (
+    id VARCHAR(255) NOT NULL,
+    type VARCHAR(50) NOT NULL CHECK (type IN ('gauge', 'counter')),
+    resolution BIGINT NOT NULL,
+    ts TIMESTAMP WITH TIME ZONE NOT NULL,
+    value DOUBLE PRECISION,
+    min DOUBLE PRECISION,
+    max DOUBLE PRECISION,
+    last DOUBLE PRECISION,
+    delta BIGINT,
+    rate DOUBLE PRECISION,
+    count BIGINT NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (id, type, resolution, ts)
+);
+
+-- Индекс для очистки агрегатов по сроку хранения
+CREATE INDEX IF NOT EXISTS idx_metrics_history_rollups_resolution_ts ON metrics_history_rollups(resolution, ts);
+
+-- Индекс для очистки сырых точек по сроку хранения
+CREATE INDEX IF NOT EXISTS idx_metrics_history_ts ON metrics_history(ts);
+
+-- Комментарии к таблице и колонкам
+COMMENT ON TABLE metrics_history_rollups IS 'Таблица для хранения агрегированной истории метрик';
+COMMENT ON COLUMN metrics_history_rollups.id IS 'Идентификатор метрики';
+COMMENT ON COLUMN metrics_history_rollups.type IS 'Тип метрики: gauge или counter';
+COMMENT ON COLUMN metrics_history_rollups.resolution IS 'Шаг агрегации в секундах';
+COMMENT ON COLUMN metrics_history_rollups.ts IS 'Начало интервала агрегации';
+COMMENT ON COLUMN metrics_history_rollups.value IS 'Среднее значение gauge метрики';
+COMMENT ON COLUMN metrics_history_rollups.min IS 'Минимальное значение gauge метрики';
+COMMENT ON COLUMN metrics_history_rollups.max IS 'Максимальное значение gauge метрики';
+COMMENT ON COLUMN metrics_history_rollups.last IS 'Последнее значение gauge метрики';
+COMMENT ON COLUMN metrics_history_rollups.delta IS 'Сумма приращений counter метрики';
+COMMENT ON COLUMN metrics_history_rollups.rate IS 'Скорость роста counter метрики в секунду';
+COMMENT ON COLUMN metrics_history_rollups.count IS 'Количество исходных обновлений';
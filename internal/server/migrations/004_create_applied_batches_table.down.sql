@@ -0,0 +1,2 @@
+-- Удаление таблицы идентификаторов примененных батчей
+DROP TABLE IF EXISTS applied_batches;
@@ -0,0 +1,13 @@
+-- Создание таблицы идентификаторов примененных батчей метрик
+CREATE TABLE IF NOT EXISTS applied_batches (
+    key VARCHAR(255) PRIMARY KEY,
+    applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Индекс для удаления устаревших идентификаторов
+CREATE INDEX IF NOT EXISTS idx_applied_batches_applied_at ON applied_batches(applied_at);
+
+-- Комментарии к таблице и колонкам
+COMMENT ON TABLE applied_batches IS 'Таблица идентификаторов недавно примененных батчей для защиты от повторного применения';
+COMMENT ON COLUMN applied_batches.key IS 'Идентификатор батча из заголовка Idempotency-Key';
+COMMENT ON COLUMN applied_batches.applied_at IS 'Время применения батча';
@@ -0,0 +1,11 @@
+-- Удаление меток метрик: ряды с метками не представимы в схеме без меток
+DELETE FROM metrics WHERE labels <> '{}'::jsonb;
+
+DROP INDEX IF EXISTS idx_metrics_labels;
+DROP INDEX IF EXISTS idx_metrics_id;
+
+ALTER TABLE metrics DROP CONSTRAINT IF EXISTS metrics_pkey;
+ALTER TABLE metrics ADD PRIMARY KEY (id, type);
+
+ALTER TABLE metrics DROP COLUMN IF EXISTS key;
+ALTER TABLE metrics DROP COLUMN IF EXISTS labels;
@@ -0,0 +1,20 @@
+-- Добавление меток метрик: ряд метрики определяется именем и набором меток
+ALTER TABLE metrics ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}'::jsonb;
+ALTER TABLE metrics ADD COLUMN IF NOT EXISTS key VARCHAR(1024);
+
+-- Ключ ряда метрики без меток совпадает с ее именем
+UPDATE metrics SET key = id WHERE key IS NULL;
+ALTER TABLE metrics ALTER COLUMN key SET NOT NULL;
+
+ALTER TABLE metrics DROP CONSTRAINT IF EXISTS metrics_pkey;
+ALTER TABLE metrics ADD PRIMARY KEY (key, type);
+
+-- Индекс для выборки рядов метрики по имени
+CREATE INDEX IF NOT EXISTS idx_metrics_id ON metrics(id);
+
+-- Индекс для отбора рядов по меткам
+CREATE INDEX IF NOT EXISTS idx_metrics_labels ON metrics USING GIN (labels);
+
+-- Комментарии к колонкам
+COMMENT ON COLUMN metrics.key IS 'Ключ ряда метрики: имя и каноническое представление меток';
+COMMENT ON COLUMN metrics.labels IS 'Метки метрики';
@@ -0,0 +1,10 @@
+-- Удаление типов метрик histogram и summary
+DELETE FROM metrics WHERE type IN ('histogram', 'summary');
+
+ALTER TABLE metrics DROP COLUMN IF EXISTS summary;
+ALTER TABLE metrics DROP COLUMN IF EXISTS histogram;
+
+ALTER TABLE metrics DROP CONSTRAINT IF EXISTS metrics_type_check;
+ALTER TABLE metrics ADD CONSTRAINT metrics_type_check CHECK (type IN ('gauge', 'counter'));
+
+COMMENT ON COLUMN metrics.type IS 'Тип метрики: gauge или counter';
@@ -0,0 +1,12 @@
+-- Добавление типов метрик histogram и summary
+ALTER TABLE metrics DROP CONSTRAINT IF EXISTS metrics_type_check;
+ALTER TABLE metrics ADD CONSTRAINT metrics_type_check
+    CHECK (type IN ('gauge', 'counter', 'histogram', 'summary'));
+
+ALTER TABLE metrics ADD COLUMN IF NOT EXISTS histogram JSONB;
+ALTER TABLE metrics ADD COLUMN IF NOT EXISTS summary JSONB;
+
+-- Комментарии к колонкам
+COMMENT ON COLUMN metrics.type IS 'Тип метрики: gauge, counter, histogram или summary';
+COMMENT ON COLUMN metrics.histogram IS 'Значение histogram метрики: границы корзин, количество наблюдений в корзинах, сумма и количество';
+COMMENT ON COLUMN metrics.summary IS 'Значение summary метрики: квантили, сумма и количество наблюдений';
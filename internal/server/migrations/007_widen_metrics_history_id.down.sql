@@ -0,0 +1,9 @@
+-- Возврат прежней длины идентификатора: ряды с длинными ключами не помещаются в колонку
+DELETE FROM metrics_history WHERE length(id) > 255;
+DELETE FROM metrics_history_rollups WHERE length(id) > 255;
+
+ALTER TABLE metrics_history ALTER COLUMN id TYPE VARCHAR(255);
+ALTER TABLE metrics_history_rollups ALTER COLUMN id TYPE VARCHAR(255);
+
+COMMENT ON COLUMN metrics_history.id IS 'Идентификатор метрики';
+COMMENT ON COLUMN metrics_history_rollups.id IS 'Идентификатор метрики';
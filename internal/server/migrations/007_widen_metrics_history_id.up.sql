@@ -0,0 +1,7 @@
+-- Расширение идентификатора в истории метрик: в нем хранится ключ ряда с метками,
+-- длина которого ограничена так же, как metrics.key
+ALTER TABLE metrics_history ALTER COLUMN id TYPE VARCHAR(1024);
+ALTER TABLE metrics_history_rollups ALTER COLUMN id TYPE VARCHAR(1024);
+
+COMMENT ON COLUMN metrics_history.id IS 'Ключ ряда метрики: имя и каноническое представление меток';
+COMMENT ON COLUMN metrics_history_rollups.id IS 'Ключ ряда метрики: имя и каноническое представление меток';
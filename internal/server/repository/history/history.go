@@ -0,0 +1,596 @@
+// Package history реализует хранилище истории обновлений метрик
+// с правилами хранения и агрегацией старых точек.
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+)
+
+// DefaultMaxPoints - количество сырых точек на метрику в памяти по умолчанию.
+// Ограничение защищает память и может оказаться короче срока хранения сырых точек:
+// при обновлении раз в 2 секунды 10000 точек покрывают около 5,5 часов из 24.
+// Для полного покрытия MaxPoints должно быть не меньше срока хранения, деленного
+// на интервал обновления метрики. Агрегаты строятся из сырых точек каждый шаг первого
+// уровня и успевают их учесть, а ответ на запрос сырых точек за вытесненный интервал
+// помечается как неполный.
+const DefaultMaxPoints = 10000
+
+// series хранит точки истории одной метрики.
+type series struct {
+	ID          string               `json:"id"`                     // имя метрики
+	MType       string               `json:"type"`                   // тип метрики
+	Points      []models.MetricPoint `json:"points"`                 // точки в порядке возрастания времени
+	TruncatedAt *time.Time           `json:"truncated_at,omitempty"` // время последней точки, вытесненной ограничением MaxPoints
+}
+
+// tier хранит точки одного уровня хранения.
+type tier struct {
+	rule      RetentionRule      // правило хранения уровня
+	series    map[string]*series // ряды по ключу метрики
+	watermark time.Time          // граница, до которой агрегированы точки предыдущего уровня
+}
+
+// MetricsHistory хранит историю обновлений метрик в памяти, в файле или в базе данных.
+type MetricsHistory struct {
+	tiers        []*tier               // уровни хранения от сырых точек к самым крупным агрегатам
+	mu           *sync.RWMutex         // мьютекс для безопасного доступа
+	config       *MetricsHistoryConfig // конфигурация истории
+	retentionTkr *time.Ticker          // таймер для периодической агрегации и очистки
+	done         chan bool             // канал для остановки таймера
+}
+
+// MetricsHistoryConfig содержит конфигурацию истории метрик.
+type MetricsHistoryConfig struct {
+	MaxPoints      int             // максимальное количество сырых точек на метрику в памяти
+	Retention      []RetentionRule // правила хранения, по умолчанию DefaultRetention
+	FilePath       string          // путь к файлу истории (опционально)
+	RestoreHistory bool            // восстанавливать ли историю из файла при старте
+	ConnectionPool *pgxpool.Pool   // пул подключений к базе данных (опционально)
+}
+
+// New создает новое хранилище истории метрик.
+func New(config *MetricsHistoryConfig) *MetricsHistory {
+	if config.MaxPoints <= 0 {
+		config.MaxPoints = DefaultMaxPoints
+	}
+
+	if len(config.Retention) == 0 {
+		config.Retention = DefaultRetention
+	}
+
+	tiers := make([]*tier, 0, len(config.Retention))
+	for _, rule := range config.Retention {
+		tiers = append(tiers, &tier{
+			rule:   rule,
+			series: make(map[string]*series),
+		})
+	}
+
+	mh := &MetricsHistory{
+		tiers:  tiers,
+		mu:     &sync.RWMutex{},
+		config: config,
+		done:   make(chan bool),
+	}
+
+	if config.RestoreHistory && config.FilePath != "" && config.ConnectionPool == nil {
+		if err := mh.LoadFromFile(); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logger.Logger.Warnf("failed to restore metrics history: %v", err)
+		}
+	}
+
+	return mh
+}
+
+// seriesKey возвращает ключ ряда для типа и имени метрики.
+func seriesKey(mType, id string) string {
+	return mType + ":" + id
+}
+
+// newPoint создает точку истории из принятой метрики.
+func newPoint(metric models.Metrics, ts time.Time, source string) models.MetricPoint {
+	point := models.MetricPoint{
+		Timestamp: ts,
+		Source:    source,
+	}
+
+	if metric.Value != nil {
+		value := *metric.Value
+		point.Value = &value
+	}
+
+	if metric.Delta != nil {
+		delta := *metric.Delta
+		point.Delta = &delta
+	}
+
+	return point
+}
+
+// Record сохраняет принятые обновления метрик в историю.
+// История ведется по рядам: ряды с разными метками хранятся раздельно под ключом ряда.
+func (mh *MetricsHistory) Record(source string, metrics []models.Metrics) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	raw := mh.tiers[0]
+
+	mh.mu.Lock()
+	for _, metric := range metrics {
+		key := seriesKey(metric.MType, metric.Key())
+
+		s, ok := raw.series[key]
+		if !ok {
+			s = &series{ID: metric.Key(), MType: metric.MType}
+			raw.series[key] = s
+		}
+
+		s.Points = append(s.Points, newPoint(metric, now, source))
+
+		if len(s.Points) > mh.config.MaxPoints {
+			drop := len(s.Points) - mh.config.MaxPoints
+			truncatedAt := s.Points[drop-1].Timestamp
+			s.TruncatedAt = &truncatedAt
+			s.Points = append(s.Points[:0:0], s.Points[drop:]...)
+		}
+	}
+	mh.mu.Unlock()
+
+	if mh.config.ConnectionPool != nil {
+		if err := mh.saveToDatabase(source, metrics, now); err != nil {
+			return fmt.Errorf("failed to save history to database: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// saveToDatabase сохраняет сырые точки истории в базу данных.
+func (mh *MetricsHistory) saveToDatabase(source string, metrics []models.Metrics, ts time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := mh.config.ConnectionPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, metric := range metrics {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO metrics_history (id, type, delta, value, source, ts)
+			 VALUES ($1, $2, $3, $4, $5, $6)`,
+			metric.Key(), metric.MType, metric.Delta, metric.Value, source, ts)
+
+		if err != nil {
+			return fmt.Errorf("failed to save history of metric %s: %w", metric.Key(), err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Query возвращает историю ряда метрики с ключом id за интервал [from, to].
+//
+// Уровень хранения выбирается автоматически: используется самый подробный уровень,
+// срок хранения которого покрывает from. Если step больше шага уровня,
+// точки дополнительно агрегируются по интервалам длиной step. Если часть сырых точек
+// интервала вытеснена ограничением MaxPoints, результат помечается как неполный.
+func (mh *MetricsHistory) Query(mType, id string, from, to time.Time, step time.Duration) (*models.MetricHistory, error) {
+	t := mh.selectTier(from, time.Now().UTC())
+
+	var points []models.MetricPoint
+	var truncated bool
+
+	if mh.config.ConnectionPool != nil {
+		var err error
+		points, err = mh.queryDatabase(t.rule.Resolution, mType, id, from, to)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		points, truncated = mh.queryMemory(t, mType, id, from, to)
+	}
+
+	resolution := t.rule.Resolution
+	if step > resolution {
+		points = Downsample(mType, points, from, step)
+		resolution = step
+	}
+
+	return &models.MetricHistory{
+		ID:         id,
+		MType:      mType,
+		Resolution: int64(resolution / time.Second),
+		Points:     points,
+		Truncated:  truncated,
+	}, nil
+}
+
+// selectTier выбирает самый подробный уровень хранения, покрывающий from.
+func (mh *MetricsHistory) selectTier(from, now time.Time) *tier {
+	for _, t := range mh.tiers {
+		if !from.Before(now.Add(-t.rule.Retention)) {
+			return t
+		}
+	}
+
+	return mh.tiers[len(mh.tiers)-1]
+}
+
+// queryMemory возвращает точки уровня хранения из памяти и признак того,
+// что часть точек интервала вытеснена ограничением MaxPoints.
+func (mh *MetricsHistory) queryMemory(t *tier, mType, id string, from, to time.Time) ([]models.MetricPoint, bool) {
+	mh.mu.RLock()
+	defer mh.mu.RUnlock()
+
+	points := make([]models.MetricPoint, 0)
+
+	s, ok := t.series[seriesKey(mType, id)]
+	if !ok {
+		return points, false
+	}
+
+	truncated := s.TruncatedAt != nil && !from.After(*s.TruncatedAt)
+
+	start := sort.Search(len(s.Points), func(i int) bool {
+		return !s.Points[i].Timestamp.Before(from)
+	})
+	end := sort.Search(len(s.Points), func(i int) bool {
+		return s.Points[i].Timestamp.After(to)
+	})
+
+	if start < end {
+		points = append(points, s.Points[start:end]...)
+	}
+
+	return points, truncated
+}
+
+// queryDatabase возвращает точки уровня хранения из базы данных.
+func (mh *MetricsHistory) queryDatabase(resolution time.Duration, mType, id string, from, to time.Time) ([]models.MetricPoint, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	points := make([]models.MetricPoint, 0)
+
+	if resolution == 0 {
+		rows, err := mh.config.ConnectionPool.Query(ctx,
+			`SELECT ts, delta, value, source FROM metrics_history
+			 WHERE id = $1 AND type = $2 AND ts BETWEEN $3 AND $4
+			 ORDER BY ts`,
+			id, mType, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query history: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var point models.MetricPoint
+			var source *string
+
+			if err := rows.Scan(&point.Timestamp, &point.Delta, &point.Value, &source); err != nil {
+				return nil, fmt.Errorf("failed to scan history point: %w", err)
+			}
+
+			if source != nil {
+				point.Source = *source
+			}
+			points = append(points, point)
+		}
+
+		return points, rows.Err()
+	}
+
+	rows, err := mh.config.ConnectionPool.Query(ctx,
+		`SELECT ts, delta, value, min, max, last, rate, count FROM metrics_history_rollups
+		 WHERE id = $1 AND type = $2 AND resolution = $3 AND ts BETWEEN $4 AND $5
+		 ORDER BY ts`,
+		id, mType, int64(resolution/time.Second), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history rollups: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var point models.MetricPoint
+
+		err := rows.Scan(&point.Timestamp, &point.Delta, &point.Value,
+			&point.Min, &point.Max, &point.Last, &point.Rate, &point.Count)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan history rollup: %w", err)
+		}
+
+		points = append(points, point)
+	}
+
+	return points, rows.Err()
+}
+
+// StartRetention запускает периодическую агрегацию и очистку истории.
+// Интервал равен шагу самого подробного уровня агрегатов.
+func (mh *MetricsHistory) StartRetention() {
+	interval := time.Minute
+	if len(mh.tiers) > 1 {
+		interval = mh.tiers[1].rule.Resolution
+	}
+
+	mh.retentionTkr = time.NewTicker(interval)
+
+	go func() {
+		for {
+			select {
+			case <-mh.retentionTkr.C:
+				mh.ApplyRetention(time.Now().UTC())
+			case <-mh.done:
+				return
+			}
+		}
+	}()
+}
+
+// StopRetention останавливает периодическую агрегацию и сохраняет историю в файл.
+func (mh *MetricsHistory) StopRetention() {
+	if mh.retentionTkr != nil {
+		mh.retentionTkr.Stop()
+		close(mh.done)
+	}
+
+	if mh.config.FilePath != "" && mh.config.ConnectionPool == nil {
+		if err := mh.SaveToFile(); err != nil {
+			logger.Logger.Errorf("failed to save metrics history on shutdown: %v", err)
+		}
+	}
+}
+
+// ApplyRetention агрегирует завершенные интервалы и удаляет устаревшие точки
+// во всех используемых хранилищах истории.
+func (mh *MetricsHistory) ApplyRetention(now time.Time) {
+	mh.Compact(now)
+
+	if mh.config.ConnectionPool != nil {
+		if err := mh.compactDatabase(now); err != nil {
+			logger.Logger.Errorf("failed to compact metrics history in database: %v", err)
+		}
+	} else if mh.config.FilePath != "" {
+		if err := mh.SaveToFile(); err != nil {
+			logger.Logger.Errorf("failed to save metrics history to file: %v", err)
+		}
+	}
+}
+
+// Compact агрегирует завершенные к моменту now интервалы каждого уровня
+// из точек предыдущего уровня и удаляет точки старше срока хранения.
+func (mh *MetricsHistory) Compact(now time.Time) {
+	mh.mu.Lock()
+	defer mh.mu.Unlock()
+
+	for i := 1; i < len(mh.tiers); i++ {
+		src, dst := mh.tiers[i-1], mh.tiers[i]
+		boundary := now.Truncate(dst.rule.Resolution)
+
+		if !boundary.After(dst.watermark) {
+			continue
+		}
+
+		for key, s := range src.series {
+			start := sort.Search(len(s.Points), func(i int) bool {
+				return !s.Points[i].Timestamp.Before(dst.watermark)
+			})
+			end := sort.Search(len(s.Points), func(i int) bool {
+				return !s.Points[i].Timestamp.Before(boundary)
+			})
+
+			if start >= end {
+				continue
+			}
+
+			rolled := Rollup(s.MType, s.Points[start:end], dst.rule.Resolution)
+			if len(rolled) == 0 {
+				continue
+			}
+
+			target, ok := dst.series[key]
+			if !ok {
+				target = &series{ID: s.ID, MType: s.MType}
+				dst.series[key] = target
+			}
+			target.Points = append(target.Points, rolled...)
+		}
+
+		dst.watermark = boundary
+	}
+
+	for _, t := range mh.tiers {
+		cutoff := now.Add(-t.rule.Retention)
+
+		for key, s := range t.series {
+			idx := sort.Search(len(s.Points), func(i int) bool {
+				return !s.Points[i].Timestamp.Before(cutoff)
+			})
+
+			if idx == len(s.Points) {
+				delete(t.series, key)
+				continue
+			}
+
+			if idx > 0 {
+				s.Points = append(s.Points[:0:0], s.Points[idx:]...)
+			}
+
+			// Вытесненные точки старше срока хранения уже не влияют на полноту ответа.
+			if s.TruncatedAt != nil && s.TruncatedAt.Before(cutoff) {
+				s.TruncatedAt = nil
+			}
+		}
+	}
+}
+
+// compactDatabase агрегирует и очищает историю в базе данных.
+func (mh *MetricsHistory) compactDatabase(now time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	for i := 1; i < len(mh.tiers); i++ {
+		src, dst := mh.tiers[i-1], mh.tiers[i]
+		resolution := int64(dst.rule.Resolution / time.Second)
+		boundary := now.Truncate(dst.rule.Resolution)
+
+		var err error
+		if src.rule.Resolution == 0 {
+			_, err = mh.config.ConnectionPool.Exec(ctx,
+				`INSERT INTO metrics_history_rollups
+				   (id, type, resolution, ts, value, min, max, last, delta, rate, count)
+				 SELECT id, type, $1::bigint,
+				   to_timestamp(floor(extract(epoch FROM ts) / $1::bigint) * $1::bigint) AS bucket,
+				   avg(value), min(value), max(value),
+				   (array_agg(value ORDER BY ts DESC))[1],
+				   sum(delta), sum(delta)::double precision / $1::bigint, count(*)
+				 FROM metrics_history
+				 WHERE ts >= COALESCE(
+				     (SELECT max(ts) FROM metrics_history_rollups WHERE resolution = $1::bigint),
+				     '-infinity'::timestamptz)
+				   AND ts < $2
+				 GROUP BY id, type, bucket
+				 ON CONFLICT (id, type, resolution, ts) DO UPDATE SET
+				   value = EXCLUDED.value, min = EXCLUDED.min, max = EXCLUDED.max,
+				   last = EXCLUDED.last, delta = EXCLUDED.delta, rate = EXCLUDED.rate,
+				   count = EXCLUDED.count`,
+				resolution, boundary)
+		} else {
+			_, err = mh.config.ConnectionPool.Exec(ctx,
+				`INSERT INTO metrics_history_rollups
+				   (id, type, resolution, ts, value, min, max, last, delta, rate, count)
+				 SELECT id, type, $1::bigint,
+				   to_timestamp(floor(extract(epoch FROM ts) / $1::bigint) * $1::bigint) AS bucket,
+				   sum(value * count) / sum(count), min(min), max(max),
+				   (array_agg(last ORDER BY ts DESC))[1],
+				   sum(delta), sum(delta)::double precision / $1::bigint, sum(count)
+				 FROM metrics_history_rollups
+				 WHERE resolution = $2
+				   AND ts >= COALESCE(
+				     (SELECT max(ts) FROM metrics_history_rollups WHERE resolution = $1::bigint),
+				     '-infinity'::timestamptz)
+				   AND ts < $3
+				 GROUP BY id, type, bucket
+				 ON CONFLICT (id, type, resolution, ts) DO UPDATE SET
+				   value = EXCLUDED.value, min = EXCLUDED.min, max = EXCLUDED.max,
+				   last = EXCLUDED.last, delta = EXCLUDED.delta, rate = EXCLUDED.rate,
+				   count = EXCLUDED.count`,
+				resolution, int64(src.rule.Resolution/time.Second), boundary)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to roll up history to %s: %w", dst.rule.Resolution, err)
+		}
+	}
+
+	for _, t := range mh.tiers {
+		cutoff := now.Add(-t.rule.Retention)
+
+		var err error
+		if t.rule.Resolution == 0 {
+			_, err = mh.config.ConnectionPool.Exec(ctx,
+				`DELETE FROM metrics_history WHERE ts < $1`, cutoff)
+		} else {
+			_, err = mh.config.ConnectionPool.Exec(ctx,
+				`DELETE FROM metrics_history_rollups WHERE resolution = $1 AND ts < $2`,
+				int64(t.rule.Resolution/time.Second), cutoff)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to apply retention %s: %w", t.rule.Retention, err)
+		}
+	}
+
+	return nil
+}
+
+// tierDump описывает уровень хранения при сохранении в файл.
+type tierDump struct {
+	Resolution time.Duration `json:"resolution"` // шаг уровня
+	Watermark  time.Time     `json:"watermark"`  // граница агрегации уровня
+	Series     []*series     `json:"series"`     // ряды уровня
+}
+
+// SaveToFile сохраняет историю в файл.
+func (mh *MetricsHistory) SaveToFile() error {
+	mh.mu.RLock()
+	dump := make([]tierDump, 0, len(mh.tiers))
+	for _, t := range mh.tiers {
+		td := tierDump{
+			Resolution: t.rule.Resolution,
+			Watermark:  t.watermark,
+			Series:     make([]*series, 0, len(t.series)),
+		}
+		for _, s := range t.series {
+			td.Series = append(td.Series, s)
+		}
+		dump = append(dump, td)
+	}
+
+	data, err := json.Marshal(dump)
+	mh.mu.RUnlock()
+
+	if err != nil {
+		return fmt.Errorf("failed to marshal history: %w", err)
+	}
+
+	dir := filepath.Dir(mh.config.FilePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	if err := os.WriteFile(mh.config.FilePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return nil
+}
+
+// LoadFromFile загружает историю из файла.
+// Уровни, отсутствующие в текущих правилах хранения, пропускаются.
+func (mh *MetricsHistory) LoadFromFile() error {
+	data, err := os.ReadFile(mh.config.FilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var dump []tierDump
+	if err := json.Unmarshal(data, &dump); err != nil {
+		return fmt.Errorf("failed to unmarshal history: %w", err)
+	}
+
+	mh.mu.Lock()
+	defer mh.mu.Unlock()
+
+	for _, td := range dump {
+		for _, t := range mh.tiers {
+			if t.rule.Resolution != td.Resolution {
+				continue
+			}
+
+			t.watermark = td.Watermark
+			for _, s := range td.Series {
+				t.series[seriesKey(s.MType, s.ID)] = s
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,233 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+)
+
+func TestRecordAndQuery(t *testing.T) {
+	hist := New(&MetricsHistoryConfig{})
+
+	value := 10.5
+	delta := int64(3)
+
+	err := hist.Record("127.0.0.1", []models.Metrics{
+		{ID: "HeapAlloc", MType: models.Gauge, Value: &value},
+		{ID: "PollCount", MType: models.Counter, Delta: &delta},
+	})
+	if err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	now := time.Now().UTC()
+
+	result, err := hist.Query(models.Gauge, "HeapAlloc", now.Add(-time.Minute), now.Add(time.Minute), 0)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	if result.Resolution != 0 {
+		t.Errorf("Expected raw resolution, got %d", result.Resolution)
+	}
+
+	if len(result.Points) != 1 {
+		t.Fatalf("Expected 1 point, got %d", len(result.Points))
+	}
+
+	if result.Points[0].Value == nil || *result.Points[0].Value != value {
+		t.Errorf("Expected value %f, got %v", value, result.Points[0].Value)
+	}
+
+	if result.Points[0].Source != "127.0.0.1" {
+		t.Errorf("Expected source 127.0.0.1, got %s", result.Points[0].Source)
+	}
+
+	result, err = hist.Query(models.Gauge, "HeapAlloc", now.Add(time.Minute), now.Add(2*time.Minute), 0)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	if len(result.Points) != 0 {
+		t.Errorf("Expected no points outside of range, got %d", len(result.Points))
+	}
+}
+
+func TestRecord_MaxPoints(t *testing.T) {
+	hist := New(&MetricsHistoryConfig{MaxPoints: 3})
+
+	for i := 0; i < 5; i++ {
+		value := float64(i)
+		hist.Record("", []models.Metrics{{ID: "Alloc", MType: models.Gauge, Value: &value}})
+	}
+
+	now := time.Now().UTC()
+	result, _ := hist.Query(models.Gauge, "Alloc", now.Add(-time.Minute), now.Add(time.Minute), 0)
+
+	if len(result.Points) != 3 {
+		t.Fatalf("Expected 3 points, got %d", len(result.Points))
+	}
+
+	if *result.Points[0].Value != 2 {
+		t.Errorf("Expected oldest points to be dropped, first value is %f", *result.Points[0].Value)
+	}
+
+	if !result.Truncated {
+		t.Error("Expected result to be marked as truncated")
+	}
+
+	// Интервал после вытесненных точек полон.
+	result, _ = hist.Query(models.Gauge, "Alloc", now.Add(time.Minute), now.Add(2*time.Minute), 0)
+	if result.Truncated {
+		t.Error("Expected interval after dropped points not to be marked as truncated")
+	}
+}
+
+func TestDownsample(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	gauge := func(offset time.Duration, value float64) models.MetricPoint {
+		return models.MetricPoint{Timestamp: from.Add(offset), Value: &value}
+	}
+	counter := func(offset time.Duration, delta int64) models.MetricPoint {
+		return models.MetricPoint{Timestamp: from.Add(offset), Delta: &delta}
+	}
+
+	gauges := Downsample(models.Gauge, []models.MetricPoint{
+		gauge(10*time.Second, 1),
+		gauge(20*time.Second, 3),
+		gauge(70*time.Second, 10),
+	}, from, time.Minute)
+
+	if len(gauges) != 2 {
+		t.Fatalf("Expected 2 gauge buckets, got %d", len(gauges))
+	}
+
+	first := gauges[0]
+	if *first.Value != 2 || *first.Min != 1 || *first.Max != 3 || *first.Last != 3 || first.Count != 2 {
+		t.Errorf("Unexpected first gauge bucket: %+v", first)
+	}
+
+	if *gauges[1].Value != 10 || !gauges[1].Timestamp.Equal(from.Add(time.Minute)) {
+		t.Errorf("Unexpected second gauge bucket: %v at %v", *gauges[1].Value, gauges[1].Timestamp)
+	}
+
+	counters := Downsample(models.Counter, []models.MetricPoint{
+		counter(5*time.Second, 1),
+		counter(15*time.Second, 2),
+		counter(65*time.Second, 4),
+	}, from, time.Minute)
+
+	if len(counters) != 2 {
+		t.Fatalf("Expected 2 counter buckets, got %d", len(counters))
+	}
+
+	if *counters[0].Delta != 3 || *counters[1].Delta != 4 {
+		t.Errorf("Unexpected counter sums: %d, %d", *counters[0].Delta, *counters[1].Delta)
+	}
+
+	if *counters[0].Rate != 3.0/60 {
+		t.Errorf("Unexpected counter rate: %f", *counters[0].Rate)
+	}
+}
+
+func TestParseRetention(t *testing.T) {
+	rules, err := ParseRetention("raw:24h,1m:30d,1h:365d")
+	if err != nil {
+		t.Fatalf("ParseRetention failed: %v", err)
+	}
+
+	if len(rules) != 3 {
+		t.Fatalf("Expected 3 rules, got %d", len(rules))
+	}
+
+	if rules[1].Resolution != time.Minute || rules[1].Retention != 30*24*time.Hour {
+		t.Errorf("Unexpected second rule: %+v", rules[1])
+	}
+
+	invalid := []string{
+		"",
+		"1m:30d",
+		"raw:24h,1h:30d,1m:1d",
+		"raw:24h,1m:1d,90s:30d",
+		"raw:30s,1m:30d",
+		"raw:24h,1m",
+	}
+
+	for _, spec := range invalid {
+		if _, err := ParseRetention(spec); err == nil {
+			t.Errorf("Expected error for spec %q", spec)
+		}
+	}
+}
+
+func TestCompact(t *testing.T) {
+	rules, _ := ParseRetention("raw:2h,1m:24h,1h:365d")
+	hist := New(&MetricsHistoryConfig{Retention: rules})
+
+	base := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	raw := hist.tiers[0]
+
+	points := make([]models.MetricPoint, 0)
+	for i := 0; i < 6; i++ {
+		value := float64(i)
+		points = append(points, models.MetricPoint{Timestamp: base.Add(time.Duration(i) * 30 * time.Second), Value: &value})
+	}
+	raw.series[seriesKey(models.Gauge, "Alloc")] = &series{ID: "Alloc", MType: models.Gauge, Points: points}
+
+	hist.Compact(base.Add(2 * time.Minute))
+
+	minutes := hist.tiers[1].series[seriesKey(models.Gauge, "Alloc")]
+	if minutes == nil || len(minutes.Points) != 2 {
+		t.Fatalf("Expected 2 minute rollups, got %+v", minutes)
+	}
+
+	if *minutes.Points[0].Value != 0.5 || *minutes.Points[1].Value != 2.5 {
+		t.Errorf("Unexpected minute averages: %f, %f", *minutes.Points[0].Value, *minutes.Points[1].Value)
+	}
+
+	hist.Compact(base.Add(3 * time.Minute))
+
+	if len(minutes.Points) != 3 {
+		t.Fatalf("Expected 3 minute rollups after second compaction, got %d", len(minutes.Points))
+	}
+
+	hist.Compact(base.Add(3 * time.Hour))
+
+	if _, ok := raw.series[seriesKey(models.Gauge, "Alloc")]; ok {
+		t.Error("Expected raw points to expire after retention")
+	}
+
+	hours := hist.tiers[2].series[seriesKey(models.Gauge, "Alloc")]
+	if hours == nil || len(hours.Points) != 1 {
+		t.Fatalf("Expected 1 hour rollup, got %+v", hours)
+	}
+
+	if *hours.Points[0].Value != 2.5 || hours.Points[0].Count != 6 || *hours.Points[0].Last != 5 {
+		t.Errorf("Unexpected hour rollup: %+v", hours.Points[0])
+	}
+}
+
+func TestSaveAndLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.history.json")
+
+	hist := New(&MetricsHistoryConfig{FilePath: path})
+
+	value := 1.5
+	hist.Record("", []models.Metrics{{ID: "Alloc", MType: models.Gauge, Value: &value}})
+
+	if err := hist.SaveToFile(); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+
+	restored := New(&MetricsHistoryConfig{FilePath: path, RestoreHistory: true})
+
+	now := time.Now().UTC()
+	result, _ := restored.Query(models.Gauge, "Alloc", now.Add(-time.Minute), now.Add(time.Minute), 0)
+
+	if len(result.Points) != 1 || *result.Points[0].Value != value {
+		t.Errorf("Expected restored history point, got %+v", result.Points)
+	}
+}
@@ -0,0 +1,120 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetentionRule описывает уровень хранения истории: шаг точек и срок их хранения.
+type RetentionRule struct {
+	Resolution time.Duration // шаг агрегации, 0 - сырые точки
+	Retention  time.Duration // срок хранения точек уровня
+}
+
+// DefaultRetention - правила хранения истории по умолчанию:
+// сырые точки за сутки, минутные агрегаты за 30 дней и часовые за год.
+var DefaultRetention = []RetentionRule{
+	{Resolution: 0, Retention: 24 * time.Hour},
+	{Resolution: time.Minute, Retention: 30 * 24 * time.Hour},
+	{Resolution: time.Hour, Retention: 365 * 24 * time.Hour},
+}
+
+// ParseRetention разбирает правила хранения в формате "raw:24h,1m:30d,1h:365d".
+//
+// Первым должно идти правило для сырых точек, шаги последующих правил должны
+// возрастать и быть кратны предыдущему шагу.
+func ParseRetention(spec string) ([]RetentionRule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, errors.New("empty retention spec")
+	}
+
+	var rules []RetentionRule
+
+	for _, part := range strings.Split(spec, ",") {
+		resolutionRaw, retentionRaw, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid retention rule %q: expected <resolution>:<retention>", part)
+		}
+
+		var rule RetentionRule
+
+		if resolutionRaw != "raw" {
+			resolution, err := parseRetentionDuration(resolutionRaw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid resolution in rule %q: %w", part, err)
+			}
+			rule.Resolution = resolution
+		}
+
+		retention, err := parseRetentionDuration(retentionRaw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retention in rule %q: %w", part, err)
+		}
+		rule.Retention = retention
+
+		rules = append(rules, rule)
+	}
+
+	if err := validateRetention(rules); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// parseRetentionDuration разбирает длительность в формате time.ParseDuration
+// с дополнительной поддержкой суффикса "d" (дни).
+func parseRetentionDuration(raw string) (time.Duration, error) {
+	raw = strings.TrimSpace(raw)
+
+	if days, ok := strings.CutSuffix(raw, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number of days %q", raw)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(raw)
+}
+
+// validateRetention проверяет согласованность правил хранения.
+func validateRetention(rules []RetentionRule) error {
+	if len(rules) == 0 {
+		return errors.New("no retention rules")
+	}
+
+	if rules[0].Resolution != 0 {
+		return errors.New("first retention rule must be for raw points")
+	}
+
+	for i, rule := range rules {
+		if rule.Retention <= 0 {
+			return fmt.Errorf("retention of rule %d must be positive", i+1)
+		}
+
+		if i == 0 {
+			continue
+		}
+
+		prev := rules[i-1]
+
+		if rule.Resolution <= prev.Resolution {
+			return fmt.Errorf("resolution of rule %d must be greater than %s", i+1, prev.Resolution)
+		}
+
+		if prev.Resolution > 0 && rule.Resolution%prev.Resolution != 0 {
+			return fmt.Errorf("resolution of rule %d must be a multiple of %s", i+1, prev.Resolution)
+		}
+
+		if prev.Retention < rule.Resolution {
+			return fmt.Errorf("retention of rule %d must be at least %s", i, rule.Resolution)
+		}
+	}
+
+	return nil
+}
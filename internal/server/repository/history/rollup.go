@@ -0,0 +1,140 @@
+package history
+
+import (
+	"time"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+)
+
+// accumulator накапливает точки одного интервала агрегации.
+type accumulator struct {
+	start      time.Time
+	count      int64
+	valueSum   float64
+	valueCount int64
+	min        *float64
+	max        *float64
+	last       *float64
+	deltaSum   int64
+	hasDelta   bool
+}
+
+// add добавляет сырую или агрегированную точку в интервал.
+func (a *accumulator) add(point models.MetricPoint) {
+	count := point.Count
+	if count <= 0 {
+		count = 1
+	}
+	a.count += count
+
+	if point.Value != nil {
+		a.valueSum += *point.Value * float64(count)
+		a.valueCount += count
+
+		low, high, last := *point.Value, *point.Value, *point.Value
+		if point.Min != nil {
+			low = *point.Min
+		}
+		if point.Max != nil {
+			high = *point.Max
+		}
+		if point.Last != nil {
+			last = *point.Last
+		}
+
+		if a.min == nil || low < *a.min {
+			a.min = &low
+		}
+		if a.max == nil || high > *a.max {
+			a.max = &high
+		}
+		a.last = &last
+	}
+
+	if point.Delta != nil {
+		a.deltaSum += *point.Delta
+		a.hasDelta = true
+	}
+}
+
+// point возвращает агрегированную точку интервала длиной step.
+func (a *accumulator) point(mType string, step time.Duration) (models.MetricPoint, bool) {
+	point := models.MetricPoint{
+		Timestamp: a.start,
+		Count:     a.count,
+	}
+
+	switch mType {
+	case models.Gauge:
+		if a.valueCount == 0 {
+			return point, false
+		}
+		avg := a.valueSum / float64(a.valueCount)
+		point.Value = &avg
+		point.Min = a.min
+		point.Max = a.max
+		point.Last = a.last
+
+	case models.Counter:
+		if !a.hasDelta {
+			return point, false
+		}
+		sum := a.deltaSum
+		rate := float64(sum) / step.Seconds()
+		point.Delta = &sum
+		point.Rate = &rate
+
+	default:
+		return point, false
+	}
+
+	return point, true
+}
+
+// aggregate группирует упорядоченные по времени точки по интервалам,
+// начало которых вычисляет bucketStart.
+func aggregate(mType string, points []models.MetricPoint, step time.Duration, bucketStart func(time.Time) time.Time) []models.MetricPoint {
+	result := make([]models.MetricPoint, 0)
+
+	var acc *accumulator
+
+	flush := func() {
+		if acc == nil {
+			return
+		}
+		if point, ok := acc.point(mType, step); ok {
+			result = append(result, point)
+		}
+	}
+
+	for _, point := range points {
+		start := bucketStart(point.Timestamp)
+
+		if acc == nil || !start.Equal(acc.start) {
+			flush()
+			acc = &accumulator{start: start}
+		}
+
+		acc.add(point)
+	}
+
+	flush()
+
+	return result
+}
+
+// Downsample агрегирует упорядоченные по времени точки по интервалам длиной step,
+// начиная с from. Для gauge вычисляются min/max/avg/last, для counter - сумма и скорость.
+func Downsample(mType string, points []models.MetricPoint, from time.Time, step time.Duration) []models.MetricPoint {
+	return aggregate(mType, points, step, func(ts time.Time) time.Time {
+		return from.Add(ts.Sub(from) / step * step)
+	})
+}
+
+// Rollup агрегирует упорядоченные по времени точки по интервалам длиной step,
+// выровненным по началу отсчета времени.
+func Rollup(mType string, points []models.MetricPoint, step time.Duration) []models.MetricPoint {
+	return aggregate(mType, points, step, func(ts time.Time) time.Time {
+		return ts.Truncate(step)
+	})
+}
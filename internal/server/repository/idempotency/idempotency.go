@@ -0,0 +1,247 @@
+// Package idempotency хранит идентификаторы недавно примененных батчей метрик,
+// чтобы повторно присланный батч не применялся дважды.
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+)
+
+const (
+	// MaxKeyLength - максимальная длина идентификатора батча (размер колонки applied_batches.key).
+	MaxKeyLength = 255
+
+	defaultTTL     = 24 * time.Hour // Время хранения идентификатора по умолчанию
+	defaultMaxKeys = 100000         // Количество идентификаторов в памяти по умолчанию
+	maxCleanupTick = time.Hour      // Максимальный интервал очистки устаревших идентификаторов
+)
+
+// insertAppliedBatch сохраняет идентификатор батча и время его применения.
+const insertAppliedBatch = `INSERT INTO applied_batches (key, applied_at) VALUES ($1, $2)
+	 ON CONFLICT (key) DO UPDATE SET applied_at = EXCLUDED.applied_at`
+
+// ValidateKey проверяет идентификатор батча, присланный клиентом: длина не больше
+// MaxKeyLength, допустимы только печатные символы ASCII.
+func ValidateKey(key string) error {
+	if len(key) > MaxKeyLength {
+		return fmt.Errorf("batch key is longer than %d characters", MaxKeyLength)
+	}
+
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return errors.New("batch key must contain only printable ASCII characters")
+		}
+	}
+
+	return nil
+}
+
+// appliedKey - идентификатор батча и время его применения.
+type appliedKey struct {
+	key       string
+	appliedAt time.Time
+}
+
+// AppliedBatches хранит идентификаторы примененных батчей в памяти и в базе данных.
+type AppliedBatches struct {
+	keys       map[string]time.Time  // время применения по идентификатору
+	order      []appliedKey          // идентификаторы в порядке применения
+	mu         *sync.Mutex           // мьютекс для безопасного доступа
+	config     *AppliedBatchesConfig // конфигурация хранилища
+	cleanupTkr *time.Ticker          // таймер для периодической очистки
+	done       chan bool             // канал для остановки таймера
+}
+
+// AppliedBatchesConfig содержит конфигурацию хранилища идентификаторов батчей.
+type AppliedBatchesConfig struct {
+	TTL            time.Duration // время, в течение которого повтор батча распознается
+	MaxKeys        int           // максимальное количество идентификаторов в памяти
+	ConnectionPool *pgxpool.Pool // пул подключений к базе данных (опционально)
+}
+
+// New создает новое хранилище идентификаторов примененных батчей.
+func New(config *AppliedBatchesConfig) *AppliedBatches {
+	if config.TTL <= 0 {
+		config.TTL = defaultTTL
+	}
+
+	if config.MaxKeys <= 0 {
+		config.MaxKeys = defaultMaxKeys
+	}
+
+	return &AppliedBatches{
+		keys:   make(map[string]time.Time),
+		mu:     &sync.Mutex{},
+		config: config,
+		done:   make(chan bool),
+	}
+}
+
+// Seen проверяет, применялся ли батч с указанным идентификатором.
+func (ab *AppliedBatches) Seen(key string) (bool, error) {
+	now := time.Now().UTC()
+
+	ab.mu.Lock()
+	appliedAt, ok := ab.keys[key]
+	ab.mu.Unlock()
+
+	if ok && now.Sub(appliedAt) < ab.config.TTL {
+		return true, nil
+	}
+
+	if ab.config.ConnectionPool == nil {
+		return false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := ab.config.ConnectionPool.QueryRow(ctx,
+		`SELECT applied_at FROM applied_batches WHERE key = $1 AND applied_at > $2`,
+		key, now.Add(-ab.config.TTL)).Scan(&appliedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check applied batch: %w", err)
+	}
+
+	ab.remember(key, appliedAt)
+
+	return true, nil
+}
+
+// Remember сохраняет идентификатор примененного батча.
+func (ab *AppliedBatches) Remember(key string) error {
+	now := time.Now().UTC()
+
+	ab.remember(key, now)
+
+	if ab.config.ConnectionPool == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := ab.config.ConnectionPool.Exec(ctx, insertAppliedBatch, key, now)
+	if err != nil {
+		return fmt.Errorf("failed to save applied batch: %w", err)
+	}
+
+	return nil
+}
+
+// RememberTx сохраняет идентификатор примененного батча в транзакции tx, в которой
+// применяются метрики батча. В памяти идентификатор запоминается вызовом commit
+// после фиксации транзакции, чтобы после отката повтор батча не был отброшен.
+func (ab *AppliedBatches) RememberTx(ctx context.Context, tx pgx.Tx, key string) (func(), error) {
+	now := time.Now().UTC()
+
+	if _, err := tx.Exec(ctx, insertAppliedBatch, key, now); err != nil {
+		return nil, fmt.Errorf("failed to save applied batch: %w", err)
+	}
+
+	return func() { ab.remember(key, now) }, nil
+}
+
+// remember сохраняет идентификатор в памяти, вытесняя самые старые при переполнении.
+func (ab *AppliedBatches) remember(key string, appliedAt time.Time) {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+
+	ab.keys[key] = appliedAt
+	ab.order = append(ab.order, appliedKey{key: key, appliedAt: appliedAt})
+
+	ab.evict(time.Now().UTC())
+}
+
+// evict удаляет из памяти устаревшие идентификаторы и идентификаторы сверх лимита.
+func (ab *AppliedBatches) evict(now time.Time) {
+	drop := 0
+
+	for drop < len(ab.order) {
+		oldest := ab.order[drop]
+		if len(ab.order)-drop <= ab.config.MaxKeys && now.Sub(oldest.appliedAt) < ab.config.TTL {
+			break
+		}
+
+		// Идентификатор мог быть сохранен повторно с более поздним временем.
+		if appliedAt, ok := ab.keys[oldest.key]; ok && appliedAt.Equal(oldest.appliedAt) {
+			delete(ab.keys, oldest.key)
+		}
+		drop++
+	}
+
+	if drop > 0 {
+		ab.order = append(ab.order[:0:0], ab.order[drop:]...)
+	}
+}
+
+// Len возвращает количество идентификаторов в памяти.
+func (ab *AppliedBatches) Len() int {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+	return len(ab.keys)
+}
+
+// Cleanup удаляет устаревшие идентификаторы из памяти и базы данных.
+func (ab *AppliedBatches) Cleanup(now time.Time) error {
+	ab.mu.Lock()
+	ab.evict(now)
+	ab.mu.Unlock()
+
+	if ab.config.ConnectionPool == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := ab.config.ConnectionPool.Exec(ctx,
+		`DELETE FROM applied_batches WHERE applied_at <= $1`, now.Add(-ab.config.TTL))
+	if err != nil {
+		return fmt.Errorf("failed to clean up applied batches: %w", err)
+	}
+
+	return nil
+}
+
+// StartCleanup запускает периодическую очистку устаревших идентификаторов.
+func (ab *AppliedBatches) StartCleanup() {
+	interval := ab.config.TTL
+	if interval > maxCleanupTick {
+		interval = maxCleanupTick
+	}
+
+	ab.cleanupTkr = time.NewTicker(interval)
+
+	go func() {
+		for {
+			select {
+			case <-ab.cleanupTkr.C:
+				if err := ab.Cleanup(time.Now().UTC()); err != nil {
+					logger.Logger.Errorf("failed to clean up applied batches: %v", err)
+				}
+			case <-ab.done:
+				return
+			}
+		}
+	}()
+}
+
+// StopCleanup останавливает периодическую очистку.
+func (ab *AppliedBatches) StopCleanup() {
+	if ab.cleanupTkr != nil {
+		ab.cleanupTkr.Stop()
+		close(ab.done)
+	}
+}
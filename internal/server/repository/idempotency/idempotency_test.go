@@ -0,0 +1,66 @@
+package idempotency
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppliedBatches_SeenAndRemember(t *testing.T) {
+	ab := New(&AppliedBatchesConfig{TTL: time.Minute})
+
+	if seen, _ := ab.Seen("batch-1"); seen {
+		t.Fatal("Expected unknown batch not to be seen")
+	}
+
+	if err := ab.Remember("batch-1"); err != nil {
+		t.Fatalf("Remember failed: %v", err)
+	}
+
+	if seen, _ := ab.Seen("batch-1"); !seen {
+		t.Error("Expected remembered batch to be seen")
+	}
+
+	ab.Cleanup(time.Now().UTC().Add(2 * time.Minute))
+
+	if seen, _ := ab.Seen("batch-1"); seen || ab.Len() != 0 {
+		t.Error("Expected expired batch to be forgotten")
+	}
+}
+
+func TestAppliedBatches_MaxKeys(t *testing.T) {
+	ab := New(&AppliedBatchesConfig{MaxKeys: 3})
+
+	for i := 1; i <= 5; i++ {
+		ab.Remember(fmt.Sprintf("batch-%d", i))
+	}
+
+	if ab.Len() != 3 {
+		t.Errorf("Expected 3 keys in memory, got %d", ab.Len())
+	}
+
+	if seen, _ := ab.Seen("batch-1"); seen {
+		t.Error("Expected oldest key to be evicted")
+	}
+
+	if seen, _ := ab.Seen("batch-5"); !seen {
+		t.Error("Expected newest key to be kept")
+	}
+}
+
+func TestValidateKey(t *testing.T) {
+	valid := []string{"", "batch-1", "0f8e6c1a-2b3d-4e5f-9a8b-7c6d5e4f3a2b", strings.Repeat("k", MaxKeyLength)}
+	for _, key := range valid {
+		if err := ValidateKey(key); err != nil {
+			t.Errorf("ValidateKey(%q) error = %v", key, err)
+		}
+	}
+
+	invalid := []string{strings.Repeat("k", MaxKeyLength+1), "batch\n1", "батч"}
+	for _, key := range invalid {
+		if err := ValidateKey(key); err == nil {
+			t.Errorf("ValidateKey(%q) error = nil", key)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"sync"
+	"time"
+)
+
+// storageState содержит сведения о восстановлении и сохранении хранилища для проверок готовности.
+type storageState struct {
+	mu         sync.RWMutex // мьютекс для безопасного доступа к состоянию
+	restored   bool         // восстановление метрик при старте завершено
+	restoreErr error        // ошибка восстановления метрик
+	lastSave   time.Time    // время последнего успешного сохранения в файл
+}
+
+// setRestored отмечает завершение восстановления метрик.
+func (s *storageState) setRestored(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.restored = true
+	s.restoreErr = err
+}
+
+// setLastSave запоминает время успешного сохранения в файл.
+func (s *storageState) setLastSave(t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastSave = t
+}
+
+// RestoreStatus сообщает, завершено ли восстановление метрик при старте, и его ошибку.
+func (ms *MetricsStorage) RestoreStatus() (bool, error) {
+	ms.state.mu.RLock()
+	defer ms.state.mu.RUnlock()
+
+	return ms.state.restored, ms.state.restoreErr
+}
+
+// LastSave возвращает время последнего успешного сохранения метрик в файл
+// или нулевое время, если сохранений еще не было.
+func (ms *MetricsStorage) LastSave() time.Time {
+	ms.state.mu.RLock()
+	defer ms.state.mu.RUnlock()
+
+	return ms.state.lastSave
+}
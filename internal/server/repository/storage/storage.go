@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"strings"
@@ -13,59 +14,83 @@ import (
 	"time"
 
 	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/interfaces"
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+	"github.com/Ko4etov/go-metrics/internal/server/service/selfmetrics"
 )
 
 // MetricsStorage реализует хранилище метрик.
 type MetricsStorage struct {
-	metrics    map[string]models.Metrics // карта метрик
-	mu         *sync.Mutex               // мьютекс для безопасного доступа
-	config     *MetricsStorageConfig     // конфигурация хранилища
-	saveTicker *time.Ticker              // таймер для периодического сохранения
-	done       chan bool                 // канал для остановки таймера
+	metrics     map[string]models.Metrics // карта метрик
+	mu          *sync.Mutex               // мьютекс для безопасного доступа
+	config      *MetricsStorageConfig     // конфигурация хранилища
+	saveTicker  *time.Ticker              // таймер для периодического сохранения
+	done        chan bool                 // канал для остановки таймера
+	subscribers map[*subscriber]struct{}  // подписчики на изменения метрик
+	subMu       sync.Mutex                // мьютекс для безопасного доступа к подписчикам
+	state       storageState              // состояние восстановления и сохранения
 }
 
 // MetricsStorageConfig содержит конфигурацию хранилища.
 type MetricsStorageConfig struct {
-	RestoreMetrics         bool          // восстанавливать метрики при старте
-	StoreMetricsInterval   int           // интервал сохранения в секундах
-	FileStorageMetricsPath string        // путь к файлу хранения
-	ConnectionPool         *pgxpool.Pool // пул подключений к базе данных
+	RestoreMetrics         bool                   // восстанавливать метрики при старте
+	StoreMetricsInterval   int                    // интервал сохранения в секундах
+	FileStorageMetricsPath string                 // путь к файлу хранения
+	ConnectionPool         *pgxpool.Pool          // пул подключений к базе данных
+	History                interfaces.History     // история обновлений метрик (опционально)
+	Idempotency            interfaces.Idempotency // идентификаторы примененных батчей (опционально)
+	SelfMetrics            *selfmetrics.Registry  // собственные метрики сервера (опционально)
 }
 
 // New создает новое хранилище метрик.
 func New(config *MetricsStorageConfig) *MetricsStorage {
 	storage := &MetricsStorage{
-		metrics: make(map[string]models.Metrics),
-		mu:      &sync.Mutex{},
-		config:  config,
-		done:    make(chan bool),
+		metrics:     make(map[string]models.Metrics),
+		mu:          &sync.Mutex{},
+		config:      config,
+		done:        make(chan bool),
+		subscribers: make(map[*subscriber]struct{}),
 	}
 
 	if config.RestoreMetrics {
 		storage.LoadSavedMetrics()
+	} else {
+		storage.state.setRestored(nil)
 	}
 
 	return storage
 }
 
 // LoadSavedMetrics загружает сохраненные метрики.
+// Отсутствие файла хранения при первом запуске ошибкой восстановления не считается.
 func (ms *MetricsStorage) LoadSavedMetrics() {
+	var err error
+
 	if ms.config.ConnectionPool != nil {
-		ms.LoadFromDatabase()
+		err = ms.LoadFromDatabase()
 	} else if ms.config.FileStorageMetricsPath != "" {
-		ms.LoadFromFile()
+		if _, statErr := os.Stat(ms.config.FileStorageMetricsPath); !os.IsNotExist(statErr) {
+			err = ms.LoadFromFile()
+		}
+	}
+
+	if err != nil {
+		logger.Logger.Errorf("failed to restore metrics: %v", err)
 	}
+
+	ms.state.setRestored(err)
 }
 
 // LoadFromDatabase загружает метрики из базы данных.
 func (ms *MetricsStorage) LoadFromDatabase() error {
 	ctx := context.Background()
 	rows, err := ms.config.ConnectionPool.Query(ctx,
-		"SELECT id, type, delta, value, hash FROM metrics")
+		"SELECT id, type, delta, value, hash, labels, histogram, summary FROM metrics")
 	if err != nil {
 		return fmt.Errorf("failed to query metrics: %w", err)
 	}
@@ -76,11 +101,29 @@ func (ms *MetricsStorage) LoadFromDatabase() error {
 
 	for rows.Next() {
 		var metric models.Metrics
-		err := rows.Scan(&metric.ID, &metric.MType, &metric.Delta, &metric.Value, &metric.Hash)
+		var labels, histogram, summary []byte
+		err := rows.Scan(&metric.ID, &metric.MType, &metric.Delta, &metric.Value, &metric.Hash,
+			&labels, &histogram, &summary)
 		if err != nil {
 			return fmt.Errorf("failed to scan metric: %w", err)
 		}
-		ms.metrics[metric.ID] = metric
+		if histogram != nil {
+			if err := json.Unmarshal(histogram, &metric.Histogram); err != nil {
+				return fmt.Errorf("failed to decode histogram of metric %s: %w", metric.ID, err)
+			}
+		}
+		if summary != nil {
+			if err := json.Unmarshal(summary, &metric.Summary); err != nil {
+				return fmt.Errorf("failed to decode summary of metric %s: %w", metric.ID, err)
+			}
+		}
+		if err := json.Unmarshal(labels, &metric.Labels); err != nil {
+			return fmt.Errorf("failed to decode labels of metric %s: %w", metric.ID, err)
+		}
+		if len(metric.Labels) == 0 {
+			metric.Labels = nil
+		}
+		ms.metrics[metric.Key()] = metric
 	}
 
 	return rows.Err()
@@ -109,30 +152,53 @@ func (ms *MetricsStorage) StartPeriodicSave() {
 
 // SaveToFile сохраняет метрики в файл.
 func (ms *MetricsStorage) SaveToFile() error {
-
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 
+	return ms.saveToFileLocked(ms.metrics)
+}
+
+// saveToFileLocked сохраняет метрики в файл и учитывает результат в собственных метриках
+// сервера. Вызывается под блокировкой хранилища.
+func (ms *MetricsStorage) saveToFileLocked(metrics map[string]models.Metrics) error {
+	start := time.Now()
+	size, err := ms.saveToFile(metrics)
+	ms.config.SelfMetrics.ObserveDuration(selfmetrics.StorageSaveDuration, nil, time.Since(start))
+
+	if err != nil {
+		ms.config.SelfMetrics.Add(selfmetrics.StorageSaveFailures, nil, 1)
+		return err
+	}
+
+	ms.config.SelfMetrics.Set(selfmetrics.StorageFileBytes, nil, float64(size))
+	ms.state.setLastSave(time.Now())
+
+	return nil
+}
+
+// saveToFile записывает метрики в файл и возвращает размер записанных данных.
+// Вызывается под блокировкой хранилища.
+func (ms *MetricsStorage) saveToFile(metrics map[string]models.Metrics) (int, error) {
 	dir := filepath.Dir(ms.config.FileStorageMetricsPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+		return 0, fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	metricsSlice := make([]models.Metrics, 0, len(ms.metrics))
-	for _, metric := range ms.metrics {
+	metricsSlice := make([]models.Metrics, 0, len(metrics))
+	for _, metric := range metrics {
 		metricsSlice = append(metricsSlice, metric)
 	}
 
 	data, err := json.MarshalIndent(metricsSlice, "", "  ")
 	if err != nil {
-		return fmt.Errorf("failed to marshal metrics: %w", err)
+		return 0, fmt.Errorf("failed to marshal metrics: %w", err)
 	}
 
 	if err := os.WriteFile(ms.config.FileStorageMetricsPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+		return 0, fmt.Errorf("failed to write file: %w", err)
 	}
 
-	return nil
+	return len(data), nil
 }
 
 // StopPeriodicSave останавливает периодическое сохранение.
@@ -175,67 +241,82 @@ func (ms *MetricsStorage) LoadFromFile() error {
 	defer ms.mu.Unlock()
 
 	for _, metric := range metricsSlice {
-		ms.metrics[metric.ID] = metric
+		ms.metrics[metric.Key()] = metric
 	}
 
 	return nil
 }
 
-// Metrics возвращает все метрики.
+// Metrics возвращает копию всех метрик по ключам рядов.
 func (ms *MetricsStorage) Metrics() map[string]models.Metrics {
-	return ms.metrics
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	return maps.Clone(ms.metrics)
 }
 
 // UpdateMetric обновляет одну метрику.
 func (ms *MetricsStorage) UpdateMetric(metric models.Metrics) error {
+	return ms.UpdateMetricFrom("", metric)
+}
+
+// UpdateMetricFrom обновляет одну метрику, полученную из указанного источника.
+func (ms *MetricsStorage) UpdateMetricFrom(source string, metric models.Metrics) error {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 
-	switch metric.MType {
-	case models.Gauge:
-		if metric.Value == nil {
-			return ErrInvalidValue
-		}
-		ms.metrics[metric.ID] = metric
-
-	case models.Counter:
-		if metric.Delta == nil {
-			return ErrInvalidDelta
-		}
-
-		if existing, exists := ms.metrics[metric.ID]; exists && existing.MType == models.Counter {
-			newDelta := *existing.Delta + *metric.Delta
-			metric.Delta = &newDelta
-		}
-		ms.metrics[metric.ID] = metric
+	accepted := metric
 
-	default:
-		return ErrInvalidType
+	metric, err := ms.mergeMetric(nil, metric)
+	if err != nil {
+		return err
 	}
 
+	// Метрика становится видна только после сохранения.
 	if ms.config.ConnectionPool != nil {
 		if err := ms.saveMetricToDatabase(metric); err != nil {
 			return fmt.Errorf("failed to save metric to database: %w", err)
 		}
-	} else if ms.config.StoreMetricsInterval == 0 && ms.config.FileStorageMetricsPath != "" {
-		return ms.SaveToFile()
+	} else if ms.syncFileSave() {
+		snapshot := maps.Clone(ms.metrics)
+		snapshot[metric.Key()] = metric
+		if err := ms.saveToFileLocked(snapshot); err != nil {
+			return err
+		}
 	}
 
+	ms.metrics[metric.Key()] = metric
+	ms.publish(metric)
+	ms.recordHistory(source, []models.Metrics{accepted})
+
 	return nil
 }
 
-// Metric возвращает метрику по идентификатору.
+// syncFileSave сообщает, сохраняются ли метрики в файл при каждом обновлении.
+func (ms *MetricsStorage) syncFileSave() bool {
+	return ms.config.StoreMetricsInterval == 0 && ms.config.FileStorageMetricsPath != ""
+}
+
+// Metric возвращает метрику по ключу ряда: имени и меткам (см. models.MetricKey).
+// Ключ метрики без меток совпадает с ее именем.
 func (ms *MetricsStorage) Metric(id string) (models.Metrics, bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
 	metric, ok := ms.metrics[id]
 	return metric, ok
 }
 
 // ResetAll сбрасывает все метрики.
 func (ms *MetricsStorage) ResetAll() {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
 	ms.metrics = make(map[string]models.Metrics)
 }
 
 // GaugeMetric возвращает значение метрики типа gauge в виде строки.
+// name - ключ ряда метрики (см. Metric).
 func (ms *MetricsStorage) GaugeMetric(name string) (string, error) {
 	metric, exists := ms.Metric(name)
 	if !exists || metric.MType != "gauge" {
@@ -250,6 +331,7 @@ func (ms *MetricsStorage) GaugeMetric(name string) (string, error) {
 }
 
 // CounterMetric возвращает значение метрики типа counter в виде строки.
+// name - ключ ряда метрики (см. Metric).
 func (ms *MetricsStorage) CounterMetric(name string) (string, error) {
 	metric, exists := ms.Metric(name)
 	if !exists || metric.MType != "counter" {
@@ -264,6 +346,7 @@ func (ms *MetricsStorage) CounterMetric(name string) (string, error) {
 }
 
 // GaugeMetricModel возвращает метрику типа gauge в виде модели.
+// name - ключ ряда метрики (см. Metric).
 func (ms *MetricsStorage) GaugeMetricModel(name string) (*models.Metrics, error) {
 	metric, exists := ms.Metric(name)
 	if !exists || metric.MType != "gauge" {
@@ -278,6 +361,7 @@ func (ms *MetricsStorage) GaugeMetricModel(name string) (*models.Metrics, error)
 }
 
 // CounterMetricModel возвращает метрику типа counter в виде модели.
+// name - ключ ряда метрики (см. Metric).
 func (ms *MetricsStorage) CounterMetricModel(name string) (*models.Metrics, error) {
 	metric, exists := ms.Metric(name)
 	if !exists || metric.MType != "counter" {
@@ -293,60 +377,206 @@ func (ms *MetricsStorage) CounterMetricModel(name string) (*models.Metrics, erro
 
 // UpdateMetricsBatch обновляет батч метрик.
 func (ms *MetricsStorage) UpdateMetricsBatch(metrics []models.Metrics) error {
+	return ms.UpdateMetricsBatchFrom("", metrics)
+}
+
+// UpdateMetricsBatchFrom обновляет батч метрик, полученный из указанного источника.
+func (ms *MetricsStorage) UpdateMetricsBatchFrom(source string, metrics []models.Metrics) error {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 
-	for _, metric := range metrics {
-		switch metric.MType {
-		case models.Gauge:
-			if metric.Value == nil {
-				return ErrInvalidValue
-			}
-			ms.metrics[metric.ID] = metric
+	return ms.applyMetricsBatch(source, "", metrics)
+}
 
-		case models.Counter:
-			if metric.Delta == nil {
-				return ErrInvalidDelta
-			}
+// UpdateMetricsBatchOnce обновляет батч метрик, если батч с идентификатором key
+// еще не применялся. Возвращает false, если батч уже был применен ранее.
+// Пустой идентификатор или отсутствие хранилища идентификаторов отключают проверку.
+func (ms *MetricsStorage) UpdateMetricsBatchOnce(source, key string, metrics []models.Metrics) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
 
-			if existing, exists := ms.metrics[metric.ID]; exists && existing.MType == models.Counter {
-				newDelta := *existing.Delta + *metric.Delta
-				metric.Delta = &newDelta
-			}
-			ms.metrics[metric.ID] = metric
+	if key == "" || ms.config.Idempotency == nil {
+		return true, ms.applyMetricsBatch(source, "", metrics)
+	}
+
+	seen, err := ms.config.Idempotency.Seen(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to check batch key: %w", err)
+	}
+
+	if seen {
+		return false, nil
+	}
+
+	if err := ms.applyMetricsBatch(source, key, metrics); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
 
-		default:
-			return ErrInvalidType
+// txIdempotency - хранилище идентификаторов батчей, сохраняющее идентификатор
+// в транзакции базы данных вместе с метриками батча.
+type txIdempotency interface {
+	RememberTx(ctx context.Context, tx pgx.Tx, key string) (commit func(), err error)
+}
+
+// applyMetricsBatch применяет батч метрик и запоминает идентификатор батча key, если он задан.
+// Батч применяется к копии хранимых значений, которая становится текущей только после
+// успешного сохранения, поэтому при ошибке хранилище не меняется. В базе данных
+// идентификатор батча сохраняется в одной транзакции с метриками.
+// Вызывается под блокировкой хранилища.
+func (ms *MetricsStorage) applyMetricsBatch(source, key string, metrics []models.Metrics) error {
+	updated := make(map[string]models.Metrics, len(metrics))
+	// В базу данных сохраняются накопленные значения, а не принятые приращения.
+	merged := make([]models.Metrics, 0, len(metrics))
+
+	for _, metric := range metrics {
+		metric, err := ms.mergeMetric(updated, metric)
+		if err != nil {
+			return err
 		}
+		updated[metric.Key()] = metric
+		merged = append(merged, metric)
 	}
 
+	remembered := key == ""
+
 	if ms.config.ConnectionPool != nil {
-		if err := ms.saveMetricsBatchToDatabase(metrics); err != nil {
+		commitKey, err := ms.saveMetricsBatchToDatabase(merged, key)
+		if err != nil {
 			return fmt.Errorf("failed to save metrics batch to database: %w", err)
 		}
-	} else if ms.config.StoreMetricsInterval == 0 && ms.config.FileStorageMetricsPath != "" {
-		return ms.SaveToFile()
+		if commitKey != nil {
+			commitKey()
+			remembered = true
+		}
+	} else if ms.syncFileSave() {
+		snapshot := maps.Clone(ms.metrics)
+		maps.Copy(snapshot, updated)
+		if err := ms.saveToFileLocked(snapshot); err != nil {
+			return err
+		}
+	}
+
+	maps.Copy(ms.metrics, updated)
+	for _, metric := range merged {
+		ms.publish(metric)
+	}
+	ms.recordHistory(source, metrics)
+
+	// Без общей транзакции с метриками идентификатор сохраняется после применения батча.
+	if !remembered {
+		if err := ms.config.Idempotency.Remember(key); err != nil {
+			return fmt.Errorf("failed to remember batch key %s: %w", key, err)
+		}
 	}
 
 	return nil
 }
 
+// mergeMetric объединяет обновление метрики с текущим значением: из pending, если ряд
+// уже обновлялся в этом батче, иначе из хранилища. Значение измерителя заменяется,
+// приращение счетчика суммируется. У гистограммы суммируются наблюдения по корзинам,
+// у сводки - количество и сумма наблюдений, а квантили заменяются последними.
+// Хранилище не меняется. Вызывается под блокировкой хранилища.
+func (ms *MetricsStorage) mergeMetric(pending map[string]models.Metrics, metric models.Metrics) (models.Metrics, error) {
+	key := metric.Key()
+	existing, exists := pending[key]
+	if !exists {
+		existing, exists = ms.metrics[key]
+	}
+	if exists && existing.MType != metric.MType {
+		exists = false
+	}
+
+	switch metric.MType {
+	case models.Gauge:
+		if metric.Value == nil {
+			return metric, ErrInvalidValue
+		}
+
+	case models.Counter:
+		if metric.Delta == nil {
+			return metric, ErrInvalidDelta
+		}
+
+		if exists {
+			newDelta := *existing.Delta + *metric.Delta
+			metric.Delta = &newDelta
+		}
+
+	case models.Histogram:
+		if metric.Histogram == nil {
+			return metric, ErrInvalidHistogram
+		}
+
+		if exists {
+			metric.Histogram = existing.Histogram.Merge(metric.Histogram)
+		}
+
+	case models.Summary:
+		if metric.Summary == nil {
+			return metric, ErrInvalidSummary
+		}
+
+		if exists {
+			metric.Summary = existing.Summary.Merge(metric.Summary)
+		}
+
+	default:
+		return metric, ErrInvalidType
+	}
+
+	return metric, nil
+}
+
+// recordHistory сохраняет принятые обновления в историю метрик.
+func (ms *MetricsStorage) recordHistory(source string, metrics []models.Metrics) {
+	if ms.config.History == nil {
+		return
+	}
+
+	// История ведется только для измерителей и счетчиков.
+	recorded := make([]models.Metrics, 0, len(metrics))
+	for _, metric := range metrics {
+		if metric.MType == models.Gauge || metric.MType == models.Counter {
+			recorded = append(recorded, metric)
+		}
+	}
+
+	if len(recorded) == 0 {
+		return
+	}
+
+	if err := ms.config.History.Record(source, recorded); err != nil {
+		logger.Logger.Errorf("failed to record metrics history: %v", err)
+	}
+}
+
 // saveMetricToDatabase сохраняет одну метрику в базу данных.
 func (ms *MetricsStorage) saveMetricToDatabase(metric models.Metrics) error {
+	args, err := metricColumns(metric)
+	if err != nil {
+		return err
+	}
+
 	operation := func() error {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
 		_, err := ms.config.ConnectionPool.Exec(ctx,
-			`INSERT INTO metrics (id, type, delta, value, hash, updated_at) 
-			 VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)
-			 ON CONFLICT (id, type) 
+			`INSERT INTO metrics (key, id, type, labels, delta, value, hash, histogram, summary, updated_at) 
+			 VALUES ($1, $2, $3, $4::jsonb, $5, $6, $7, $8::jsonb, $9::jsonb, CURRENT_TIMESTAMP)
+			 ON CONFLICT (key, type) 
 			 DO UPDATE SET 
 			   delta = EXCLUDED.delta,
 			   value = EXCLUDED.value,
 			   hash = EXCLUDED.hash,
+			   histogram = EXCLUDED.histogram,
+			   summary = EXCLUDED.summary,
 			   updated_at = CURRENT_TIMESTAMP`,
-			metric.ID, metric.MType, metric.Delta, metric.Value, metric.Hash)
+			args...)
 
 		return err
 	}
@@ -354,8 +584,26 @@ func (ms *MetricsStorage) saveMetricToDatabase(metric models.Metrics) error {
 	return ms.executeWithRetry(operation, "save metric to database")
 }
 
-// saveMetricsBatchToDatabase сохраняет батч метрик в базу данных.
-func (ms *MetricsStorage) saveMetricsBatchToDatabase(metrics []models.Metrics) error {
+// saveMetricsBatchToDatabase сохраняет батч метрик в базу данных. Если хранилище
+// идентификаторов поддерживает транзакции, идентификатор батча key сохраняется в той же
+// транзакции, а возвращаемая функция запоминает его после фиксации; иначе она равна nil.
+func (ms *MetricsStorage) saveMetricsBatchToDatabase(metrics []models.Metrics, key string) (func(), error) {
+	idempotency, _ := ms.config.Idempotency.(txIdempotency)
+	if key == "" {
+		idempotency = nil
+	}
+
+	rows := make([][]any, 0, len(metrics))
+	for _, metric := range metrics {
+		args, err := metricColumns(metric)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, args)
+	}
+
+	var commitKey func()
+
 	operation := func() error {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
@@ -366,27 +614,86 @@ func (ms *MetricsStorage) saveMetricsBatchToDatabase(metrics []models.Metrics) e
 		}
 		defer tx.Rollback(ctx)
 
-		for _, metric := range metrics {
+		for i, metric := range metrics {
 			_, err := tx.Exec(ctx,
-				`INSERT INTO metrics (id, type, delta, value, hash, updated_at) 
-				 VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)
-				 ON CONFLICT (id, type) 
+				`INSERT INTO metrics (key, id, type, labels, delta, value, hash, histogram, summary, updated_at) 
+				 VALUES ($1, $2, $3, $4::jsonb, $5, $6, $7, $8::jsonb, $9::jsonb, CURRENT_TIMESTAMP)
+				 ON CONFLICT (key, type) 
 				 DO UPDATE SET 
 				   delta = EXCLUDED.delta,
 				   value = EXCLUDED.value,
 				   hash = EXCLUDED.hash,
+				   histogram = EXCLUDED.histogram,
+				   summary = EXCLUDED.summary,
 				   updated_at = CURRENT_TIMESTAMP`,
-				metric.ID, metric.MType, metric.Delta, metric.Value, metric.Hash)
+				rows[i]...)
 
 			if err != nil {
-				return fmt.Errorf("failed to save metric %s: %w", metric.ID, err)
+				return fmt.Errorf("failed to save metric %s: %w", metric.Key(), err)
+			}
+		}
+
+		if idempotency != nil {
+			if commitKey, err = idempotency.RememberTx(ctx, tx, key); err != nil {
+				return fmt.Errorf("failed to remember batch key %s: %w", key, err)
 			}
 		}
 
 		return tx.Commit(ctx)
 	}
 
-	return ms.executeWithRetry(operation, "save metrics batch to database")
+	if err := ms.executeWithRetry(operation, "save metrics batch to database"); err != nil {
+		return nil, err
+	}
+
+	return commitKey, nil
+}
+
+// labelsJSON возвращает метки метрики в формате JSON для сохранения в базе данных.
+func labelsJSON(labels models.Labels) string {
+	if len(labels) == 0 {
+		return "{}"
+	}
+
+	data, err := json.Marshal(labels)
+	if err != nil {
+		return "{}"
+	}
+
+	return string(data)
+}
+
+// metricColumns возвращает значения колонок метрики для запроса сохранения в базу данных.
+func metricColumns(metric models.Metrics) ([]any, error) {
+	histogram, err := jsonColumn(metric.Histogram)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode histogram of metric %s: %w", metric.Key(), err)
+	}
+
+	summary, err := jsonColumn(metric.Summary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode summary of metric %s: %w", metric.Key(), err)
+	}
+
+	return []any{
+		metric.Key(), metric.ID, metric.MType, labelsJSON(metric.Labels),
+		metric.Delta, metric.Value, metric.Hash, histogram, summary,
+	}, nil
+}
+
+// jsonColumn возвращает значение в формате JSON для сохранения в базе данных,
+// для пустого значения - NULL.
+func jsonColumn[T any](value *T) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
 }
 
 // executeWithRetry выполняет операцию с повторными попытками.
@@ -412,6 +719,7 @@ func (ms *MetricsStorage) executeWithRetry(operation func() error, operationName
 		}
 
 		if attempt < maxRetries {
+			ms.config.SelfMetrics.Add(selfmetrics.DBRetriesTotal, models.Labels{"operation": operationName}, 1)
 			delay := retryDelays[attempt]
 			time.Sleep(delay)
 		}
@@ -460,9 +768,13 @@ func (ms *MetricsStorage) isPostgresRetriableCode(pgErr *pgconn.PgError) bool {
 	return false
 }
 
-// isPostgresNonRetriableError проверяет не повторяемые ошибки PostgreSQL.
+// isPostgresNonRetriableError проверяет не повторяемые ошибки PostgreSQL: ошибки данных
+// (например, слишком длинная строка), нарушения ограничений и ошибки запроса.
+// Повтор таких операций завершится той же ошибкой.
 func (ms *MetricsStorage) isPostgresNonRetriableError(pgErr *pgconn.PgError) bool {
-	return pgErr.Code == pgerrcode.UniqueViolation
+	return pgerrcode.IsDataException(pgErr.Code) ||
+		pgerrcode.IsIntegrityConstraintViolation(pgErr.Code) ||
+		pgerrcode.IsSyntaxErrororAccessRuleViolation(pgErr.Code)
 }
 
 // isContextError проверяет ошибки контекста.
@@ -489,7 +801,9 @@ func (ms *MetricsStorage) isNetworkErrorByContent(err error) bool {
 
 // Ошибки хранилища.
 var (
-	ErrInvalidType  = errors.New("invalid metric type")
-	ErrInvalidValue = errors.New("invalid value for gauge metric")
-	ErrInvalidDelta = errors.New("invalid delta for counter metric")
-)
\ No newline at end of file
+	ErrInvalidType      = errors.New("invalid metric type")
+	ErrInvalidValue     = errors.New("invalid value for gauge metric")
+	ErrInvalidDelta     = errors.New("invalid delta for counter metric")
+	ErrInvalidHistogram = errors.New("invalid value for histogram metric")
+	ErrInvalidSummary   = errors.New("invalid value for summary metric")
+)
@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+)
+
+func readSavedMetrics(t *testing.T, path string) []models.Metrics {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read storage file: %v", err)
+	}
+
+	var metrics []models.Metrics
+	if err := json.Unmarshal(data, &metrics); err != nil {
+		t.Fatalf("failed to decode storage file: %v", err)
+	}
+
+	return metrics
+}
+
+func TestUpdateMetric_SynchronousSave(t *testing.T) {
+	logger.Initialize("error")
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	store := New(&MetricsStorageConfig{FileStorageMetricsPath: path})
+
+	done := make(chan error, 1)
+	go func() {
+		value := 1.5
+		done <- store.UpdateMetric(models.Metrics{ID: "Alloc", MType: models.Gauge, Value: &value})
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("UpdateMetric() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("UpdateMetric() blocked on synchronous save")
+	}
+
+	if metrics := readSavedMetrics(t, path); len(metrics) != 1 {
+		t.Errorf("saved %d metrics, want 1", len(metrics))
+	}
+}
+
+func TestStopPeriodicSave_FlushesToFile(t *testing.T) {
+	logger.Initialize("error")
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	store := New(&MetricsStorageConfig{FileStorageMetricsPath: path, StoreMetricsInterval: 300})
+	store.StartPeriodicSave()
+
+	delta := int64(3)
+	if err := store.UpdateMetric(models.Metrics{ID: "PollCount", MType: models.Counter, Delta: &delta}); err != nil {
+		t.Fatalf("UpdateMetric() error = %v", err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("storage file written before shutdown: %v", err)
+	}
+
+	store.StopPeriodicSave()
+
+	metrics := readSavedMetrics(t, path)
+	if len(metrics) != 1 || metrics[0].ID != "PollCount" || *metrics[0].Delta != 3 {
+		t.Errorf("saved metrics = %+v, want PollCount=3", metrics)
+	}
+
+	if store.LastSave().IsZero() {
+		t.Error("LastSave() is zero after shutdown save")
+	}
+}
+
+// failingIdempotency - хранилище идентификаторов батчей, которое не может их сохранить.
+type failingIdempotency struct{}
+
+func (failingIdempotency) Seen(string) (bool, error) { return false, nil }
+
+func (failingIdempotency) Remember(string) error { return errors.New("disk full") }
+
+func TestUpdateMetricsBatchOnce_FailedBatchLeavesStorageUnchanged(t *testing.T) {
+	logger.Initialize("error")
+	store := New(&MetricsStorageConfig{})
+
+	delta := int64(1)
+	if err := store.UpdateMetric(models.Metrics{ID: "PollCount", MType: models.Counter, Delta: &delta}); err != nil {
+		t.Fatalf("UpdateMetric() error = %v", err)
+	}
+
+	// Вторая метрика батча невалидна, поэтому первая не должна примениться.
+	batch := []models.Metrics{
+		{ID: "PollCount", MType: models.Counter, Delta: &delta},
+		{ID: "Alloc", MType: models.Gauge},
+	}
+	if _, err := store.UpdateMetricsBatchOnce("", "batch-1", batch); err == nil {
+		t.Fatal("UpdateMetricsBatchOnce() error = nil, want invalid value error")
+	}
+
+	if value, _ := store.CounterMetric("PollCount"); value != "1" {
+		t.Errorf("PollCount = %s after failed batch, want 1", value)
+	}
+	if _, ok := store.Metric("Alloc"); ok {
+		t.Error("Alloc stored from failed batch")
+	}
+}
+
+func TestUpdateMetricsBatchOnce_ReturnsRememberError(t *testing.T) {
+	logger.Initialize("error")
+	store := New(&MetricsStorageConfig{Idempotency: failingIdempotency{}})
+
+	delta := int64(1)
+	batch := []models.Metrics{{ID: "PollCount", MType: models.Counter, Delta: &delta}}
+
+	if _, err := store.UpdateMetricsBatchOnce("", "batch-1", batch); err == nil {
+		t.Error("UpdateMetricsBatchOnce() error = nil, want remember error")
+	}
+}
+
+func TestIsRetriableDBError_DataErrors(t *testing.T) {
+	store := New(&MetricsStorageConfig{})
+
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{pgerrcode.StringDataRightTruncationDataException, false},
+		{pgerrcode.UniqueViolation, false},
+		{pgerrcode.UndefinedTable, false},
+		{pgerrcode.AdminShutdown, true},
+		{pgerrcode.SerializationFailure, true},
+	}
+
+	for _, tt := range tests {
+		err := fmt.Errorf("save metric: %w", &pgconn.PgError{Code: tt.code})
+		if got := store.isRetriableDBError(err); got != tt.want {
+			t.Errorf("isRetriableDBError(%s) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestMetricColumns_ReportsEncodingError(t *testing.T) {
+	metric := models.Metrics{
+		ID:        "latency",
+		MType:     models.Histogram,
+		Histogram: &models.HistogramValue{Buckets: []float64{1}, Counts: []uint64{1, 0}, Sum: math.NaN(), Count: 1},
+	}
+
+	if _, err := metricColumns(metric); err == nil {
+		t.Fatal("expected error for histogram that cannot be encoded to JSON")
+	}
+
+	metric.Histogram.Sum = 0.5
+	args, err := metricColumns(metric)
+	if err != nil {
+		t.Fatalf("metricColumns() error = %v", err)
+	}
+	if args[7] == nil {
+		t.Error("expected histogram column to be set")
+	}
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+)
+
+// subscriber - подписчик на изменения метрик хранилища.
+type subscriber struct {
+	updates chan models.Metrics       // канал изменений с ограниченным буфером
+	filter  func(models.Metrics) bool // фильтр изменений (опционально)
+}
+
+// Subscribe подписывает на изменения метрик: в канал передается новое значение
+// каждой метрики, измененной через UpdateMetric или UpdateMetricsBatch и прошедшей filter.
+//
+// Канал имеет буфер размером buffer. Если подписчик не успевает читать изменения
+// и буфер переполнен, подписка отменяется и канал закрывается, чтобы медленный
+// подписчик не задерживал запись метрик. Возвращаемая функция отменяет подписку.
+func (ms *MetricsStorage) Subscribe(filter func(models.Metrics) bool, buffer int) (<-chan models.Metrics, func()) {
+	sub := &subscriber{
+		updates: make(chan models.Metrics, buffer),
+		filter:  filter,
+	}
+
+	ms.subMu.Lock()
+	ms.subscribers[sub] = struct{}{}
+	ms.subMu.Unlock()
+
+	return sub.updates, func() {
+		ms.unsubscribe(sub)
+	}
+}
+
+// unsubscribe отменяет подписку и закрывает ее канал, если она еще активна.
+func (ms *MetricsStorage) unsubscribe(sub *subscriber) {
+	ms.subMu.Lock()
+	defer ms.subMu.Unlock()
+
+	if _, ok := ms.subscribers[sub]; ok {
+		delete(ms.subscribers, sub)
+		close(sub.updates)
+	}
+}
+
+// publish передает изменение метрики подписчикам без блокировки.
+// Подписчики с переполненным буфером отключаются.
+func (ms *MetricsStorage) publish(metric models.Metrics) {
+	ms.subMu.Lock()
+	defer ms.subMu.Unlock()
+
+	for sub := range ms.subscribers {
+		if sub.filter != nil && !sub.filter(metric) {
+			continue
+		}
+
+		select {
+		case sub.updates <- metric:
+		default:
+			logger.Logger.Warnf("[storage] subscriber buffer is full, subscription dropped")
+			delete(ms.subscribers, sub)
+			close(sub.updates)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+)
+
+func TestSubscribe(t *testing.T) {
+	logger.Initialize("error")
+	store := New(&MetricsStorageConfig{})
+
+	counters, cancelCounters := store.Subscribe(func(metric models.Metrics) bool {
+		return metric.MType == models.Counter
+	}, 10)
+	defer cancelCounters()
+
+	slow, _ := store.Subscribe(nil, 1)
+
+	value := 1.5
+	delta := int64(2)
+	store.UpdateMetric(models.Metrics{ID: "Alloc", MType: models.Gauge, Value: &value})
+	store.UpdateMetricsBatch([]models.Metrics{
+		{ID: "PollCount", MType: models.Counter, Delta: &delta},
+		{ID: "PollCount", MType: models.Counter, Delta: &delta},
+	})
+
+	for _, want := range []int64{2, 4} {
+		metric := <-counters
+		if metric.ID != "PollCount" || *metric.Delta != want {
+			t.Errorf("expected PollCount with accumulated delta %d, got %+v", want, metric)
+		}
+	}
+
+	if metric := <-slow; metric.ID != "Alloc" {
+		t.Errorf("expected first update to be delivered to slow subscriber, got %+v", metric)
+	}
+	if _, ok := <-slow; ok {
+		t.Error("expected slow subscriber to be dropped on buffer overflow")
+	}
+
+	cancelCounters()
+	if _, ok := <-counters; ok {
+		t.Error("expected channel to be closed after unsubscribe")
+	}
+	cancelCounters()
+}
@@ -2,48 +2,98 @@
 package router
 
 import (
+	"crypto/rsa"
+	"net"
+	"time"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/Ko4etov/go-metrics/internal/server/handler"
+	"github.com/Ko4etov/go-metrics/internal/server/interfaces"
 	"github.com/Ko4etov/go-metrics/internal/server/middlewares"
 	"github.com/Ko4etov/go-metrics/internal/server/repository/storage"
+	"github.com/Ko4etov/go-metrics/internal/server/service/alerting"
 	"github.com/Ko4etov/go-metrics/internal/server/service/audit"
+	"github.com/Ko4etov/go-metrics/internal/server/service/health"
+	"github.com/Ko4etov/go-metrics/internal/server/service/selfmetrics"
 )
 
 // RouteConfig содержит конфигурацию для маршрутизатора.
 type RouteConfig struct {
-	Storage  *storage.MetricsStorage // хранилище метрик
-	Pgx      *pgxpool.Pool           // пул подключений к базе данных
-	HashKey  string                  // ключ для хеширования
-	AuditSvc *audit.AuditService     // сервис аудита (опционально)
+	Storage          *storage.MetricsStorage // хранилище метрик
+	Pgx              *pgxpool.Pool           // пул подключений к базе данных
+	HashKey          string                  // ключ для хеширования
+	HashMode         string                  // режим проверки подписи запросов
+	CryptoKey        *rsa.PrivateKey         // закрытый ключ для расшифровки запросов (опционально)
+	CryptoMode       string                  // режим проверки шифрования запросов записи
+	TrustedSubnet    *net.IPNet              // доверенная подсеть агентов (опционально)
+	AuditSvc         *audit.AuditService     // сервис аудита (опционально)
+	History          interfaces.History      // история обновлений метрик (опционально)
+	Alerting         *alerting.Engine        // движок оповещений (опционально)
+	LogSampleRate    float64                 // доля обычных запросов, попадающих в журнал доступа
+	LogSlowThreshold time.Duration           // порог медленного запроса, 0 - отключен
+	SelfMetrics      *selfmetrics.Registry   // собственные метрики сервера (опционально)
+	Health           *health.Checker         // проверка готовности сервера (опционально)
 }
 
 // New создает новый маршрутизатор с настройкой всех middleware и обработчиков.
 func New(config *RouteConfig) *chi.Mux {
 	metricHandler := handler.New(config.Storage, config.Pgx)
 	hashConfig := &middlewares.HashConfig{
-		SecretKey: config.HashKey,
+		SecretKey:   config.HashKey,
+		Mode:        config.HashMode,
+		SelfMetrics: config.SelfMetrics,
+	}
+	loggingConfig := &middlewares.LoggingConfig{
+		SampleRate:    config.LogSampleRate,
+		SlowThreshold: config.LogSlowThreshold,
 	}
 
 	r := chi.NewRouter()
 
+	// Журнал доступа подключается первым, чтобы учитывать и запросы,
+	// отклоненные остальными middleware.
+	r.Use(middlewares.WithLogging(loggingConfig))
+	r.Use(middlewares.WithSelfMetrics(config.SelfMetrics))
+	r.Use(middlewares.WithDecryption(config.CryptoKey))
 	r.Use(middlewares.WithCompression)
 	r.Use(middlewares.WithHashing(hashConfig))
-	r.Use(middlewares.WithLogging)
-
-	r.Post("/update/{metricType}/{metricName}/{metricValue}", metricHandler.UpdateMetric)
-	r.Post("/update/", metricHandler.UpdateMetricJSON)
-	if config.AuditSvc != nil {
-		auditHandler := metricHandler.UpdateMetricsBatchWithAudit(config.AuditSvc)
-		r.Post("/updates/", auditHandler)
-	} else {
-		r.Post("/updates/", metricHandler.UpdateMetricsBatch)
-	}
+
+	// Запись метрик разрешена только агентам из доверенной подсети
+	// и только с шифрованием и подписью, если их проверка включена.
+	r.Group(func(r chi.Router) {
+		r.Use(middlewares.WithTrustedSubnet(config.TrustedSubnet))
+		r.Use(middlewares.WithEncryptionCheck(config.CryptoKey, config.CryptoMode))
+		r.Use(middlewares.WithSignatureCheck(hashConfig))
+
+		r.Post("/update/{metricType}/{metricName}/{metricValue}", metricHandler.UpdateMetric)
+		r.Post("/update/", metricHandler.UpdateMetricJSON)
+		if config.AuditSvc != nil {
+			auditHandler := metricHandler.UpdateMetricsBatchWithAudit(config.AuditSvc)
+			r.Post("/updates/", auditHandler)
+		} else {
+			r.Post("/updates/", metricHandler.UpdateMetricsBatch)
+		}
+		r.Post("/write", metricHandler.WriteInflux)
+	})
+
 	r.Get("/value/{metricType}/{metricName}", metricHandler.GetMetric)
 	r.Post("/value/", metricHandler.GetMetricJSON)
+	if config.History != nil {
+		r.Get("/history/{metricType}/{metricName}", metricHandler.GetMetricHistory(config.History))
+	}
+	if config.Alerting != nil {
+		r.Get("/alerts", metricHandler.GetAlerts(config.Alerting))
+	}
+	r.Get("/stream", metricHandler.StreamMetrics(config.Storage))
 	r.Get("/ping", metricHandler.DBPing)
+	r.Get("/healthz", metricHandler.Healthz)
+	if config.Health != nil {
+		r.Get("/readyz", metricHandler.Readyz(config.Health))
+	}
+	r.Get("/metrics", metricHandler.GetMetricsPrometheus)
 	r.Get("/", metricHandler.GetMetrics)
 
 	return r
-}
\ No newline at end of file
+}
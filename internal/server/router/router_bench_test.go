@@ -0,0 +1,96 @@
+package router_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/repository/storage"
+	"github.com/Ko4etov/go-metrics/internal/server/router"
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+)
+
+const benchHashKey = "secret"
+
+// benchMetrics возвращает n метрик для наполнения хранилища и тел запросов.
+func benchMetrics(n int) []models.Metrics {
+	metrics := make([]models.Metrics, 0, n)
+	for i := 0; i < n; i++ {
+		value := float64(i) * 1.5
+		metrics = append(metrics, models.Metrics{ID: fmt.Sprintf("Metric%d", i), MType: models.Gauge, Value: &value})
+	}
+	return metrics
+}
+
+// newBenchRouter создает маршрутизатор со всеми middleware и заполненным хранилищем.
+func newBenchRouter(b *testing.B) http.Handler {
+	b.Helper()
+	logger.Initialize("error")
+
+	store := storage.New(&storage.MetricsStorageConfig{})
+	if err := store.UpdateMetricsBatch(benchMetrics(100)); err != nil {
+		b.Fatalf("failed to fill storage: %v", err)
+	}
+
+	return router.New(&router.RouteConfig{Storage: store, HashKey: benchHashKey, HashMode: "log"})
+}
+
+func BenchmarkUpdates(b *testing.B) {
+	r := newBenchRouter(b)
+
+	body, _ := json.Marshal(benchMetrics(30))
+	mac := hmac.New(sha256.New, []byte(benchHashKey))
+	mac.Write(body)
+	hash := hex.EncodeToString(mac.Sum(nil))
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write(body)
+	gz.Close()
+	payload := compressed.Bytes()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "gzip")
+		req.Header.Set("Accept-Encoding", "gzip")
+		req.Header.Set("HashSHA256", hash)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			b.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func BenchmarkIndex(b *testing.B) {
+	r := newBenchRouter(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			b.Fatalf("unexpected status %d", rec.Code)
+		}
+	}
+}
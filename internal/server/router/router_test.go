@@ -0,0 +1,46 @@
+package router_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Ko4etov/go-metrics/internal/server/middlewares"
+	"github.com/Ko4etov/go-metrics/internal/server/repository/storage"
+	"github.com/Ko4etov/go-metrics/internal/server/router"
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+)
+
+func TestNew_SignatureEnforcedOnlyForWrites(t *testing.T) {
+	logger.Initialize("error")
+
+	store := storage.New(&storage.MetricsStorageConfig{})
+	if err := store.UpdateMetricsBatch(benchMetrics(1)); err != nil {
+		t.Fatalf("failed to fill storage: %v", err)
+	}
+	r := router.New(&router.RouteConfig{Storage: store, HashKey: "secret", HashMode: middlewares.HashModeEnforce})
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{"unsigned update", "/update/", `{"id":"Alloc","type":"gauge","value":1}`, http.StatusBadRequest},
+		{"unsigned read", "/value/", `{"id":"Metric0","type":"gauge"}`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
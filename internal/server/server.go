@@ -2,17 +2,32 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"google.golang.org/grpc"
+
 	"github.com/Ko4etov/go-metrics/internal/server/config"
+	graphiteserver "github.com/Ko4etov/go-metrics/internal/server/graphite_server"
+	grpcserver "github.com/Ko4etov/go-metrics/internal/server/grpc_server"
+	"github.com/Ko4etov/go-metrics/internal/server/repository/history"
+	"github.com/Ko4etov/go-metrics/internal/server/repository/idempotency"
 	"github.com/Ko4etov/go-metrics/internal/server/repository/storage"
 	"github.com/Ko4etov/go-metrics/internal/server/router"
+	"github.com/Ko4etov/go-metrics/internal/server/service/alerting"
 	"github.com/Ko4etov/go-metrics/internal/server/service/audit"
+	"github.com/Ko4etov/go-metrics/internal/server/service/health"
 	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
 	"github.com/Ko4etov/go-metrics/internal/server/service/profiler"
+	"github.com/Ko4etov/go-metrics/internal/server/service/selfmetrics"
+	statsdserver "github.com/Ko4etov/go-metrics/internal/server/statsd_server"
 )
 
 // Server представляет HTTP-сервер для системы метрик.
@@ -27,11 +42,22 @@ func New(config *config.ServerConfig) *Server {
 	}
 }
 
-// Run запускает HTTP-сервер.
-func (s *Server) Run() {
+// Run запускает HTTP-сервер и работает до завершения ctx.
+//
+// После завершения ctx сервер перестает считаться готовым и еще ShutdownDrain
+// принимает запросы, чтобы балансировщик успел исключить его из ротации. Затем он
+// дожидается обработки принятых запросов, останавливает приемники метрик со сбросом
+// накопленных значений, доставляет события аудита и сохраняет хранилище. На ожидание
+// отводится ShutdownTimeout из конфигурации; по его истечении оставшиеся соединения
+// закрываются принудительно.
+func (s *Server) Run(ctx context.Context) error {
+	if s.config.ConnectionPool != nil {
+		defer s.config.ConnectionPool.Close()
+	}
+
 	if s.config.ProfilingEnable {
 		if err := os.MkdirAll(s.config.ProfilingDir, 0755); err != nil {
-			logger.Logger.Fatalf("failed to create profile directory: %v", err)
+			return fmt.Errorf("failed to create profile directory: %w", err)
 		}
 
 		profiler.StartProfiling(s.config.ProfileServerAddress)
@@ -39,25 +65,58 @@ func (s *Server) Run() {
 		profiler.SaveProfiling(s.config.ProfilingDir, 30*time.Second)
 	}
 
+	metricsHistory := history.New(&history.MetricsHistoryConfig{
+		Retention:      s.config.HistoryRetention,
+		MaxPoints:      s.config.HistoryMaxPoints,
+		FilePath:       historyFilePath(s.config.FileStorageMetricsPath),
+		RestoreHistory: s.config.RestoreMetrics,
+		ConnectionPool: s.config.ConnectionPool,
+	})
+	metricsHistory.StartRetention()
+	defer metricsHistory.StopRetention()
+
+	appliedBatches := idempotency.New(&idempotency.AppliedBatchesConfig{
+		TTL:            s.config.IdempotencyTTL,
+		ConnectionPool: s.config.ConnectionPool,
+	})
+	appliedBatches.StartCleanup()
+	defer appliedBatches.StopCleanup()
+
+	var selfMetrics *selfmetrics.Registry
+	if s.config.SelfMetricsInterval > 0 {
+		selfMetrics = selfmetrics.New()
+	}
+
 	storageConfig := &storage.MetricsStorageConfig{
 		RestoreMetrics:         s.config.RestoreMetrics,
 		StoreMetricsInterval:   s.config.StoreMetricsInterval,
 		FileStorageMetricsPath: s.config.FileStorageMetricsPath,
 		ConnectionPool:         s.config.ConnectionPool,
+		History:                metricsHistory,
+		Idempotency:            appliedBatches,
+		SelfMetrics:            selfMetrics,
 	}
 
 	metricsStorage := storage.New(storageConfig)
 
+	if selfMetrics != nil {
+		selfMetrics.GaugeFunc(selfmetrics.StorageSeries, func() float64 {
+			return float64(len(metricsStorage.Metrics()))
+		})
+		selfMetrics.Start(metricsStorage, s.config.SelfMetricsInterval)
+		defer selfMetrics.Stop()
+	}
+
 	var auditSvc *audit.AuditService
 
 	if s.config.AuditFile != "" || s.config.AuditURL != "" {
 		auditSvc = audit.NewAuditService()
+		auditSvc.SetSelfMetrics(selfMetrics)
 
 		if s.config.AuditFile != "" {
 			fileAuditor, err := audit.NewFileAuditor(s.config.AuditFile)
 			if err != nil {
-				fmt.Printf("Failed to create file auditor: %v\n", err)
-				os.Exit(1)
+				return fmt.Errorf("failed to create file auditor: %w", err)
 			}
 			defer fileAuditor.Close()
 			auditSvc.Subscribe(fileAuditor)
@@ -69,21 +128,212 @@ func (s *Server) Run() {
 		}
 	}
 
+	var alertEngine *alerting.Engine
+
+	if s.config.AlertRules != nil {
+		alertEngine = alerting.NewEngine(
+			metricsStorage,
+			s.config.AlertRules,
+			s.config.AlertInterval,
+		)
+		alertEngine.Start()
+		defer alertEngine.Stop()
+	}
+
+	healthChecker := health.NewChecker(0)
+	healthChecker.Add(health.RestoreCheck(metricsStorage.RestoreStatus))
+	if s.config.ConnectionPool != nil {
+		healthChecker.Add(health.DatabaseCheck(s.config.ConnectionPool))
+	}
+	if s.config.FileStorageMetricsPath != "" {
+		healthChecker.Add(health.FileStorageCheck(s.config.FileStorageMetricsPath))
+		if s.config.StoreMetricsInterval > 0 {
+			healthChecker.Add(health.PeriodicSaveCheck(metricsStorage.LastSave,
+				time.Duration(s.config.StoreMetricsInterval)*time.Second))
+		}
+	}
+	if auditSvc != nil {
+		healthChecker.Add(health.AuditCheck(auditSvc))
+	}
+
 	routerConfig := &router.RouteConfig{
-		Storage:  metricsStorage,
-		Pgx:      s.config.ConnectionPool,
-		HashKey:  s.config.HashKey,
-		AuditSvc: auditSvc,
+		Storage:          metricsStorage,
+		Pgx:              s.config.ConnectionPool,
+		HashKey:          s.config.HashKey,
+		HashMode:         s.config.HashMode,
+		CryptoKey:        s.config.CryptoKey,
+		CryptoMode:       s.config.CryptoMode,
+		TrustedSubnet:    s.config.TrustedSubnet,
+		AuditSvc:         auditSvc,
+		History:          metricsHistory,
+		Alerting:         alertEngine,
+		LogSampleRate:    s.config.LogSampleRate,
+		LogSlowThreshold: s.config.LogSlowThreshold,
+		SelfMetrics:      selfMetrics,
+		Health:           healthChecker,
 	}
 	serverRouter := router.New(routerConfig)
 
 	if s.config.StoreMetricsInterval > 0 {
 		metricsStorage.StartPeriodicSave()
-		defer metricsStorage.StopPeriodicSave()
+	}
+	// Хранилище сохраняется в файл при остановке и без периодического сохранения.
+	defer metricsStorage.StopPeriodicSave()
+
+	// Остановка приемников метрик; defer нужен для выхода по ошибке запуска,
+	// повторная остановка ничего не делает.
+	var stopIngest []func()
+
+	if s.config.GRPCAddress != "" {
+		grpcServer := grpcserver.New(&grpcserver.MetricsServerConfig{
+			Storage:       metricsStorage,
+			HashKey:       s.config.HashKey,
+			HashMode:      s.config.HashMode,
+			TrustedSubnet: s.config.TrustedSubnet,
+			SelfMetrics:   selfMetrics,
+		})
+		defer stopGRPC(grpcServer, s.config.ShutdownTimeout)
+		stopIngest = append(stopIngest, func() { stopGRPC(grpcServer, s.config.ShutdownTimeout) })
+
+		listener, err := net.Listen("tcp", s.config.GRPCAddress)
+		if err != nil {
+			return fmt.Errorf("failed to listen gRPC address: %w", err)
+		}
+
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				logger.Logger.Errorf("gRPC server error: %v", err)
+			}
+		}()
+	}
+
+	if s.config.StatsDAddress != "" {
+		statsDServer := statsdserver.New(&statsdserver.StatsDServerConfig{
+			Storage:       metricsStorage,
+			AuditSvc:      auditSvc,
+			TrustedSubnet: s.config.TrustedSubnet,
+			FlushInterval: s.config.StatsDFlushInterval,
+		})
+		defer statsDServer.Stop()
+		stopIngest = append(stopIngest, statsDServer.Stop)
+
+		conn, err := net.ListenPacket("udp", s.config.StatsDAddress)
+		if err != nil {
+			return fmt.Errorf("failed to listen StatsD address: %w", err)
+		}
+
+		go func() {
+			if err := statsDServer.Serve(conn); err != nil {
+				logger.Logger.Errorf("StatsD server error: %v", err)
+			}
+		}()
+	}
+
+	if s.config.GraphiteAddress != "" {
+		graphiteServer := graphiteserver.New(&graphiteserver.GraphiteServerConfig{
+			Storage:        metricsStorage,
+			Mapping:        s.config.GraphiteMapping,
+			TrustedSubnet:  s.config.TrustedSubnet,
+			MaxConnections: s.config.GraphiteMaxConnections,
+		})
+		defer graphiteServer.Stop()
+		stopIngest = append(stopIngest, graphiteServer.Stop)
+
+		listener, err := net.Listen("tcp", s.config.GraphiteAddress)
+		if err != nil {
+			return fmt.Errorf("failed to listen Graphite address: %w", err)
+		}
+
+		go func() {
+			if err := graphiteServer.Serve(listener); err != nil {
+				logger.Logger.Errorf("Graphite server error: %v", err)
+			}
+		}()
+	}
+
+	// Контекст запросов отменяется в начале остановки, чтобы завершились
+	// бесконечные потоки событий, которых иначе пришлось бы ждать до таймаута.
+	requestsCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+
+	httpServer := &http.Server{
+		Addr:    s.config.ServerAddress,
+		Handler: serverRouter,
+		BaseContext: func(net.Listener) context.Context {
+			return requestsCtx
+		},
+	}
+	httpServer.RegisterOnShutdown(cancelRequests)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("HTTP server error: %w", err)
+	case <-ctx.Done():
+	}
+
+	logger.Logger.Info("shutting down server")
+	healthChecker.SetShuttingDown()
+
+	if s.config.ShutdownDrain > 0 {
+		logger.Logger.Infof("draining for %s before shutdown", s.config.ShutdownDrain)
+		time.Sleep(s.config.ShutdownDrain)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		logger.Logger.Errorf("failed to finish in-flight HTTP requests: %v", err)
+		httpServer.Close()
 	}
 
-	err := http.ListenAndServe(s.config.ServerAddress, serverRouter)
-	if err != nil {
-		panic(err)
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		logger.Logger.Errorf("HTTP server error: %v", err)
 	}
-}
\ No newline at end of file
+
+	// Приемники останавливаются до доставки аудита: при остановке они сбрасывают
+	// накопленные метрики, и события аудита этого сброса тоже должны быть доставлены.
+	for _, stop := range stopIngest {
+		stop()
+	}
+
+	if auditSvc != nil {
+		if err := auditSvc.Flush(shutdownCtx); err != nil {
+			logger.Logger.Errorf("failed to deliver pending audit events: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// stopGRPC дожидается завершения активных вызовов gRPC-сервера не дольше timeout,
+// после чего останавливает его принудительно.
+func stopGRPC(server *grpc.Server, timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		server.Stop()
+	}
+}
+
+// historyFilePath возвращает путь к файлу истории рядом с файлом хранения метрик.
+func historyFilePath(metricsPath string) string {
+	if metricsPath == "" {
+		return ""
+	}
+
+	ext := filepath.Ext(metricsPath)
+
+	return strings.TrimSuffix(metricsPath, ext) + ".history" + ext
+}
@@ -0,0 +1,283 @@
+package alerting
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/interfaces"
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+)
+
+// Состояния оповещения.
+const (
+	StatePending  = "pending"  // условие выполняется, но время удержания еще не истекло
+	StateFiring   = "firing"   // оповещение сработало
+	StateResolved = "resolved" // условие сработавшего оповещения перестало выполняться
+)
+
+// Alert представляет состояние оповещения по правилу.
+type Alert struct {
+	Rule        string     `json:"rule"`                  // имя правила
+	MetricID    string     `json:"metric"`                // имя метрики
+	Severity    string     `json:"severity"`              // уровень важности
+	State       string     `json:"state"`                 // состояние оповещения
+	Value       float64    `json:"value"`                 // последнее значение метрики
+	Comparison  string     `json:"comparison"`            // оператор сравнения
+	Threshold   float64    `json:"threshold"`             // пороговое значение
+	Description string     `json:"description,omitempty"` // описание правила
+	ActiveAt    time.Time  `json:"active_at"`             // время начала выполнения условия
+	FiredAt     *time.Time `json:"fired_at,omitempty"`    // время срабатывания
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"` // время разрешения
+}
+
+// Engine периодически проверяет правила оповещений по хранилищу метрик
+// и уведомляет подписчиков о срабатываниях и разрешениях.
+type Engine struct {
+	storage   interfaces.Storage // хранилище метрик
+	rules     []Rule             // правила оповещений
+	interval  time.Duration      // интервал проверки правил
+	notifiers []Notifier         // получатели уведомлений
+	alerts    map[string]*Alert  // активные оповещения по имени правила
+	mu        sync.RWMutex       // мьютекс для безопасного доступа
+	queue     chan Alert         // очередь уведомлений для отправки по порядку
+	stopped   bool               // флаг остановки движка
+	ticker    *time.Ticker       // таймер для периодической проверки
+	done      chan bool          // канал для остановки таймера
+	wg        sync.WaitGroup     // группа ожидания отправки уведомлений
+	ctx       context.Context    // контекст отправки уведомлений, отменяется при остановке
+	cancel    context.CancelFunc // отмена отправки уведомлений
+
+	stopTimeout time.Duration // время ожидания отправки оставшихся уведомлений при остановке
+}
+
+// notificationQueueSize - размер очереди неотправленных уведомлений.
+const notificationQueueSize = 100
+
+// defaultStopTimeout - время ожидания отправки оставшихся уведомлений при остановке,
+// после которого отправка отменяется.
+const defaultStopTimeout = 5 * time.Second
+
+// NewEngine создает движок оповещений с вебхуками из набора правил.
+func NewEngine(storage interfaces.Storage, ruleSet *RuleSet, interval time.Duration) *Engine {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	engine := &Engine{
+		storage:     storage,
+		rules:       ruleSet.Rules,
+		interval:    interval,
+		alerts:      make(map[string]*Alert),
+		queue:       make(chan Alert, notificationQueueSize),
+		done:        make(chan bool),
+		ctx:         ctx,
+		cancel:      cancel,
+		stopTimeout: defaultStopTimeout,
+	}
+
+	for _, url := range ruleSet.Webhooks {
+		engine.Subscribe(NewWebhookNotifier(url))
+	}
+
+	engine.wg.Add(1)
+	go engine.deliver()
+
+	return engine
+}
+
+// Subscribe добавляет получателя уведомлений.
+func (e *Engine) Subscribe(notifier Notifier) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.notifiers = append(e.notifiers, notifier)
+}
+
+// Start запускает периодическую проверку правил.
+func (e *Engine) Start() {
+	e.ticker = time.NewTicker(e.interval)
+
+	go func() {
+		for {
+			select {
+			case <-e.ticker.C:
+				e.Evaluate(time.Now().UTC())
+			case <-e.done:
+				return
+			}
+		}
+	}()
+}
+
+// Stop останавливает проверку правил и дожидается отправки уведомлений.
+// Если уведомления не отправлены за stopTimeout, отправка отменяется,
+// а оставшиеся в очереди уведомления отбрасываются.
+func (e *Engine) Stop() {
+	if e.ticker != nil {
+		e.ticker.Stop()
+		close(e.done)
+	}
+
+	e.mu.Lock()
+	if !e.stopped {
+		e.stopped = true
+		close(e.queue)
+	}
+	e.mu.Unlock()
+
+	delivered := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(delivered)
+	}()
+
+	select {
+	case <-delivered:
+	case <-time.After(e.stopTimeout):
+		logger.Logger.Warnf("[alerting] notifications not delivered in %v, delivery cancelled", e.stopTimeout)
+		e.cancel()
+		<-delivered
+	}
+
+	e.cancel()
+}
+
+// Evaluate проверяет все правила на момент now и обновляет состояния оповещений.
+func (e *Engine) Evaluate(now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.stopped {
+		return
+	}
+
+	for i := range e.rules {
+		rule := &e.rules[i]
+		alert, active := e.alerts[rule.Name]
+
+		value, ok := e.metricValue(rule)
+		if !ok || !rule.matches(value) {
+			if active && alert.State == StateFiring {
+				resolvedAt := now
+				alert.State = StateResolved
+				alert.ResolvedAt = &resolvedAt
+				if ok {
+					alert.Value = value
+				}
+				e.dispatch(*alert)
+			}
+			delete(e.alerts, rule.Name)
+			continue
+		}
+
+		if !active {
+			alert = &Alert{
+				Rule:        rule.Name,
+				MetricID:    rule.MetricID,
+				Severity:    rule.Severity,
+				State:       StatePending,
+				Comparison:  rule.Comparison,
+				Threshold:   rule.Threshold,
+				Description: rule.Description,
+				ActiveAt:    now,
+			}
+			e.alerts[rule.Name] = alert
+		}
+
+		alert.Value = value
+
+		if alert.State == StatePending && now.Sub(alert.ActiveAt) >= time.Duration(rule.For) {
+			firedAt := now
+			alert.State = StateFiring
+			alert.FiredAt = &firedAt
+			e.dispatch(*alert)
+		}
+	}
+}
+
+// metricValue возвращает текущее значение метрики правила.
+func (e *Engine) metricValue(rule *Rule) (float64, bool) {
+	metric, ok := e.storage.Metric(rule.MetricID)
+	if !ok {
+		return 0, false
+	}
+
+	if rule.MetricType != "" && metric.MType != rule.MetricType {
+		return 0, false
+	}
+
+	switch metric.MType {
+	case models.Gauge:
+		if metric.Value != nil {
+			return *metric.Value, true
+		}
+	case models.Counter:
+		if metric.Delta != nil {
+			return float64(*metric.Delta), true
+		}
+	}
+
+	return 0, false
+}
+
+// dispatch ставит уведомление в очередь отправки.
+// Если очередь переполнена, уведомление отбрасывается.
+func (e *Engine) dispatch(alert Alert) {
+	select {
+	case e.queue <- alert:
+	default:
+		logger.Logger.Warnf("[alerting] notification queue is full, alert %s (%s) dropped", alert.Rule, alert.State)
+	}
+}
+
+// deliver отправляет уведомления из очереди всем получателям в порядке их появления.
+func (e *Engine) deliver() {
+	defer e.wg.Done()
+
+	for alert := range e.queue {
+		if e.ctx.Err() != nil {
+			logger.Logger.Warnf("[alerting] engine stopped, alert %s (%s) dropped", alert.Rule, alert.State)
+			continue
+		}
+
+		e.mu.RLock()
+		notifiers := make([]Notifier, len(e.notifiers))
+		copy(notifiers, e.notifiers)
+		e.mu.RUnlock()
+
+		var wg sync.WaitGroup
+
+		for _, notifier := range notifiers {
+			wg.Add(1)
+			go func(n Notifier) {
+				defer wg.Done()
+
+				ctx, cancel := context.WithTimeout(e.ctx, 30*time.Second)
+				defer cancel()
+
+				if err := n.Notify(ctx, alert); err != nil {
+					logger.Logger.Errorf("[alerting] %s failed to deliver alert %s: %v", n.Name(), alert.Rule, err)
+				}
+			}(notifier)
+		}
+
+		wg.Wait()
+	}
+}
+
+// Alerts возвращает активные оповещения, упорядоченные по имени правила.
+func (e *Engine) Alerts() []Alert {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	alerts := make([]Alert, 0, len(e.alerts))
+	for _, alert := range e.alerts {
+		alerts = append(alerts, *alert)
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].Rule < alerts[j].Rule
+	})
+
+	return alerts
+}
@@ -0,0 +1,232 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/repository/storage"
+)
+
+type notifierMock struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func (m *notifierMock) Notify(ctx context.Context, alert Alert) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.alerts = append(m.alerts, alert)
+	return nil
+}
+
+func (m *notifierMock) Name() string {
+	return "notifierMock"
+}
+
+func (m *notifierMock) states() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	states := make([]string, 0, len(m.alerts))
+	for _, alert := range m.alerts {
+		states = append(states, alert.State)
+	}
+	return states
+}
+
+func TestEngine_StateTransitions(t *testing.T) {
+	store := storage.New(&storage.MetricsStorageConfig{})
+
+	ruleSet := &RuleSet{Rules: []Rule{{
+		Name:       "HighHeap",
+		MetricID:   "HeapAlloc",
+		Comparison: OpGreater,
+		Threshold:  100,
+		For:        Duration(time.Minute),
+	}}}
+	if err := ruleSet.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	engine := NewEngine(store, ruleSet, time.Second)
+	notifier := &notifierMock{}
+	engine.Subscribe(notifier)
+
+	setHeap := func(value float64) {
+		store.UpdateMetric(models.Metrics{ID: "HeapAlloc", MType: models.Gauge, Value: &value})
+	}
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	setHeap(150)
+	engine.Evaluate(now)
+
+	alerts := engine.Alerts()
+	if len(alerts) != 1 || alerts[0].State != StatePending {
+		t.Fatalf("Expected pending alert, got %+v", alerts)
+	}
+
+	engine.Evaluate(now.Add(30 * time.Second))
+	if alerts := engine.Alerts(); alerts[0].State != StatePending {
+		t.Errorf("Expected alert to stay pending before for duration, got %s", alerts[0].State)
+	}
+
+	engine.Evaluate(now.Add(time.Minute))
+	if alerts := engine.Alerts(); alerts[0].State != StateFiring {
+		t.Errorf("Expected firing alert, got %s", alerts[0].State)
+	}
+
+	setHeap(50)
+	engine.Evaluate(now.Add(2 * time.Minute))
+	engine.Stop()
+
+	if alerts := engine.Alerts(); len(alerts) != 0 {
+		t.Errorf("Expected no active alerts after resolve, got %+v", alerts)
+	}
+
+	states := notifier.states()
+	if len(states) != 2 || states[0] != StateFiring || states[1] != StateResolved {
+		t.Errorf("Expected firing and resolved notifications, got %v", states)
+	}
+}
+
+func TestEngine_PendingCancelledWithoutNotification(t *testing.T) {
+	store := storage.New(&storage.MetricsStorageConfig{})
+
+	ruleSet := &RuleSet{Rules: []Rule{{
+		Name:       "ManyPolls",
+		MetricID:   "PollCount",
+		Comparison: OpGreaterEqual,
+		Threshold:  10,
+		For:        Duration(time.Minute),
+		Severity:   SeverityCritical,
+	}}}
+
+	engine := NewEngine(store, ruleSet, time.Second)
+	notifier := &notifierMock{}
+	engine.Subscribe(notifier)
+
+	delta := int64(10)
+	store.UpdateMetric(models.Metrics{ID: "PollCount", MType: models.Counter, Delta: &delta})
+
+	now := time.Now()
+	engine.Evaluate(now)
+
+	store.ResetAll()
+	engine.Evaluate(now.Add(time.Second))
+	engine.Stop()
+
+	if len(engine.Alerts()) != 0 {
+		t.Error("Expected pending alert to be dropped when metric disappears")
+	}
+
+	if states := notifier.states(); len(states) != 0 {
+		t.Errorf("Expected no notifications, got %v", states)
+	}
+}
+
+func TestEngine_StopCancelsPendingDelivery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	store := storage.New(&storage.MetricsStorageConfig{})
+	value := 150.0
+	store.UpdateMetric(models.Metrics{ID: "HeapAlloc", MType: models.Gauge, Value: &value})
+
+	ruleSet := &RuleSet{
+		Rules:    []Rule{{Name: "HighHeap", MetricID: "HeapAlloc", Comparison: OpGreater, Threshold: 100}},
+		Webhooks: []string{server.URL},
+	}
+
+	engine := NewEngine(store, ruleSet, time.Second)
+	engine.stopTimeout = 100 * time.Millisecond
+	engine.Evaluate(time.Now())
+
+	start := time.Now()
+	engine.Stop()
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Stop waited %v for webhook retries", elapsed)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	received := make(chan Alert, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert Alert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			t.Errorf("Failed to decode alert: %v", err)
+		}
+		received <- alert
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.URL)
+
+	err := notifier.Notify(context.Background(), Alert{Rule: "HighHeap", State: StateFiring})
+	if err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	alert := <-received
+	if alert.Rule != "HighHeap" || alert.State != StateFiring {
+		t.Errorf("Unexpected alert received: %+v", alert)
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	dir := t.TempDir()
+
+	yamlPath := filepath.Join(dir, "rules.yaml")
+	os.WriteFile(yamlPath, []byte(`
+webhooks:
+  - http://localhost:9000/alerts
+rules:
+  - name: HighCPU
+    metric: CPUutilization1
+    comparison: ">"
+    threshold: 90
+    for: 5m
+    severity: critical
+`), 0644)
+
+	ruleSet, err := LoadRules(yamlPath)
+	if err != nil {
+		t.Fatalf("LoadRules(yaml) failed: %v", err)
+	}
+
+	if len(ruleSet.Rules) != 1 || time.Duration(ruleSet.Rules[0].For) != 5*time.Minute {
+		t.Errorf("Unexpected YAML rules: %+v", ruleSet.Rules)
+	}
+
+	jsonPath := filepath.Join(dir, "rules.json")
+	os.WriteFile(jsonPath, []byte(`{"rules":[{"name":"LowMemory","metric":"FreeMemory","comparison":"<","threshold":1000,"for":"30s"}]}`), 0644)
+
+	ruleSet, err = LoadRules(jsonPath)
+	if err != nil {
+		t.Fatalf("LoadRules(json) failed: %v", err)
+	}
+
+	if ruleSet.Rules[0].Severity != SeverityWarning || time.Duration(ruleSet.Rules[0].For) != 30*time.Second {
+		t.Errorf("Unexpected JSON rules: %+v", ruleSet.Rules)
+	}
+
+	invalidPath := filepath.Join(dir, "invalid.json")
+	os.WriteFile(invalidPath, []byte(`{"rules":[{"name":"Bad","metric":"Alloc","comparison":"~","threshold":1}]}`), 0644)
+
+	if _, err := LoadRules(invalidPath); err == nil {
+		t.Error("Expected error for invalid comparison")
+	}
+}
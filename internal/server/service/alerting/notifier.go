@@ -0,0 +1,70 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	retriableagent "github.com/Ko4etov/go-metrics/internal/service/retriable_agent"
+)
+
+// Notifier определяет интерфейс доставки уведомлений об оповещениях.
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+	Name() string // имя получателя
+}
+
+// WebhookNotifier доставляет уведомления об оповещениях на вебхук по HTTP.
+type WebhookNotifier struct {
+	url           string
+	Client        *resty.Client
+	RetiebleAgent *retriableagent.RetriableAgent
+}
+
+// NewWebhookNotifier создает новый получатель уведомлений для вебхука.
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	client := resty.New().
+		SetTimeout(5 * time.Second).
+		SetRetryCount(2)
+
+	retriableAgent := retriableagent.New(3, []time.Duration{1 * time.Second, 3 * time.Second, 5 * time.Second})
+
+	return &WebhookNotifier{
+		url:           url,
+		Client:        client,
+		RetiebleAgent: retriableAgent,
+	}
+}
+
+// Notify отправляет уведомление об оповещении на вебхук.
+func (wn *WebhookNotifier) Notify(ctx context.Context, alert Alert) error {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %w", err)
+	}
+
+	return wn.RetiebleAgent.SendContext(ctx, func() error {
+		resp, err := wn.Client.R().
+			SetContext(ctx).
+			SetBody(data).
+			SetHeader("Content-Type", "application/json").
+			Post(wn.url)
+		if err != nil {
+			return fmt.Errorf("failed to send alert: %w", err)
+		}
+
+		if resp.IsError() {
+			return fmt.Errorf("server error: %s", resp.Status())
+		}
+
+		return nil
+	})
+}
+
+// Name возвращает имя получателя уведомлений.
+func (wn *WebhookNotifier) Name() string {
+	return fmt.Sprintf("WebhookNotifier(%s)", wn.url)
+}
@@ -0,0 +1,192 @@
+// Package alerting реализует правила оповещений по значениям метрик
+// и доставку уведомлений о срабатываниях во внешние системы.
+package alerting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+)
+
+// Операторы сравнения значения метрики с порогом.
+const (
+	OpGreater      = ">"
+	OpGreaterEqual = ">="
+	OpLess         = "<"
+	OpLessEqual    = "<="
+	OpEqual        = "=="
+	OpNotEqual     = "!="
+)
+
+// Уровни важности оповещений.
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Duration - длительность, задаваемая в файле правил строкой вида "30s" или "5m".
+type Duration time.Duration
+
+// UnmarshalJSON разбирает длительность из JSON-строки.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("duration must be a string: %w", err)
+	}
+
+	return d.parse(raw)
+}
+
+// UnmarshalYAML разбирает длительность из YAML-строки.
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	return d.parse(value.Value)
+}
+
+// MarshalJSON кодирует длительность в JSON-строку.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// parse разбирает длительность из строки.
+func (d *Duration) parse(raw string) error {
+	if raw == "" {
+		*d = 0
+		return nil
+	}
+
+	parsed, err := time.ParseDuration(raw)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", raw, err)
+	}
+
+	*d = Duration(parsed)
+
+	return nil
+}
+
+// Rule описывает правило оповещения.
+type Rule struct {
+	Name        string   `json:"name" yaml:"name"`                                   // уникальное имя правила
+	MetricID    string   `json:"metric" yaml:"metric"`                               // имя метрики
+	MetricType  string   `json:"type,omitempty" yaml:"type,omitempty"`               // тип метрики (опционально)
+	Comparison  string   `json:"comparison" yaml:"comparison"`                       // оператор сравнения
+	Threshold   float64  `json:"threshold" yaml:"threshold"`                         // пороговое значение
+	For         Duration `json:"for,omitempty" yaml:"for,omitempty"`                 // время удержания условия до срабатывания
+	Severity    string   `json:"severity,omitempty" yaml:"severity,omitempty"`       // уровень важности
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"` // описание для уведомлений
+}
+
+// RuleSet содержит правила оповещений и адреса для доставки уведомлений.
+type RuleSet struct {
+	Webhooks []string `json:"webhooks" yaml:"webhooks"` // URL вебхуков для уведомлений
+	Rules    []Rule   `json:"rules" yaml:"rules"`       // правила оповещений
+}
+
+// LoadRules загружает правила оповещений из YAML- или JSON-файла.
+// Формат определяется по расширению файла: .yaml и .yml - YAML, остальные - JSON.
+func LoadRules(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+
+	var ruleSet RuleSet
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &ruleSet)
+	default:
+		err = json.Unmarshal(data, &ruleSet)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rules file %s: %w", path, err)
+	}
+
+	if err := ruleSet.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid rules file %s: %w", path, err)
+	}
+
+	return &ruleSet, nil
+}
+
+// Validate проверяет корректность правил и заполняет значения по умолчанию.
+func (rs *RuleSet) Validate() error {
+	names := make(map[string]bool, len(rs.Rules))
+
+	for i := range rs.Rules {
+		rule := &rs.Rules[i]
+
+		if rule.Name == "" {
+			return fmt.Errorf("rule %d: name is required", i+1)
+		}
+
+		if names[rule.Name] {
+			return fmt.Errorf("rule %s: duplicate name", rule.Name)
+		}
+		names[rule.Name] = true
+
+		if rule.MetricID == "" {
+			return fmt.Errorf("rule %s: metric is required", rule.Name)
+		}
+
+		if rule.MetricType != "" && rule.MetricType != models.Gauge && rule.MetricType != models.Counter {
+			return fmt.Errorf("rule %s: invalid metric type %q", rule.Name, rule.MetricType)
+		}
+
+		switch rule.Comparison {
+		case OpGreater, OpGreaterEqual, OpLess, OpLessEqual, OpEqual, OpNotEqual:
+		default:
+			return fmt.Errorf("rule %s: invalid comparison %q", rule.Name, rule.Comparison)
+		}
+
+		if rule.For < 0 {
+			return fmt.Errorf("rule %s: for must not be negative", rule.Name)
+		}
+
+		switch rule.Severity {
+		case "":
+			rule.Severity = SeverityWarning
+		case SeverityInfo, SeverityWarning, SeverityCritical:
+		default:
+			return fmt.Errorf("rule %s: invalid severity %q", rule.Name, rule.Severity)
+		}
+	}
+
+	for _, webhook := range rs.Webhooks {
+		if webhook == "" {
+			return errors.New("webhook URL must not be empty")
+		}
+	}
+
+	return nil
+}
+
+// matches проверяет, выполняется ли условие правила для значения.
+func (r *Rule) matches(value float64) bool {
+	switch r.Comparison {
+	case OpGreater:
+		return value > r.Threshold
+	case OpGreaterEqual:
+		return value >= r.Threshold
+	case OpLess:
+		return value < r.Threshold
+	case OpLessEqual:
+		return value <= r.Threshold
+	case OpEqual:
+		return value == r.Threshold
+	case OpNotEqual:
+		return value != r.Threshold
+	}
+
+	return false
+}
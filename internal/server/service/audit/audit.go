@@ -10,6 +10,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+	"github.com/Ko4etov/go-metrics/internal/server/service/selfmetrics"
 	retriableagent "github.com/Ko4etov/go-metrics/internal/service/retriable_agent"
 	"github.com/go-resty/resty/v2"
 )
@@ -32,17 +35,34 @@ type Subscriber interface {
 	Name() string // имя подписчика
 }
 
+// SubscriberState описывает результаты последних доставок событий подписчику.
+type SubscriberState struct {
+	Name          string    `json:"name"`                      // имя подписчика
+	LastSuccessAt time.Time `json:"last_success_at,omitempty"` // время последней успешной доставки
+	LastErrorAt   time.Time `json:"last_error_at,omitempty"`   // время последней ошибки доставки
+	LastError     string    `json:"last_error,omitempty"`      // текст последней ошибки доставки
+}
+
+// Healthy сообщает, что последняя доставка подписчику была успешной или доставок еще не было.
+func (s SubscriberState) Healthy() bool {
+	return s.LastErrorAt.IsZero() || s.LastSuccessAt.After(s.LastErrorAt)
+}
+
 // AuditService управляет подписчиками и уведомляет их о событиях.
 type AuditService struct {
 	subscribers []Subscriber
 	mu          sync.RWMutex
 	enabled     bool
+	selfMetrics *selfmetrics.Registry       // собственные метрики сервера (опционально)
+	states      map[string]*SubscriberState // результаты доставок по подписчикам
+	inflight    sync.WaitGroup              // асинхронные уведомления, еще не доставленные подписчикам
 }
 
 // NewAuditService создает новый сервис аудита.
 func NewAuditService() *AuditService {
 	return &AuditService{
 		subscribers: make([]Subscriber, 0),
+		states:      make(map[string]*SubscriberState),
 	}
 }
 
@@ -51,9 +71,48 @@ func (as *AuditService) Subscribe(subscriber Subscriber) {
 	as.mu.Lock()
 	defer as.mu.Unlock()
 	as.subscribers = append(as.subscribers, subscriber)
+	as.states[subscriber.Name()] = &SubscriberState{Name: subscriber.Name()}
 	as.enabled = true
 }
 
+// SubscriberStates возвращает результаты последних доставок событий подписчикам.
+func (as *AuditService) SubscriberStates() []SubscriberState {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	states := make([]SubscriberState, 0, len(as.subscribers))
+	for _, sub := range as.subscribers {
+		states = append(states, *as.states[sub.Name()])
+	}
+
+	return states
+}
+
+// recordDelivery запоминает результат доставки события подписчику.
+func (as *AuditService) recordDelivery(name string, err error) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	state, ok := as.states[name]
+	if !ok {
+		return
+	}
+
+	if err != nil {
+		state.LastErrorAt = time.Now()
+		state.LastError = err.Error()
+	} else {
+		state.LastSuccessAt = time.Now()
+	}
+}
+
+// SetSelfMetrics подключает учет ошибок доставки событий в собственных метриках сервера.
+func (as *AuditService) SetSelfMetrics(registry *selfmetrics.Registry) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.selfMetrics = registry
+}
+
 // Notify уведомляет всех подписчиков о событии аудита.
 func (as *AuditService) Notify(ctx context.Context, event AuditEvent) error {
 	if !as.enabled {
@@ -63,6 +122,7 @@ func (as *AuditService) Notify(ctx context.Context, event AuditEvent) error {
 	as.mu.RLock()
 	subscribers := make([]Subscriber, len(as.subscribers))
 	copy(subscribers, as.subscribers)
+	selfMetrics := as.selfMetrics
 	as.mu.RUnlock()
 
 	var wg sync.WaitGroup
@@ -72,7 +132,10 @@ func (as *AuditService) Notify(ctx context.Context, event AuditEvent) error {
 		wg.Add(1)
 		go func(s Subscriber) {
 			defer wg.Done()
-			if err := s.Audit(ctx, event); err != nil {
+			err := s.Audit(ctx, event)
+			as.recordDelivery(s.Name(), err)
+			if err != nil {
+				selfMetrics.Add(selfmetrics.AuditFailuresTotal, models.Labels{"subscriber": s.Name()}, 1)
 				errCh <- fmt.Errorf("subscriber %s: %w", s.Name(), err)
 			}
 		}(sub)
@@ -92,6 +155,43 @@ func (as *AuditService) Notify(ctx context.Context, event AuditEvent) error {
 	return nil
 }
 
+// NotifyAsync уведомляет подписчиков о событии в отдельной горутине, ограничивая
+// доставку временем timeout. Незавершенных уведомлений можно дождаться методом Flush.
+func (as *AuditService) NotifyAsync(event AuditEvent, timeout time.Duration) {
+	if !as.enabled {
+		return
+	}
+
+	as.inflight.Add(1)
+	go func() {
+		defer as.inflight.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		if err := as.Notify(ctx, event); err != nil {
+			logger.Logger.Infof("[audit] Failed to send audit event: %v\n", err)
+		}
+	}()
+}
+
+// Flush дожидается доставки асинхронных уведомлений. Если ctx завершается раньше,
+// возвращается его ошибка.
+func (as *AuditService) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		as.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // FileAuditor реализует аудит в файл.
 type FileAuditor struct {
 	filePath string
@@ -142,8 +242,16 @@ func (fa *FileAuditor) Name() string {
 	return fmt.Sprintf("FileAuditor(%s)", fa.filePath)
 }
 
-// Close закрывает файловый аудитор.
+// Close сбрасывает записанные события на диск и закрывает файловый аудитор.
 func (fa *FileAuditor) Close() error {
+	fa.mu.Lock()
+	defer fa.mu.Unlock()
+
+	if err := fa.file.Sync(); err != nil {
+		fa.file.Close()
+		return fmt.Errorf("failed to sync audit file: %w", err)
+	}
+
 	return fa.file.Close()
 }
 
@@ -0,0 +1,61 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowSubscriber доставляет события с задержкой.
+type slowSubscriber struct {
+	delay     time.Duration
+	delivered atomic.Int32
+}
+
+func (s *slowSubscriber) Audit(ctx context.Context, event AuditEvent) error {
+	select {
+	case <-time.After(s.delay):
+		s.delivered.Add(1)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *slowSubscriber) Name() string {
+	return "slow"
+}
+
+func TestAuditService_FlushWaitsForAsyncNotifications(t *testing.T) {
+	sub := &slowSubscriber{delay: 50 * time.Millisecond}
+	auditSvc := NewAuditService()
+	auditSvc.Subscribe(sub)
+
+	for range 3 {
+		auditSvc.NotifyAsync(AuditEvent{Metrics: []string{"Alloc"}}, time.Second)
+	}
+
+	if err := auditSvc.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	if got := sub.delivered.Load(); got != 3 {
+		t.Errorf("delivered %d events, want 3", got)
+	}
+}
+
+func TestAuditService_FlushDeadline(t *testing.T) {
+	auditSvc := NewAuditService()
+	auditSvc.Subscribe(&slowSubscriber{delay: time.Second})
+
+	auditSvc.NotifyAsync(AuditEvent{Metrics: []string{"Alloc"}}, time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := auditSvc.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Flush() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,136 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Ko4etov/go-metrics/internal/server/service/audit"
+)
+
+// DatabaseCheck проверяет доступность базы данных: время ответа на ping
+// и статистику пула подключений.
+func DatabaseCheck(pool *pgxpool.Pool) Check {
+	return Check{
+		Name:     "database",
+		Critical: true,
+		Run: func(ctx context.Context) CheckResult {
+			stat := pool.Stat()
+			details := map[string]any{
+				"total_conns":    stat.TotalConns(),
+				"idle_conns":     stat.IdleConns(),
+				"acquired_conns": stat.AcquiredConns(),
+				"max_conns":      stat.MaxConns(),
+			}
+
+			start := time.Now()
+			err := pool.Ping(ctx)
+			details["ping_latency_ms"] = float64(time.Since(start).Microseconds()) / 1000
+
+			if err != nil {
+				result := Failed(fmt.Errorf("ping failed: %w", err))
+				result.Details = details
+				return result
+			}
+
+			return OK(details)
+		},
+	}
+}
+
+// FileStorageCheck проверяет, что в каталог файла хранения метрик можно записывать.
+func FileStorageCheck(path string) Check {
+	dir := filepath.Dir(path)
+
+	return Check{
+		Name:     "file_storage",
+		Critical: true,
+		Run: func(ctx context.Context) CheckResult {
+			file, err := os.CreateTemp(dir, ".readyz-*")
+			if err != nil {
+				return Failed(fmt.Errorf("directory %s is not writable: %w", dir, err))
+			}
+			file.Close()
+			os.Remove(file.Name())
+
+			return OK(map[string]any{"path": path})
+		},
+	}
+}
+
+// PeriodicSaveCheck проверяет, что периодическое сохранение метрик с интервалом interval
+// не отстает: с последнего успешного сохранения (или с запуска) прошло не больше
+// трех интервалов. Функция lastSave возвращает время последнего успешного сохранения.
+func PeriodicSaveCheck(lastSave func() time.Time, interval time.Duration) Check {
+	started := time.Now()
+
+	return Check{
+		Name: "periodic_save",
+		Run: func(ctx context.Context) CheckResult {
+			last := lastSave()
+			details := map[string]any{"interval_seconds": interval.Seconds()}
+
+			since := started
+			if !last.IsZero() {
+				details["last_save"] = last.UTC().Format(time.RFC3339)
+				since = last
+			}
+
+			if lag := time.Since(since); lag > 3*interval {
+				result := Failed(fmt.Errorf("no successful save for %s", lag.Round(time.Second)))
+				result.Details = details
+				return result
+			}
+
+			return OK(details)
+		},
+	}
+}
+
+// RestoreCheck проверяет, что восстановление метрик при старте завершено.
+// Функция status возвращает признак завершения и ошибку восстановления;
+// ошибка восстановления не делает сервер неготовым, но отражается в отчете.
+func RestoreCheck(status func() (bool, error)) Check {
+	return Check{
+		Name:     "restore",
+		Critical: true,
+		Run: func(ctx context.Context) CheckResult {
+			restored, err := status()
+			switch {
+			case !restored:
+				return CheckResult{Status: StatusFail, Error: "restore in progress"}
+			case err != nil:
+				return CheckResult{Status: StatusDegraded, Error: err.Error()}
+			}
+
+			return OK(nil)
+		},
+	}
+}
+
+// AuditCheck проверяет результаты последних доставок событий аудита подписчикам.
+func AuditCheck(auditSvc *audit.AuditService) Check {
+	return Check{
+		Name: "audit",
+		Run: func(ctx context.Context) CheckResult {
+			states := auditSvc.SubscriberStates()
+			details := map[string]any{"subscribers": states}
+
+			for _, state := range states {
+				if !state.Healthy() {
+					return CheckResult{
+						Status:  StatusFail,
+						Error:   fmt.Sprintf("subscriber %s: %s", state.Name, state.LastError),
+						Details: details,
+					}
+				}
+			}
+
+			return OK(details)
+		},
+	}
+}
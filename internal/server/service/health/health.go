@@ -0,0 +1,154 @@
+// Package health проверяет готовность сервера к обслуживанию запросов.
+//
+// Checker выполняет зарегистрированные проверки зависимостей и сводит их результаты
+// в общий статус. Отказ критичной проверки делает сервер неготовым, отказ некритичной -
+// только понижает статус до degraded. Во время остановки сервер всегда неготов,
+// чтобы балансировщики перестали направлять на него запросы.
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Статусы проверок и сервера.
+const (
+	StatusOK       = "ok"       // зависимость или сервер работают нормально
+	StatusDegraded = "degraded" // некритичная зависимость работает с ошибками
+	StatusFail     = "fail"     // зависимость недоступна или сервер не готов
+)
+
+// defaultTimeout - время на выполнение всех проверок по умолчанию.
+const defaultTimeout = 2 * time.Second
+
+// CheckResult - результат одной проверки.
+type CheckResult struct {
+	Status  string         `json:"status"`            // статус проверки
+	Error   string         `json:"error,omitempty"`   // причина отказа
+	Details map[string]any `json:"details,omitempty"` // дополнительные сведения о зависимости
+}
+
+// Check описывает проверку зависимости.
+type Check struct {
+	Name     string                                // имя проверки в отчете
+	Critical bool                                  // отказ делает сервер неготовым
+	Run      func(ctx context.Context) CheckResult // выполнение проверки
+}
+
+// Report - отчет о готовности сервера.
+type Report struct {
+	Status string                 `json:"status"` // общий статус сервера
+	Checks map[string]CheckResult `json:"checks"` // результаты проверок по именам
+}
+
+// Ready сообщает, готов ли сервер обслуживать запросы.
+func (r *Report) Ready() bool {
+	return r.Status != StatusFail
+}
+
+// Checker выполняет проверки готовности сервера.
+type Checker struct {
+	mu           sync.RWMutex  // мьютекс для безопасного доступа к проверкам
+	checks       []Check       // зарегистрированные проверки
+	timeout      time.Duration // время на выполнение всех проверок
+	shuttingDown atomic.Bool   // сервер останавливается
+}
+
+// NewChecker создает проверку готовности. Если timeout не положителен,
+// используется значение по умолчанию 2 секунды.
+func NewChecker(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	return &Checker{timeout: timeout}
+}
+
+// Add регистрирует проверку зависимости.
+func (c *Checker) Add(check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks = append(c.checks, check)
+}
+
+// SetShuttingDown переводит сервер в состояние остановки: дальнейшие проверки
+// готовности завершаются отказом.
+func (c *Checker) SetShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Check выполняет все проверки параллельно и возвращает отчет.
+// Проверка, не уложившаяся в отведенное время, считается отказавшей.
+func (c *Checker) Check(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	c.mu.RLock()
+	checks := make([]Check, len(c.checks))
+	copy(checks, c.checks)
+	c.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runCheck(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{
+		Status: StatusOK,
+		Checks: make(map[string]CheckResult, len(checks)+1),
+	}
+
+	for i, check := range checks {
+		result := results[i]
+		report.Checks[check.Name] = result
+
+		switch {
+		case result.Status == StatusFail && check.Critical:
+			report.Status = StatusFail
+		case result.Status != StatusOK && report.Status == StatusOK:
+			report.Status = StatusDegraded
+		}
+	}
+
+	if c.shuttingDown.Load() {
+		report.Status = StatusFail
+		report.Checks["shutdown"] = CheckResult{Status: StatusFail, Error: "server is shutting down"}
+	}
+
+	return report
+}
+
+// runCheck выполняет проверку с учетом срока ctx.
+func runCheck(ctx context.Context, check Check) CheckResult {
+	done := make(chan CheckResult, 1)
+	go func() {
+		done <- check.Run(ctx)
+	}()
+
+	select {
+	case result := <-done:
+		return result
+	case <-ctx.Done():
+		return Failed(ctx.Err())
+	}
+}
+
+// OK возвращает успешный результат проверки со сведениями details.
+func OK(details map[string]any) CheckResult {
+	return CheckResult{Status: StatusOK, Details: details}
+}
+
+// Failed возвращает результат отказавшей проверки.
+func Failed(err error) CheckResult {
+	return CheckResult{Status: StatusFail, Error: err.Error()}
+}
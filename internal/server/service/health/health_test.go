@@ -0,0 +1,187 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Ko4etov/go-metrics/internal/server/service/audit"
+)
+
+func staticCheck(name string, critical bool, status string) Check {
+	return Check{
+		Name:     name,
+		Critical: critical,
+		Run: func(ctx context.Context) CheckResult {
+			return CheckResult{Status: status}
+		},
+	}
+}
+
+func TestChecker_Status(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks []Check
+		want   string
+	}{
+		{"no checks", nil, StatusOK},
+		{"all ok", []Check{staticCheck("a", true, StatusOK), staticCheck("b", false, StatusOK)}, StatusOK},
+		{"non-critical fail", []Check{staticCheck("a", true, StatusOK), staticCheck("b", false, StatusFail)}, StatusDegraded},
+		{"critical degraded", []Check{staticCheck("a", true, StatusDegraded)}, StatusDegraded},
+		{"critical fail", []Check{staticCheck("a", true, StatusFail), staticCheck("b", false, StatusFail)}, StatusFail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewChecker(0)
+			for _, check := range tt.checks {
+				checker.Add(check)
+			}
+
+			report := checker.Check(context.Background())
+			if report.Status != tt.want {
+				t.Errorf("status = %s, want %s", report.Status, tt.want)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Errorf("got %d check results, want %d", len(report.Checks), len(tt.checks))
+			}
+		})
+	}
+}
+
+func TestChecker_ShuttingDown(t *testing.T) {
+	checker := NewChecker(0)
+	checker.Add(staticCheck("a", true, StatusOK))
+
+	if report := checker.Check(context.Background()); !report.Ready() {
+		t.Fatalf("expected ready before shutdown, got %+v", report)
+	}
+
+	checker.SetShuttingDown()
+
+	report := checker.Check(context.Background())
+	if report.Ready() {
+		t.Error("expected not ready during shutdown")
+	}
+	if report.Checks["shutdown"].Status != StatusFail {
+		t.Errorf("shutdown check = %+v", report.Checks["shutdown"])
+	}
+}
+
+func TestChecker_Timeout(t *testing.T) {
+	checker := NewChecker(20 * time.Millisecond)
+	checker.Add(Check{
+		Name:     "slow",
+		Critical: true,
+		Run: func(ctx context.Context) CheckResult {
+			time.Sleep(time.Second)
+			return OK(nil)
+		},
+	})
+
+	start := time.Now()
+	report := checker.Check(context.Background())
+
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("check did not respect timeout")
+	}
+	if report.Status != StatusFail {
+		t.Errorf("status = %s, want %s", report.Status, StatusFail)
+	}
+}
+
+func TestFileStorageCheck(t *testing.T) {
+	dir := t.TempDir()
+
+	if result := FileStorageCheck(filepath.Join(dir, "metrics.json")).Run(context.Background()); result.Status != StatusOK {
+		t.Errorf("writable directory: %+v", result)
+	}
+
+	missing := filepath.Join(dir, "missing", "metrics.json")
+	if result := FileStorageCheck(missing).Run(context.Background()); result.Status != StatusFail {
+		t.Errorf("missing directory: %+v", result)
+	}
+}
+
+func TestPeriodicSaveCheck(t *testing.T) {
+	interval := 10 * time.Millisecond
+	var lastSave time.Time
+
+	check := PeriodicSaveCheck(func() time.Time { return lastSave }, interval)
+
+	if result := check.Run(context.Background()); result.Status != StatusOK {
+		t.Errorf("right after start: %+v", result)
+	}
+
+	time.Sleep(4 * interval)
+	if result := check.Run(context.Background()); result.Status != StatusFail {
+		t.Errorf("no saves: %+v", result)
+	}
+
+	lastSave = time.Now()
+	result := check.Run(context.Background())
+	if result.Status != StatusOK || result.Details["last_save"] == nil {
+		t.Errorf("after save: %+v", result)
+	}
+}
+
+func TestRestoreCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		restored bool
+		err      error
+		want     string
+	}{
+		{"in progress", false, nil, StatusFail},
+		{"restored", true, nil, StatusOK},
+		{"restore failed", true, errors.New("broken file"), StatusDegraded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := RestoreCheck(func() (bool, error) { return tt.restored, tt.err })
+			if result := check.Run(context.Background()); result.Status != tt.want {
+				t.Errorf("status = %s, want %s", result.Status, tt.want)
+			}
+		})
+	}
+}
+
+// stubSubscriber - подписчик аудита с управляемой ошибкой доставки.
+type stubSubscriber struct {
+	err error
+}
+
+func (s *stubSubscriber) Audit(ctx context.Context, event audit.AuditEvent) error {
+	return s.err
+}
+
+func (s *stubSubscriber) Name() string {
+	return "stub"
+}
+
+func TestAuditCheck(t *testing.T) {
+	subscriber := &stubSubscriber{}
+	auditSvc := audit.NewAuditService()
+	auditSvc.Subscribe(subscriber)
+
+	check := AuditCheck(auditSvc)
+
+	if result := check.Run(context.Background()); result.Status != StatusOK {
+		t.Errorf("before deliveries: %+v", result)
+	}
+
+	subscriber.err = errors.New("connection refused")
+	auditSvc.Notify(context.Background(), audit.AuditEvent{})
+	if result := check.Run(context.Background()); result.Status != StatusFail || result.Error == "" {
+		t.Errorf("after failed delivery: %+v", result)
+	}
+
+	subscriber.err = nil
+	auditSvc.Notify(context.Background(), audit.AuditEvent{})
+	if result := check.Run(context.Background()); result.Status != StatusOK {
+		t.Errorf("after recovered delivery: %+v", result)
+	}
+}
@@ -0,0 +1,183 @@
+// Package influx реализует разбор строкового протокола InfluxDB (line protocol).
+//
+// Строка протокола имеет вид:
+//
+//	measurement[,tag=value...] field=value[,field=value...] [timestamp]
+//
+// Значения полей бывают вещественными (1.5), целыми (1i), беззнаковыми (1u),
+// логическими (t, true, f, false) и строковыми ("text").
+package influx
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidLine - ошибка разбора строки протокола.
+var ErrInvalidLine = errors.New("invalid line protocol")
+
+// Point - точка данных, разобранная из строки протокола.
+type Point struct {
+	Measurement string            // имя измерения
+	Tags        map[string]string // теги точки
+	Fields      map[string]any    // поля: float64, int64, uint64, bool или string
+	Timestamp   *int64            // временная метка (опционально)
+}
+
+// Parse разбирает тело запроса в точки. Пустые строки и комментарии (#) пропускаются.
+// Ошибка содержит номер первой некорректной строки.
+func Parse(data []byte) ([]Point, error) {
+	lines := strings.Split(string(data), "\n")
+	points := make([]Point, 0, len(lines))
+
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		point, err := ParseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		points = append(points, point)
+	}
+
+	return points, nil
+}
+
+// ParseLine разбирает одну строку протокола.
+func ParseLine(line string) (Point, error) {
+	sections := splitUnescaped(line, ' ', true)
+	if len(sections) < 2 || len(sections) > 3 {
+		return Point{}, fmt.Errorf("%w: expected measurement, fields and optional timestamp", ErrInvalidLine)
+	}
+
+	point := Point{Tags: make(map[string]string), Fields: make(map[string]any)}
+
+	key := splitUnescaped(sections[0], ',', false)
+	point.Measurement = unescape(key[0])
+	if point.Measurement == "" {
+		return Point{}, fmt.Errorf("%w: missing measurement", ErrInvalidLine)
+	}
+
+	for _, tag := range key[1:] {
+		name, value, ok := cutUnescaped(tag, '=')
+		if !ok || name == "" || value == "" {
+			return Point{}, fmt.Errorf("%w: invalid tag %q", ErrInvalidLine, tag)
+		}
+		point.Tags[unescape(name)] = unescape(value)
+	}
+
+	for _, field := range splitUnescaped(sections[1], ',', true) {
+		name, raw, ok := cutUnescaped(field, '=')
+		if !ok || name == "" {
+			return Point{}, fmt.Errorf("%w: invalid field %q", ErrInvalidLine, field)
+		}
+
+		value, err := parseFieldValue(raw)
+		if err != nil {
+			return Point{}, fmt.Errorf("%w: field %q: %v", ErrInvalidLine, name, err)
+		}
+		point.Fields[unescape(name)] = value
+	}
+
+	if len(sections) == 3 {
+		ts, err := strconv.ParseInt(sections[2], 10, 64)
+		if err != nil {
+			return Point{}, fmt.Errorf("%w: invalid timestamp %q", ErrInvalidLine, sections[2])
+		}
+		point.Timestamp = &ts
+	}
+
+	return point, nil
+}
+
+// parseFieldValue разбирает значение поля по его суффиксу или кавычкам.
+// Вещественные NaN и бесконечности протоколом не допускаются.
+func parseFieldValue(raw string) (any, error) {
+	if raw == "" {
+		return nil, errors.New("empty value")
+	}
+
+	if strings.HasPrefix(raw, `"`) {
+		if len(raw) < 2 || !strings.HasSuffix(raw, `"`) {
+			return nil, fmt.Errorf("unterminated string %s", raw)
+		}
+		replacer := strings.NewReplacer(`\"`, `"`, `\\`, `\`)
+		return replacer.Replace(raw[1 : len(raw)-1]), nil
+	}
+
+	switch raw {
+	case "t", "T", "true", "True", "TRUE":
+		return true, nil
+	case "f", "F", "false", "False", "FALSE":
+		return false, nil
+	}
+
+	switch raw[len(raw)-1] {
+	case 'i':
+		return strconv.ParseInt(raw[:len(raw)-1], 10, 64)
+	case 'u':
+		return strconv.ParseUint(raw[:len(raw)-1], 10, 64)
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, err
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, fmt.Errorf("non-finite value %s", raw)
+	}
+
+	return value, nil
+}
+
+// splitUnescaped разбивает s по разделителю sep, не экранированному обратной косой чертой.
+// Если quoted установлен, разделители внутри двойных кавычек также не учитываются.
+func splitUnescaped(s string, sep byte, quoted bool) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case quoted && s[i] == '"':
+			inQuotes = !inQuotes
+		case s[i] == sep && !inQuotes:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+// cutUnescaped разделяет s по первому неэкранированному разделителю sep.
+func cutUnescaped(s string, sep byte) (before, after string, found bool) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			return s[:i], s[i+1:], true
+		}
+	}
+
+	return s, "", false
+}
+
+// unescape убирает экранирование запятых, знаков равенства и пробелов.
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	return strings.NewReplacer(`\,`, ",", `\=`, "=", `\ `, " ").Replace(s)
+}
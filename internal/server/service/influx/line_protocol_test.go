@@ -0,0 +1,60 @@
+package influx
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseLine(t *testing.T) {
+	point, err := ParseLine(`cpu\ load,host=web\,1,region=eu usage_idle=97.5,procs=12i,total=3u,up=t,note="a \"b\", c" 1700000000000000000`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if point.Measurement != "cpu load" {
+		t.Errorf("unexpected measurement %q", point.Measurement)
+	}
+	if point.Tags["host"] != "web,1" || point.Tags["region"] != "eu" {
+		t.Errorf("unexpected tags %v", point.Tags)
+	}
+
+	expected := map[string]any{
+		"usage_idle": 97.5,
+		"procs":      int64(12),
+		"total":      uint64(3),
+		"up":         true,
+		"note":       `a "b", c`,
+	}
+	for name, want := range expected {
+		if got := point.Fields[name]; got != want {
+			t.Errorf("field %s = %#v, want %#v", name, got, want)
+		}
+	}
+
+	if point.Timestamp == nil || *point.Timestamp != 1700000000000000000 {
+		t.Errorf("unexpected timestamp %v", point.Timestamp)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []string{
+		"cpu",
+		"cpu value=1 123 extra",
+		",host=a value=1",
+		"cpu,host value=1",
+		"cpu value=",
+		"cpu value=abc",
+		"cpu value=NaN",
+		"cpu value=-Inf",
+		`cpu value="unterminated`,
+		"cpu value=1 notatime",
+	}
+
+	for _, line := range tests {
+		t.Run(line, func(t *testing.T) {
+			if _, err := Parse([]byte("# comment\n\n" + line)); !errors.Is(err, ErrInvalidLine) {
+				t.Errorf("expected ErrInvalidLine, got %v", err)
+			}
+		})
+	}
+}
@@ -5,8 +5,8 @@ import (
 	"go.uber.org/zap"
 )
 
-// Logger - глобальный экземпляр логгера.
-var Logger zap.SugaredLogger
+// Logger - глобальный экземпляр логгера. До вызова Initialize записи отбрасываются.
+var Logger = *zap.NewNop().Sugar()
 
 // Initialize инициализирует логгер с указанным уровнем логирования.
 //
@@ -22,6 +22,9 @@ func Initialize(level string) error {
 	cfg := zap.NewProductionConfig()
 
 	cfg.Level = lvl
+	// Встроенное прореживание zap отбрасывает одинаковые сообщения сверх лимита,
+	// из-за чего терялись бы записи журнала доступа; его доля задается отдельно.
+	cfg.Sampling = nil
 
 	zl, err := cfg.Build()
 	if err != nil {
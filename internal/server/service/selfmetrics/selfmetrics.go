@@ -0,0 +1,295 @@
+// Package selfmetrics собирает операционные метрики самого сервера: количество и
+// длительность HTTP-запросов, сохранения хранилища, повторы запросов к базе данных,
+// ошибки доставки аудита.
+//
+// Метрики имеют имена с префиксом Namespace и периодически записываются в обычное
+// хранилище, поэтому доступны через все API чтения, эндпоинт Prometheus и правила
+// оповещений. Агенты и приемники не могут записывать метрики с этим префиксом.
+package selfmetrics
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/interfaces"
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+)
+
+// Namespace - зарезервированный префикс имен собственных метрик сервера.
+const Namespace = models.ReservedPrefix
+
+// source - источник обновлений собственных метрик в истории и хранилище.
+const source = "self"
+
+// Имена собственных метрик сервера.
+const (
+	HTTPRequestsTotal    = Namespace + "http_requests_total"           // счетчик HTTP-запросов по маршруту, методу и статусу
+	HTTPRequestDuration  = Namespace + "http_request_duration_seconds" // гистограмма длительности HTTP-запросов по маршруту
+	HTTPRequestsInFlight = Namespace + "http_requests_in_flight"       // количество обрабатываемых HTTP-запросов
+	StorageSeries        = Namespace + "storage_series"                // количество рядов в хранилище
+	StorageFileBytes     = Namespace + "storage_file_bytes"            // размер файла хранилища после последнего сохранения
+	StorageSaveDuration  = Namespace + "storage_save_duration_seconds" // гистограмма длительности сохранения хранилища в файл
+	StorageSaveFailures  = Namespace + "storage_save_failures_total"   // счетчик ошибок сохранения хранилища в файл
+	DBRetriesTotal       = Namespace + "db_retries_total"              // счетчик повторов запросов к базе данных по операции
+	AuditFailuresTotal   = Namespace + "audit_failures_total"          // счетчик ошибок доставки событий аудита по подписчику
+	HashRejectedRequests = Namespace + "hash_rejected_requests_total"  // счетчик запросов, отклоненных из-за неверной подписи
+)
+
+// DurationBuckets - границы корзин гистограмм длительности в секундах.
+var DurationBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Reserved проверяет, относится ли имя метрики к зарезервированному пространству имен.
+func Reserved(id string) bool {
+	return strings.HasPrefix(id, Namespace)
+}
+
+// series - накопленное значение одного ряда собственных метрик.
+type series struct {
+	metric    models.Metrics         // имя, тип и метки ряда
+	delta     int64                  // прирост счетчика с прошлой записи
+	value     float64                // значение измерителя
+	histogram *models.HistogramValue // наблюдения гистограммы с прошлой записи
+}
+
+// Registry накапливает собственные метрики сервера между записями в хранилище.
+// Методы безопасны для конкурентного использования; методы nil-реестра ничего не делают,
+// поэтому компоненты могут вызывать их без проверки, подключен ли реестр.
+type Registry struct {
+	mu     sync.Mutex                // мьютекс для безопасного доступа к рядам
+	series map[string]*series        // ряды по ключу метрики
+	gauges map[string]func() float64 // измерители, вычисляемые при записи
+
+	done chan struct{}  // канал остановки периодической записи
+	wg   sync.WaitGroup // группа ожидания периодической записи
+}
+
+// New создает пустой реестр собственных метрик.
+func New() *Registry {
+	return &Registry{
+		series: make(map[string]*series),
+		gauges: make(map[string]func() float64),
+	}
+}
+
+// Add увеличивает счетчик name с метками labels на delta.
+func (r *Registry) Add(name string, labels models.Labels, delta int64) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.get(name, models.Counter, labels).delta += delta
+}
+
+// Set устанавливает значение измерителя name с метками labels.
+func (r *Registry) Set(name string, labels models.Labels, value float64) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.get(name, models.Gauge, labels).value = value
+}
+
+// AddGauge изменяет значение измерителя name с метками labels на delta.
+func (r *Registry) AddGauge(name string, labels models.Labels, delta float64) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.get(name, models.Gauge, labels).value += delta
+}
+
+// Observe добавляет наблюдение value в гистограмму name с метками labels.
+// Гистограммы используют корзины DurationBuckets.
+func (r *Registry) Observe(name string, labels models.Labels, value float64) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.get(name, models.Histogram, labels)
+	if s.histogram == nil {
+		s.histogram = &models.HistogramValue{
+			Buckets: slices.Clone(DurationBuckets),
+			Counts:  make([]uint64, len(DurationBuckets)+1),
+		}
+	}
+
+	index := sort.SearchFloat64s(DurationBuckets, value)
+	s.histogram.Counts[index]++
+	s.histogram.Sum += value
+	s.histogram.Count++
+}
+
+// ObserveDuration добавляет длительность в секундах в гистограмму name с метками labels.
+func (r *Registry) ObserveDuration(name string, labels models.Labels, duration time.Duration) {
+	r.Observe(name, labels, duration.Seconds())
+}
+
+// GaugeFunc регистрирует измеритель name без меток, значение которого вычисляется
+// функцией fn при каждой записи в хранилище.
+func (r *Registry) GaugeFunc(name string, fn func() float64) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.gauges[name] = fn
+}
+
+// get возвращает ряд метрики, создавая его при необходимости. Вызывается под мьютексом.
+func (r *Registry) get(name, metricType string, labels models.Labels) *series {
+	metric := models.Metrics{ID: name, MType: metricType, Labels: labels}
+	key := metric.Key()
+
+	s, ok := r.series[key]
+	if !ok {
+		s = &series{metric: metric}
+		r.series[key] = s
+	}
+
+	return s
+}
+
+// Snapshot возвращает накопленные метрики и сбрасывает приросты счетчиков
+// и наблюдения гистограмм. Счетчики и гистограммы без изменений пропускаются.
+func (r *Registry) Snapshot() []models.Metrics {
+	r.mu.Lock()
+	gauges := make(map[string]func() float64, len(r.gauges))
+	for name, fn := range r.gauges {
+		gauges[name] = fn
+	}
+
+	metrics := make([]models.Metrics, 0, len(r.series)+len(gauges))
+
+	for _, s := range r.series {
+		metric := s.metric
+
+		switch metric.MType {
+		case models.Counter:
+			if s.delta == 0 {
+				continue
+			}
+			delta := s.delta
+			metric.Delta = &delta
+			s.delta = 0
+		case models.Gauge:
+			value := s.value
+			metric.Value = &value
+		case models.Histogram:
+			if s.histogram == nil || s.histogram.Count == 0 {
+				continue
+			}
+			metric.Histogram = s.histogram
+			s.histogram = nil
+		}
+
+		metrics = append(metrics, metric)
+	}
+	r.mu.Unlock()
+
+	// Функции измерителей вызываются без мьютекса: они могут обращаться к хранилищу,
+	// которое само сообщает метрики в реестр.
+	for name, fn := range gauges {
+		value := fn()
+		metrics = append(metrics, models.Metrics{ID: name, MType: models.Gauge, Value: &value})
+	}
+
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].Key() < metrics[j].Key()
+	})
+
+	return metrics
+}
+
+// Restore возвращает в реестр приросты счетчиков и наблюдения гистограмм из снимка,
+// который не удалось записать, чтобы они вошли в следующую запись.
+func (r *Registry) Restore(metrics []models.Metrics) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, metric := range metrics {
+		switch {
+		case metric.MType == models.Counter && metric.Delta != nil:
+			r.get(metric.ID, metric.MType, metric.Labels).delta += *metric.Delta
+		case metric.MType == models.Histogram && metric.Histogram != nil:
+			s := r.get(metric.ID, metric.MType, metric.Labels)
+			if s.histogram == nil {
+				s.histogram = metric.Histogram.Copy()
+			} else {
+				s.histogram = metric.Histogram.Merge(s.histogram)
+			}
+		}
+	}
+}
+
+// Publish записывает накопленные метрики в хранилище.
+// При ошибке записи приросты счетчиков и наблюдения гистограмм сохраняются в реестре.
+func (r *Registry) Publish(storage interfaces.Storage) error {
+	metrics := r.Snapshot()
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	if err := storage.UpdateMetricsBatchFrom(source, metrics); err != nil {
+		r.Restore(metrics)
+		return err
+	}
+
+	return nil
+}
+
+// Start запускает периодическую запись метрик в хранилище с интервалом interval.
+func (r *Registry) Start(storage interfaces.Storage, interval time.Duration) {
+	r.done = make(chan struct{})
+	r.wg.Add(1)
+
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := r.Publish(storage); err != nil {
+					logger.Logger.Errorf("[self-metrics] failed to publish metrics: %v", err)
+				}
+			case <-r.done:
+				return
+			}
+		}
+	}()
+}
+
+// Stop останавливает периодическую запись метрик.
+func (r *Registry) Stop() {
+	if r.done == nil {
+		return
+	}
+
+	close(r.done)
+	r.wg.Wait()
+	r.done = nil
+}
@@ -0,0 +1,153 @@
+package selfmetrics_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/interfaces"
+	"github.com/Ko4etov/go-metrics/internal/server/repository/storage"
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+	"github.com/Ko4etov/go-metrics/internal/server/service/selfmetrics"
+)
+
+func TestRegistry_Snapshot(t *testing.T) {
+	r := selfmetrics.New()
+
+	r.Add(selfmetrics.DBRetriesTotal, models.Labels{"operation": "save"}, 1)
+	r.Add(selfmetrics.DBRetriesTotal, models.Labels{"operation": "save"}, 2)
+	r.Set(selfmetrics.StorageFileBytes, nil, 128)
+	r.AddGauge(selfmetrics.HTTPRequestsInFlight, nil, 1)
+	r.ObserveDuration(selfmetrics.StorageSaveDuration, nil, 20*time.Millisecond)
+	r.ObserveDuration(selfmetrics.StorageSaveDuration, nil, 20*time.Second)
+	r.GaugeFunc(selfmetrics.StorageSeries, func() float64 { return 42 })
+
+	metrics := byID(r.Snapshot())
+
+	retries := metrics[selfmetrics.DBRetriesTotal]
+	if retries.Delta == nil || *retries.Delta != 3 || retries.Labels["operation"] != "save" {
+		t.Errorf("unexpected retries counter: %+v", retries)
+	}
+	if fileBytes := metrics[selfmetrics.StorageFileBytes]; fileBytes.Value == nil || *fileBytes.Value != 128 {
+		t.Errorf("unexpected file bytes gauge: %+v", fileBytes)
+	}
+	if series := metrics[selfmetrics.StorageSeries]; series.Value == nil || *series.Value != 42 {
+		t.Errorf("unexpected series gauge: %+v", series)
+	}
+
+	histogram := metrics[selfmetrics.StorageSaveDuration].Histogram
+	if histogram == nil || histogram.Count != 2 {
+		t.Fatalf("unexpected save duration histogram: %+v", histogram)
+	}
+	if err := histogram.Validate(); err != nil {
+		t.Errorf("invalid histogram: %v", err)
+	}
+	if histogram.Counts[3] != 1 || histogram.Counts[len(histogram.Counts)-1] != 1 {
+		t.Errorf("unexpected bucket counts: %v", histogram.Counts)
+	}
+
+	// Приросты счетчиков и гистограммы сбрасываются, измерители сохраняются.
+	metrics = byID(r.Snapshot())
+	if _, ok := metrics[selfmetrics.DBRetriesTotal]; ok {
+		t.Error("counter without changes should be skipped")
+	}
+	if _, ok := metrics[selfmetrics.StorageSaveDuration]; ok {
+		t.Error("histogram without observations should be skipped")
+	}
+	if inFlight := metrics[selfmetrics.HTTPRequestsInFlight]; inFlight.Value == nil || *inFlight.Value != 1 {
+		t.Errorf("unexpected in-flight gauge: %+v", inFlight)
+	}
+}
+
+func TestRegistry_Nil(t *testing.T) {
+	var r *selfmetrics.Registry
+
+	r.Add(selfmetrics.DBRetriesTotal, nil, 1)
+	r.Set(selfmetrics.StorageFileBytes, nil, 1)
+	r.AddGauge(selfmetrics.HTTPRequestsInFlight, nil, 1)
+	r.ObserveDuration(selfmetrics.StorageSaveDuration, nil, time.Second)
+	r.GaugeFunc(selfmetrics.StorageSeries, func() float64 { return 1 })
+}
+
+func TestRegistry_Publish(t *testing.T) {
+	logger.Initialize("error")
+
+	store := storage.New(&storage.MetricsStorageConfig{})
+	r := selfmetrics.New()
+
+	r.Add(selfmetrics.AuditFailuresTotal, models.Labels{"subscriber": "http"}, 1)
+	if err := r.Publish(store); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	r.Add(selfmetrics.AuditFailuresTotal, models.Labels{"subscriber": "http"}, 2)
+	if err := r.Publish(store); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	key := models.Metrics{ID: selfmetrics.AuditFailuresTotal, MType: models.Counter, Labels: models.Labels{"subscriber": "http"}}.Key()
+	metric, ok := store.Metric(key)
+	if !ok {
+		t.Fatal("self metric not published to storage")
+	}
+	if metric.Delta == nil || *metric.Delta != 3 {
+		t.Errorf("published counter = %v, want 3", metric.Delta)
+	}
+}
+
+// failingStorage - хранилище, запись батчей в которое завершается ошибкой, пока установлен fail.
+type failingStorage struct {
+	interfaces.Storage
+	fail bool
+}
+
+func (s *failingStorage) UpdateMetricsBatchFrom(source string, metrics []models.Metrics) error {
+	if s.fail {
+		return errors.New("storage unavailable")
+	}
+	return s.Storage.UpdateMetricsBatchFrom(source, metrics)
+}
+
+func TestRegistry_PublishFailureKeepsDeltas(t *testing.T) {
+	logger.Initialize("error")
+
+	store := &failingStorage{Storage: storage.New(&storage.MetricsStorageConfig{}), fail: true}
+	r := selfmetrics.New()
+
+	r.Add(selfmetrics.DBRetriesTotal, nil, 2)
+	r.ObserveDuration(selfmetrics.StorageSaveDuration, nil, 10*time.Millisecond)
+	if err := r.Publish(store); err == nil {
+		t.Fatal("Publish() expected error")
+	}
+
+	r.Add(selfmetrics.DBRetriesTotal, nil, 1)
+	r.ObserveDuration(selfmetrics.StorageSaveDuration, nil, 20*time.Millisecond)
+	store.fail = false
+	if err := r.Publish(store); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	if metric, ok := store.Metric(selfmetrics.DBRetriesTotal); !ok || metric.Delta == nil || *metric.Delta != 3 {
+		t.Errorf("published counter = %+v, want 3", metric)
+	}
+	if metric, ok := store.Metric(selfmetrics.StorageSaveDuration); !ok || metric.Histogram == nil || metric.Histogram.Count != 2 {
+		t.Errorf("published histogram = %+v, want 2 observations", metric)
+	}
+}
+
+func TestReserved(t *testing.T) {
+	if !selfmetrics.Reserved(selfmetrics.HTTPRequestsTotal) {
+		t.Errorf("%s should be reserved", selfmetrics.HTTPRequestsTotal)
+	}
+	if selfmetrics.Reserved("Alloc") {
+		t.Error("Alloc should not be reserved")
+	}
+}
+
+func byID(metrics []models.Metrics) map[string]models.Metrics {
+	result := make(map[string]models.Metrics, len(metrics))
+	for _, metric := range metrics {
+		result[metric.ID] = metric
+	}
+	return result
+}
@@ -0,0 +1,327 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server"
+	"github.com/Ko4etov/go-metrics/internal/server/config"
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+)
+
+// freeAddress возвращает свободный адрес на локальном интерфейсе.
+func freeAddress(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find free port: %v", err)
+	}
+	defer listener.Close()
+
+	return listener.Addr().String()
+}
+
+func TestRun_GracefulShutdown(t *testing.T) {
+	logger.Initialize("error")
+
+	address := freeAddress(t)
+	path := filepath.Join(t.TempDir(), "metrics.json")
+
+	cfg := &config.ServerConfig{
+		ServerAddress:          address,
+		StoreMetricsInterval:   300,
+		FileStorageMetricsPath: path,
+		ShutdownTimeout:        5 * time.Second,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.New(cfg).Run(ctx)
+	}()
+
+	url := fmt.Sprintf("http://%s", address)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		resp, err := http.Get(url + "/healthz")
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not start: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	resp, err := http.Post(url+"/update/counter/PollCount/5", "text/plain", nil)
+	if err != nil {
+		t.Fatalf("failed to update metric: %v", err)
+	}
+	resp.Body.Close()
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	case <-time.After(cfg.ShutdownTimeout):
+		t.Fatal("Run() did not return after shutdown")
+	}
+
+	if _, err := http.Get(url + "/healthz"); err == nil {
+		t.Error("server still accepts requests after shutdown")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("metrics were not saved on shutdown: %v", err)
+	}
+
+	var metrics []models.Metrics
+	if err := json.Unmarshal(data, &metrics); err != nil {
+		t.Fatalf("failed to decode saved metrics: %v", err)
+	}
+
+	if len(metrics) != 1 || metrics[0].ID != "PollCount" || *metrics[0].Delta != 5 {
+		t.Errorf("saved metrics = %+v, want PollCount=5", metrics)
+	}
+}
+
+func TestRun_ShutdownClosesStreams(t *testing.T) {
+	logger.Initialize("error")
+
+	address := freeAddress(t)
+	cfg := &config.ServerConfig{
+		ServerAddress:   address,
+		ShutdownTimeout: 5 * time.Second,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.New(cfg).Run(ctx)
+	}()
+
+	url := fmt.Sprintf("http://%s/stream", address)
+
+	var resp *http.Response
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		var err error
+		resp, err = http.Get(url)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not start: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	defer resp.Body.Close()
+
+	start := time.Now()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	case <-time.After(cfg.ShutdownTimeout):
+		t.Fatal("Run() did not return after shutdown")
+	}
+
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("shutdown waited %v for the open stream", elapsed)
+	}
+}
+
+func TestRun_ReturnsListenError(t *testing.T) {
+	logger.Initialize("error")
+
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to occupy port: %v", err)
+	}
+	defer busy.Close()
+
+	cfg := &config.ServerConfig{
+		ServerAddress:   freeAddress(t),
+		GraphiteAddress: busy.Addr().String(),
+		ShutdownTimeout: 5 * time.Second,
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.New(cfg).Run(context.Background())
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("Run() error = nil, want listen error")
+		}
+	case <-time.After(cfg.ShutdownTimeout):
+		t.Fatal("Run() did not return after listen error")
+	}
+}
+
+func TestRun_ShutdownAuditsFinalStatsDFlush(t *testing.T) {
+	logger.Initialize("error")
+
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find free UDP port: %v", err)
+	}
+	statsDAddress := udp.LocalAddr().String()
+	udp.Close()
+
+	address := freeAddress(t)
+	auditPath := filepath.Join(t.TempDir(), "audit.log")
+
+	cfg := &config.ServerConfig{
+		ServerAddress:       address,
+		StatsDAddress:       statsDAddress,
+		StatsDFlushInterval: time.Hour,
+		AuditFile:           auditPath,
+		ShutdownTimeout:     5 * time.Second,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.New(cfg).Run(ctx)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		resp, err := http.Get(fmt.Sprintf("http://%s/healthz", address))
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not start: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	conn, err := net.Dial("udp", statsDAddress)
+	if err != nil {
+		t.Fatalf("failed to dial StatsD: %v", err)
+	}
+	conn.Write([]byte("jobs:3|c"))
+	conn.Close()
+
+	// Даем приемнику прочитать пакет: до остановки значения не сбрасываются.
+	time.Sleep(100 * time.Millisecond)
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	case <-time.After(cfg.ShutdownTimeout):
+		t.Fatal("Run() did not return after shutdown")
+	}
+
+	data, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatalf("failed to read audit file: %v", err)
+	}
+
+	if !strings.Contains(string(data), "jobs") {
+		t.Errorf("audit file %q has no event for the final StatsD flush", data)
+	}
+}
+
+func TestRun_DrainReportsNotReady(t *testing.T) {
+	logger.Initialize("error")
+
+	address := freeAddress(t)
+	cfg := &config.ServerConfig{
+		ServerAddress:   address,
+		ShutdownTimeout: 5 * time.Second,
+		ShutdownDrain:   500 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.New(cfg).Run(ctx)
+	}()
+
+	url := fmt.Sprintf("http://%s/readyz", address)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not become ready: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	start := time.Now()
+	cancel()
+
+	// Во время паузы сервер принимает соединения, но не готов.
+	deadline = time.Now().Add(cfg.ShutdownDrain)
+	for {
+		resp, err := http.Get(url)
+		if err != nil {
+			t.Fatalf("server refused connection during drain: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusServiceUnavailable {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("/readyz = %d during drain, want 503", resp.StatusCode)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	case <-time.After(cfg.ShutdownTimeout):
+		t.Fatal("Run() did not return after shutdown")
+	}
+
+	if elapsed := time.Since(start); elapsed < cfg.ShutdownDrain {
+		t.Errorf("shutdown took %v, want at least the drain delay %v", elapsed, cfg.ShutdownDrain)
+	}
+}
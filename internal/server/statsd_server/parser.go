@@ -0,0 +1,119 @@
+package statsdserver
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+)
+
+// Типы метрик протокола StatsD.
+const (
+	typeCounter   = "c"  // счетчик
+	typeGauge     = "g"  // измеритель
+	typeTimer     = "ms" // длительность в миллисекундах
+	typeHistogram = "h"  // гистограмма DogStatsD
+)
+
+// errInvalidLine - ошибка разбора строки протокола StatsD.
+var errInvalidLine = errors.New("invalid statsd line")
+
+// sample - одно значение, разобранное из строки протокола StatsD.
+type sample struct {
+	name     string        // имя метрики, приведенное к допустимому виду
+	mType    string        // тип метрики StatsD
+	value    float64       // значение
+	relative bool          // значение измерителя задано приращением (+N или -N)
+	rate     float64       // частота выборки от 0 до 1
+	labels   models.Labels // метки из тегов DogStatsD
+}
+
+// parseLine разбирает строку вида name:value|type[|@rate][|#tag:value,...].
+// Секции DogStatsD, отличные от частоты выборки и тегов, игнорируются.
+func parseLine(line string) (sample, error) {
+	nameEnd := strings.IndexByte(line, ':')
+	if nameEnd <= 0 {
+		return sample{}, fmt.Errorf("%w: missing metric name: %q", errInvalidLine, line)
+	}
+
+	s := sample{name: models.SanitizeName(line[:nameEnd]), rate: 1}
+
+	sections := strings.Split(line[nameEnd+1:], "|")
+	if len(sections) < 2 {
+		return sample{}, fmt.Errorf("%w: missing metric type: %q", errInvalidLine, line)
+	}
+
+	s.mType = sections[1]
+	switch s.mType {
+	case typeCounter, typeGauge, typeTimer, typeHistogram:
+	default:
+		return sample{}, fmt.Errorf("%w: unsupported metric type %q", errInvalidLine, s.mType)
+	}
+
+	rawValue := sections[0]
+	if s.mType == typeGauge && (strings.HasPrefix(rawValue, "+") || strings.HasPrefix(rawValue, "-")) {
+		s.relative = true
+	}
+
+	// Бесконечные значения и NaN нельзя сохранить в JSON, отрицательные приращения
+	// счетчика не принимаются и по HTTP.
+	value, err := strconv.ParseFloat(rawValue, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return sample{}, fmt.Errorf("%w: invalid value %q", errInvalidLine, rawValue)
+	}
+	if s.mType == typeCounter && value < 0 {
+		return sample{}, fmt.Errorf("%w: negative counter value %q", errInvalidLine, rawValue)
+	}
+	s.value = value
+
+	for _, section := range sections[2:] {
+		switch {
+		case strings.HasPrefix(section, "@"):
+			rate, err := strconv.ParseFloat(section[1:], 64)
+			if err != nil || rate <= 0 || rate > 1 {
+				return sample{}, fmt.Errorf("%w: invalid sample rate %q", errInvalidLine, section)
+			}
+			s.rate = rate
+		case strings.HasPrefix(section, "#"):
+			labels, err := parseTags(section[1:])
+			if err != nil {
+				return sample{}, err
+			}
+			s.labels = labels
+		}
+	}
+
+	series := models.Metrics{ID: s.name, MType: metricType(s.mType), Labels: s.labels}
+	if err := series.ValidateSeries(); err != nil {
+		return sample{}, fmt.Errorf("%w: %v", errInvalidLine, err)
+	}
+
+	return s, nil
+}
+
+// parseTags преобразует теги DogStatsD вида name:value,name:value в метки.
+// Теги без значения пропускаются: метка не может иметь пустое значение.
+func parseTags(raw string) (models.Labels, error) {
+	labels := make(models.Labels)
+
+	for _, tag := range strings.Split(raw, ",") {
+		name, value, ok := strings.Cut(tag, ":")
+		if !ok || value == "" {
+			continue
+		}
+		labels[models.SanitizeName(name)] = value
+	}
+
+	if len(labels) == 0 {
+		return nil, nil
+	}
+
+	if err := labels.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidLine, err)
+	}
+
+	return labels, nil
+}
@@ -0,0 +1,386 @@
+// Package statsdserver предоставляет UDP-приемник метрик в формате StatsD.
+//
+// Приемник разбирает строки протокола StatsD с тегами DogStatsD, агрегирует
+// значения по отправителям и периодически сбрасывает их в хранилище метрик:
+// счетчики - суммой приращений с учетом частоты выборки, измерители - последним
+// значением, длительности и гистограммы - гистограммой с фиксированными корзинами.
+package statsdserver
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/interfaces"
+	"github.com/Ko4etov/go-metrics/internal/server/service/audit"
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+)
+
+// TimerBuckets - границы корзин гистограмм для длительностей и гистограмм StatsD
+// в единицах исходных значений (для длительностей - миллисекунды).
+var TimerBuckets = []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+const (
+	maxPacketSize    = 65535 // максимальный размер UDP-пакета
+	defaultMaxSeries = 1000  // количество рядов, при котором сброс выполняется досрочно
+)
+
+// StatsDServerConfig содержит конфигурацию приемника StatsD.
+type StatsDServerConfig struct {
+	Storage       interfaces.Storage  // хранилище метрик
+	AuditSvc      *audit.AuditService // сервис аудита (опционально)
+	TrustedSubnet *net.IPNet          // доверенная подсеть отправителей (опционально)
+	FlushInterval time.Duration       // интервал сброса агрегированных метрик
+	MaxSeries     int                 // количество рядов для досрочного сброса (по умолчанию 1000)
+}
+
+// series - агрегированный ряд метрики одного отправителя.
+type series struct {
+	metric  models.Metrics // метрика с именем, типом и метками
+	counter float64        // сумма приращений счетчика с учетом частоты выборки
+}
+
+// Server принимает метрики StatsD по UDP и сбрасывает их в хранилище.
+type Server struct {
+	storage       interfaces.Storage            // хранилище метрик
+	auditSvc      *audit.AuditService           // сервис аудита
+	trustedSubnet *net.IPNet                    // доверенная подсеть отправителей
+	flushInterval time.Duration                 // интервал сброса
+	maxSeries     int                           // количество рядов для досрочного сброса
+	mu            sync.Mutex                    // мьютекс для безопасного доступа к агрегатам
+	pending       map[string]map[string]*series // агрегаты по адресу отправителя и ключу ряда
+	seriesCount   int                           // количество агрегированных рядов
+	remainders    map[string]map[string]float64 // дробные остатки счетчиков до следующего сброса
+	conn          net.PacketConn                // UDP-соединение приемника
+	flushNow      chan struct{}                 // сигнал досрочного сброса
+	done          chan bool                     // канал для остановки сброса
+	wg            sync.WaitGroup                // группа ожидания горутины сброса
+	stopped       bool                          // флаг остановки приемника
+}
+
+// New создает приемник StatsD.
+func New(config *StatsDServerConfig) *Server {
+	maxSeries := config.MaxSeries
+	if maxSeries <= 0 {
+		maxSeries = defaultMaxSeries
+	}
+
+	return &Server{
+		storage:       config.Storage,
+		auditSvc:      config.AuditSvc,
+		trustedSubnet: config.TrustedSubnet,
+		flushInterval: config.FlushInterval,
+		maxSeries:     maxSeries,
+		pending:       make(map[string]map[string]*series),
+		remainders:    make(map[string]map[string]float64),
+		flushNow:      make(chan struct{}, 1),
+		done:          make(chan bool),
+	}
+}
+
+// Serve принимает пакеты из conn до его закрытия методом Stop.
+func (s *Server) Serve(conn net.PacketConn) error {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return net.ErrClosed
+	}
+	s.conn = conn
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	go s.runFlush()
+
+	buf := make([]byte, maxPacketSize)
+
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		s.handlePacket(addr, buf[:n])
+	}
+}
+
+// Stop закрывает приемник и сбрасывает накопленные метрики в хранилище.
+func (s *Server) Stop() {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	s.stopped = true
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	close(s.done)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	s.Flush()
+}
+
+// runFlush периодически сбрасывает агрегированные метрики.
+func (s *Server) runFlush() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.Flush()
+		case <-s.flushNow:
+			s.Flush()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// handlePacket разбирает пакет отправителя addr и добавляет значения в агрегаты.
+func (s *Server) handlePacket(addr net.Addr, packet []byte) {
+	source := sourceIP(addr)
+
+	if s.trustedSubnet != nil {
+		ip := net.ParseIP(source)
+		if ip == nil || !s.trustedSubnet.Contains(ip) {
+			logger.Logger.Warnf("[statsd] packet from untrusted address rejected: %s", addr)
+			return
+		}
+	}
+
+	for _, line := range strings.Split(string(packet), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		sample, err := parseLine(line)
+		if err != nil {
+			logger.Logger.Warnf("[statsd] %s: %v", source, err)
+			continue
+		}
+
+		s.add(source, sample)
+	}
+}
+
+// add добавляет значение в агрегат ряда отправителя.
+func (s *Server) add(source string, sample sample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mType := metricType(sample.mType)
+	key := mType + ":" + models.MetricKey(sample.name, sample.labels)
+
+	bySource, ok := s.pending[source]
+	if !ok {
+		bySource = make(map[string]*series)
+		s.pending[source] = bySource
+	}
+
+	agg, ok := bySource[key]
+	if !ok {
+		agg = &series{metric: models.Metrics{ID: sample.name, MType: mType, Labels: sample.labels}}
+		bySource[key] = agg
+		s.seriesCount++
+	}
+
+	switch mType {
+	case models.Counter:
+		agg.counter += sample.value / sample.rate
+	case models.Gauge:
+		value := sample.value
+		if sample.relative {
+			value += s.currentGauge(agg)
+		}
+		agg.metric.Value = &value
+	case models.Histogram:
+		if agg.metric.Histogram == nil {
+			agg.metric.Histogram = &models.HistogramValue{
+				Buckets: TimerBuckets,
+				Counts:  make([]uint64, len(TimerBuckets)+1),
+			}
+		}
+		observe(agg.metric.Histogram, sample.value, sample.rate)
+	}
+
+	if s.seriesCount >= s.maxSeries {
+		select {
+		case s.flushNow <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// currentGauge возвращает текущее значение измерителя: агрегированное с прошлого сброса
+// или сохраненное в хранилище.
+func (s *Server) currentGauge(agg *series) float64 {
+	if agg.metric.Value != nil {
+		return *agg.metric.Value
+	}
+
+	stored, ok := s.storage.Metric(agg.metric.Key())
+	if ok && stored.MType == models.Gauge && stored.Value != nil {
+		return *stored.Value
+	}
+
+	return 0
+}
+
+// Flush сбрасывает агрегированные метрики в хранилище отдельным батчем для каждого отправителя
+// и отправляет по батчу событие аудита.
+func (s *Server) Flush() {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = make(map[string]map[string]*series)
+	s.seriesCount = 0
+
+	batches := make(map[string][]models.Metrics, len(pending))
+	sources := make([]string, 0, len(pending))
+	for source, bySource := range pending {
+		remainders, ok := s.remainders[source]
+		if !ok {
+			remainders = make(map[string]float64)
+			s.remainders[source] = remainders
+		}
+
+		batches[source] = collectSeries(bySource, remainders)
+		sources = append(sources, source)
+
+		if len(remainders) == 0 {
+			delete(s.remainders, source)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Strings(sources)
+
+	for _, source := range sources {
+		metrics := batches[source]
+		if len(metrics) == 0 {
+			continue
+		}
+
+		if err := s.storage.UpdateMetricsBatchFrom(source, metrics); err != nil {
+			logger.Logger.Errorf("[statsd] failed to update metrics from %s: %v", source, err)
+			continue
+		}
+
+		s.sendAuditEvent(source, metrics)
+	}
+}
+
+// sendAuditEvent отправляет событие аудита о сброшенном батче отправителя.
+func (s *Server) sendAuditEvent(source string, metrics []models.Metrics) {
+	if s.auditSvc == nil {
+		return
+	}
+
+	metricNames := make([]string, 0, len(metrics))
+	for _, metric := range metrics {
+		metricNames = append(metricNames, metric.ID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	event := audit.AuditEvent{
+		TS:        time.Now().Unix(),
+		Metrics:   metricNames,
+		IPAddress: source,
+	}
+
+	if err := s.auditSvc.Notify(ctx, event); err != nil {
+		logger.Logger.Infof("[audit] Failed to send audit event: %v\n", err)
+	}
+}
+
+// collectSeries преобразует агрегаты в метрики, упорядоченные по ключу ряда.
+// Приращение счетчика с учетом частоты выборки дробное, поэтому в хранилище уходит
+// его целая часть, а дробный остаток сохраняется в remainders и добавляется
+// к приращению при следующем сбросе. Счетчики с нулевой целой частью пропускаются.
+func collectSeries(bySource map[string]*series, remainders map[string]float64) []models.Metrics {
+	keys := make([]string, 0, len(bySource))
+	for key := range bySource {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	metrics := make([]models.Metrics, 0, len(keys))
+	for _, key := range keys {
+		agg := bySource[key]
+		metric := agg.metric
+
+		if metric.MType == models.Counter {
+			total := agg.counter + remainders[key]
+			delta := int64(math.Trunc(total))
+
+			if remainder := total - float64(delta); remainder != 0 {
+				remainders[key] = remainder
+			} else {
+				delete(remainders, key)
+			}
+
+			if delta == 0 {
+				continue
+			}
+			metric.Delta = &delta
+		}
+
+		metrics = append(metrics, metric)
+	}
+
+	return metrics
+}
+
+// observe добавляет значение в гистограмму. При частоте выборки меньше 1
+// наблюдение учитывается с весом 1/rate.
+func observe(h *models.HistogramValue, value, rate float64) {
+	weight := uint64(math.Round(1 / rate))
+	if weight == 0 {
+		weight = 1
+	}
+
+	h.Counts[sort.SearchFloat64s(h.Buckets, value)] += weight
+	h.Sum += value * float64(weight)
+	h.Count += weight
+}
+
+// metricType возвращает тип метрики хранилища для типа StatsD.
+func metricType(statsdType string) string {
+	switch statsdType {
+	case typeCounter:
+		return models.Counter
+	case typeGauge:
+		return models.Gauge
+	default:
+		return models.Histogram
+	}
+}
+
+// sourceIP возвращает IP-адрес отправителя пакета.
+func sourceIP(addr net.Addr) string {
+	if udpAddr, ok := addr.(*net.UDPAddr); ok {
+		return udpAddr.IP.String()
+	}
+
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+
+	return host
+}
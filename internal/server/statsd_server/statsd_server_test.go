@@ -0,0 +1,223 @@
+package statsdserver
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ko4etov/go-metrics/internal/models"
+	"github.com/Ko4etov/go-metrics/internal/server/repository/storage"
+	"github.com/Ko4etov/go-metrics/internal/server/service/audit"
+	"github.com/Ko4etov/go-metrics/internal/server/service/logger"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    sample
+		wantErr bool
+	}{
+		{
+			line: "api.requests:3|c|@0.5",
+			want: sample{name: "api_requests", mType: typeCounter, value: 3, rate: 0.5},
+		},
+		{
+			line: "queue-size:-2|g|#env:prod,host:web1,canary",
+			want: sample{name: "queue_size", mType: typeGauge, value: -2, relative: true, rate: 1,
+				labels: models.Labels{"env": "prod", "host": "web1"}},
+		},
+		{
+			line: "db.query:12.5|ms",
+			want: sample{name: "db_query", mType: typeTimer, value: 12.5, rate: 1},
+		},
+		{
+			line: "payload:512|h|c:container|#region:eu",
+			want: sample{name: "payload", mType: typeHistogram, value: 512, rate: 1,
+				labels: models.Labels{"region": "eu"}},
+		},
+		{line: "users:42|s", wantErr: true},
+		{line: "requests|c", wantErr: true},
+		{line: "requests:1", wantErr: true},
+		{line: "requests:abc|c", wantErr: true},
+		{line: "requests:-1|c", wantErr: true},
+		{line: "queue-size:NaN|g", wantErr: true},
+		{line: "db.query:Inf|ms", wantErr: true},
+		{line: "payload:-Inf|h", wantErr: true},
+		{line: "requests:1|c|@2", wantErr: true},
+		{line: "requests:1|c|#__name__:x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := parseLine(tt.line)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got.name != tt.want.name || got.mType != tt.want.mType || got.value != tt.want.value ||
+				got.relative != tt.want.relative || got.rate != tt.want.rate ||
+				got.labels.String() != tt.want.labels.String() {
+				t.Errorf("parseLine() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// auditRecorder запоминает полученные события аудита.
+type auditRecorder struct {
+	mu     sync.Mutex
+	events []audit.AuditEvent
+}
+
+func (r *auditRecorder) Audit(_ context.Context, event audit.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *auditRecorder) Name() string { return "recorder" }
+
+func TestServer_AggregatesAndFlushes(t *testing.T) {
+	logger.Initialize("error")
+
+	store := storage.New(&storage.MetricsStorageConfig{})
+	initial := 10.0
+	store.UpdateMetric(models.Metrics{ID: "queue", MType: models.Gauge, Value: &initial})
+
+	recorder := &auditRecorder{}
+	auditSvc := audit.NewAuditService()
+	auditSvc.Subscribe(recorder)
+
+	server := New(&StatsDServerConfig{
+		Storage:       store,
+		AuditSvc:      auditSvc,
+		FlushInterval: time.Hour,
+	})
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(conn) }()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer client.Close()
+
+	packets := []string{
+		"requests:1|c|#host:web1\nrequests:2|c|@0.5|#host:web1",
+		"queue:+5|g\nqueue:-1|g",
+		"latency:7|ms\nlatency:120|ms\ninvalid line",
+	}
+	for _, packet := range packets {
+		if _, err := client.Write([]byte(packet)); err != nil {
+			t.Fatalf("failed to send packet: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		server.mu.Lock()
+		count := server.seriesCount
+		var received bool
+		for _, bySource := range server.pending {
+			if agg, ok := bySource[models.Histogram+":latency"]; ok && agg.metric.Histogram.Count == 2 {
+				received = true
+			}
+		}
+		server.mu.Unlock()
+
+		if count == 3 && received {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("packets were not aggregated in time, %d series", count)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	server.Stop()
+	if err := <-served; err != nil {
+		t.Fatalf("Serve returned error: %v", err)
+	}
+
+	counter, ok := store.Metric(`requests{host="web1"}`)
+	if !ok || counter.Delta == nil || *counter.Delta != 5 {
+		t.Errorf("expected counter delta 5, got %+v", counter)
+	}
+
+	gauge, ok := store.Metric("queue")
+	if !ok || gauge.Value == nil || *gauge.Value != 14 {
+		t.Errorf("expected gauge value 14, got %+v", gauge)
+	}
+
+	histogram, ok := store.Metric("latency")
+	if !ok || histogram.Histogram == nil || histogram.Histogram.Count != 2 ||
+		histogram.Histogram.Counts[1] != 1 || histogram.Histogram.Counts[5] != 1 {
+		t.Errorf("unexpected histogram %+v", histogram.Histogram)
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if len(recorder.events) != 1 || recorder.events[0].IPAddress != "127.0.0.1" || len(recorder.events[0].Metrics) != 3 {
+		t.Errorf("expected one audit event from 127.0.0.1 with 3 metrics, got %+v", recorder.events)
+	}
+}
+
+func TestServer_RejectsUntrustedSender(t *testing.T) {
+	logger.Initialize("error")
+
+	store := storage.New(&storage.MetricsStorageConfig{})
+	_, subnet, _ := net.ParseCIDR("10.0.0.0/8")
+	server := New(&StatsDServerConfig{Storage: store, TrustedSubnet: subnet, FlushInterval: time.Hour})
+
+	server.handlePacket(&net.UDPAddr{IP: net.ParseIP("192.168.1.5"), Port: 8125}, []byte("requests:1|c"))
+	server.handlePacket(&net.UDPAddr{IP: net.ParseIP("10.1.2.3"), Port: 8125}, []byte("trusted:1|c"))
+	server.Flush()
+
+	if _, ok := store.Metric("requests"); ok {
+		t.Error("expected metric from untrusted sender to be dropped")
+	}
+	if _, ok := store.Metric("trusted"); !ok {
+		t.Error("expected metric from trusted sender to be stored")
+	}
+}
+
+func TestServer_CarriesCounterRemainder(t *testing.T) {
+	logger.Initialize("error")
+
+	store := storage.New(&storage.MetricsStorageConfig{})
+	server := New(&StatsDServerConfig{Storage: store, FlushInterval: time.Hour})
+
+	// Каждый сброс получает 2.5 с учетом частоты выборки.
+	for range 4 {
+		server.add("10.0.0.1", sample{name: "requests", mType: typeCounter, value: 1, rate: 0.4})
+		server.Flush()
+	}
+
+	// Дробная часть меньше единицы не теряется, а копится между сбросами.
+	for range 4 {
+		server.add("10.0.0.1", sample{name: "retries", mType: typeCounter, value: 0.25, rate: 1})
+		server.Flush()
+	}
+
+	if value, err := store.CounterMetric("requests"); err != nil || value != "10" {
+		t.Errorf("requests = %q (%v), want 10", value, err)
+	}
+	if value, err := store.CounterMetric("retries"); err != nil || value != "1" {
+		t.Errorf("retries = %q (%v), want 1", value, err)
+	}
+}
@@ -0,0 +1,373 @@
+// Package configloader загружает параметры конфигурации из флагов командной строки,
+// переменных окружения и файла конфигурации в формате JSON.
+//
+// Значение параметра берется из первого источника, в котором оно задано:
+// флаги, переменные окружения, файл конфигурации, значение по умолчанию.
+// Путь к файлу конфигурации задается флагом -c/-config или переменной окружения CONFIG.
+//
+// Длительности задаются строками в формате time.ParseDuration ("1s", "500ms").
+// Число без единицы измерения трактуется в единицах параметра, например
+// интервал сохранения "300" означает 300 секунд.
+package configloader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfigEnv - переменная окружения с путем к файлу конфигурации.
+const ConfigEnv = "CONFIG"
+
+// Param описывает источники значения параметра.
+type Param struct {
+	Key   string // ключ в файле конфигурации
+	Env   string // переменная окружения
+	Flag  string // имя флага командной строки
+	Usage string // описание флага
+}
+
+// value - значение параметра определенного типа.
+type value interface {
+	set(s string) error                 // разбирает значение из флага или переменной окружения
+	setJSON(data json.RawMessage) error // разбирает значение из файла конфигурации
+	String() string                     // значение для вывода в справке
+}
+
+// parameter - зарегистрированный параметр.
+type parameter struct {
+	Param
+	value   value   // значение параметра
+	flagSet *string // значение флага, если флаг был указан
+}
+
+// Loader загружает значения зарегистрированных параметров.
+type Loader struct {
+	flags      *flag.FlagSet                   // флаги командной строки
+	params     []*parameter                    // параметры в порядке регистрации
+	keys       map[string]*parameter           // параметры по ключам файла конфигурации
+	configPath string                          // путь к файлу конфигурации из флага
+	LookupEnv  func(key string) (string, bool) // чтение переменных окружения, по умолчанию os.LookupEnv
+}
+
+// New создает загрузчик параметров программы name.
+func New(name string) *Loader {
+	l := &Loader{
+		flags:     flag.NewFlagSet(name, flag.ContinueOnError),
+		keys:      make(map[string]*parameter),
+		LookupEnv: os.LookupEnv,
+	}
+
+	l.flags.StringVar(&l.configPath, "c", "", "Path to JSON config file")
+	l.flags.StringVar(&l.configPath, "config", "", "Path to JSON config file")
+
+	return l
+}
+
+// SetOutput задает поток для вывода справки и ошибок разбора флагов.
+func (l *Loader) SetOutput(w io.Writer) {
+	l.flags.SetOutput(w)
+}
+
+// String регистрирует строковый параметр со значением по умолчанию def.
+func (l *Loader) String(p *string, param Param, def string) {
+	*p = def
+	l.register(param, (*stringValue)(p))
+}
+
+// Int регистрирует целочисленный параметр со значением по умолчанию def.
+func (l *Loader) Int(p *int, param Param, def int) {
+	*p = def
+	l.register(param, (*intValue)(p))
+}
+
+// Float64 регистрирует дробный параметр со значением по умолчанию def.
+func (l *Loader) Float64(p *float64, param Param, def float64) {
+	*p = def
+	l.register(param, (*float64Value)(p))
+}
+
+// Bool регистрирует логический параметр со значением по умолчанию def.
+func (l *Loader) Bool(p *bool, param Param, def bool) {
+	*p = def
+	l.register(param, (*boolValue)(p))
+}
+
+// Duration регистрирует параметр-длительность со значением по умолчанию def.
+// Число без единицы измерения трактуется в единицах unit.
+func (l *Loader) Duration(p *time.Duration, param Param, def, unit time.Duration) {
+	*p = def
+	l.register(param, &durationValue{p: p, unit: unit})
+}
+
+// register добавляет параметр и его флаг.
+func (l *Loader) register(param Param, v value) {
+	p := &parameter{Param: param, value: v}
+	l.params = append(l.params, p)
+
+	if param.Key != "" {
+		l.keys[param.Key] = p
+	}
+
+	if param.Flag != "" {
+		l.flags.Var(&flagValue{p: p}, param.Flag, param.Usage)
+	}
+}
+
+// Load разбирает аргументы командной строки args и заполняет параметры.
+// Ошибки всех источников возвращаются вместе с указанием источника и параметра.
+// Если запрошена справка, возвращается flag.ErrHelp.
+func (l *Loader) Load(args []string) error {
+	if err := l.flags.Parse(args); err != nil {
+		return err
+	}
+
+	if l.flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(l.flags.Args(), " "))
+	}
+
+	var errs []error
+
+	configPath := l.configPath
+	if configPath == "" {
+		configPath, _ = l.LookupEnv(ConfigEnv)
+	}
+
+	if configPath != "" {
+		if err := l.loadFile(configPath); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, p := range l.params {
+		if p.Env == "" {
+			continue
+		}
+
+		if env, ok := l.LookupEnv(p.Env); ok {
+			if err := p.value.set(env); err != nil {
+				errs = append(errs, fmt.Errorf("environment variable %s: %w", p.Env, err))
+			}
+		}
+	}
+
+	// Флаги разбираются при Parse, но применяются последними, чтобы иметь приоритет.
+	for _, p := range l.params {
+		if p.flagSet != nil {
+			if err := p.value.set(*p.flagSet); err != nil {
+				errs = append(errs, fmt.Errorf("flag -%s: %w", p.Flag, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// loadFile применяет значения из файла конфигурации path.
+func (l *Loader) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("config file %s: invalid JSON: %w", path, err)
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		p, ok := l.keys[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("config file %s: unknown parameter %q", path, key))
+			continue
+		}
+
+		if err := p.value.setJSON(values[key]); err != nil {
+			errs = append(errs, fmt.Errorf("config file %s: parameter %q: %w", path, key, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// flagValue откладывает применение значения флага до загрузки остальных источников.
+type flagValue struct {
+	p *parameter
+}
+
+func (f *flagValue) String() string {
+	if f.p == nil {
+		return ""
+	}
+	return f.p.value.String()
+}
+
+func (f *flagValue) Set(s string) error {
+	f.p.flagSet = &s
+	return nil
+}
+
+// IsBoolFlag позволяет указывать логические флаги без значения.
+func (f *flagValue) IsBoolFlag() bool {
+	_, ok := f.p.value.(*boolValue)
+	return ok
+}
+
+type stringValue string
+
+func (v *stringValue) set(s string) error {
+	*v = stringValue(s)
+	return nil
+}
+
+func (v *stringValue) setJSON(data json.RawMessage) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("expected string, got %s", data)
+	}
+	*v = stringValue(s)
+	return nil
+}
+
+func (v *stringValue) String() string { return string(*v) }
+
+type intValue int
+
+func (v *intValue) set(s string) error {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("invalid integer %q", s)
+	}
+	*v = intValue(n)
+	return nil
+}
+
+func (v *intValue) setJSON(data json.RawMessage) error {
+	var n int
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("expected integer, got %s", data)
+	}
+	*v = intValue(n)
+	return nil
+}
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+
+type float64Value float64
+
+func (v *float64Value) set(s string) error {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", s)
+	}
+	*v = float64Value(f)
+	return nil
+}
+
+func (v *float64Value) setJSON(data json.RawMessage) error {
+	var f float64
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("expected number, got %s", data)
+	}
+	*v = float64Value(f)
+	return nil
+}
+
+func (v *float64Value) String() string { return strconv.FormatFloat(float64(*v), 'g', -1, 64) }
+
+type boolValue bool
+
+func (v *boolValue) set(s string) error {
+	b, err := strconv.ParseBool(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("invalid boolean %q", s)
+	}
+	*v = boolValue(b)
+	return nil
+}
+
+func (v *boolValue) setJSON(data json.RawMessage) error {
+	var b bool
+	if err := json.Unmarshal(data, &b); err != nil {
+		return fmt.Errorf("expected boolean, got %s", data)
+	}
+	*v = boolValue(b)
+	return nil
+}
+
+func (v *boolValue) String() string { return strconv.FormatBool(bool(*v)) }
+
+// durationValue - длительность; число без единицы измерения задается в единицах unit.
+type durationValue struct {
+	p    *time.Duration
+	unit time.Duration
+}
+
+func (v *durationValue) set(s string) error {
+	s = strings.TrimSpace(s)
+
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		*v.p = time.Duration(n) * v.unit
+		return nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: expected a value like \"1s\" or a number of %s", s, unitName(v.unit))
+	}
+	*v.p = d
+	return nil
+}
+
+func (v *durationValue) setJSON(data json.RawMessage) error {
+	data = bytes.TrimSpace(data)
+
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return v.set(s)
+	}
+
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("expected duration string like \"1s\" or a number of %s, got %s", unitName(v.unit), data)
+	}
+	*v.p = time.Duration(n) * v.unit
+	return nil
+}
+
+func (v *durationValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return v.p.String()
+}
+
+// unitName возвращает название единицы измерения длительности для сообщений об ошибках.
+func unitName(unit time.Duration) string {
+	switch unit {
+	case time.Millisecond:
+		return "milliseconds"
+	case time.Second:
+		return "seconds"
+	case time.Minute:
+		return "minutes"
+	case time.Hour:
+		return "hours"
+	}
+	return unit.String()
+}
@@ -0,0 +1,240 @@
+package configloader
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testParams - набор параметров для проверки загрузчика.
+type testParams struct {
+	Address  string
+	Interval time.Duration
+	Timeout  time.Duration
+	Restore  bool
+	Limit    int
+	Rate     float64
+}
+
+// newTestLoader создает загрузчик с переменными окружения env.
+func newTestLoader(p *testParams, env map[string]string) *Loader {
+	l := New("test")
+	l.SetOutput(io.Discard)
+	l.LookupEnv = func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+
+	l.String(&p.Address, Param{Key: "address", Env: "ADDRESS", Flag: "a"}, ":8080")
+	l.Duration(&p.Interval, Param{Key: "store_interval", Env: "STORE_INTERVAL", Flag: "i"}, 300*time.Second, time.Second)
+	l.Duration(&p.Timeout, Param{Key: "slow_threshold", Env: "SLOW_THRESHOLD", Flag: "slow"}, 500*time.Millisecond, time.Millisecond)
+	l.Bool(&p.Restore, Param{Key: "restore", Env: "RESTORE", Flag: "r"}, true)
+	l.Int(&p.Limit, Param{Key: "rate_limit", Env: "RATE_LIMIT", Flag: "l"}, 1)
+	l.Float64(&p.Rate, Param{Key: "sample_rate", Env: "SAMPLE_RATE", Flag: "rate"}, 1)
+
+	return l
+}
+
+// writeConfig записывает файл конфигурации и возвращает путь к нему.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	var p testParams
+	if err := newTestLoader(&p, nil).Load(nil); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	want := testParams{Address: ":8080", Interval: 300 * time.Second, Timeout: 500 * time.Millisecond, Restore: true, Limit: 1, Rate: 1}
+	if p != want {
+		t.Errorf("Load() = %+v, want %+v", p, want)
+	}
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeConfig(t, `{
+		"address": "file:1",
+		"store_interval": "1s",
+		"restore": false,
+		"rate_limit": 3
+	}`)
+
+	env := map[string]string{
+		"CONFIG":         path,
+		"ADDRESS":        "env:2",
+		"STORE_INTERVAL": "2",
+	}
+
+	var p testParams
+	if err := newTestLoader(&p, env).Load([]string{"-a", "flag:3"}); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if p.Address != "flag:3" {
+		t.Errorf("Address = %q, want flag value", p.Address)
+	}
+	if p.Interval != 2*time.Second {
+		t.Errorf("Interval = %s, want env value 2s", p.Interval)
+	}
+	if p.Restore {
+		t.Error("Restore = true, want file value false")
+	}
+	if p.Limit != 3 {
+		t.Errorf("Limit = %d, want file value 3", p.Limit)
+	}
+	if p.Rate != 1 {
+		t.Errorf("Rate = %v, want default 1", p.Rate)
+	}
+}
+
+func TestLoad_ConfigFlagOverridesEnv(t *testing.T) {
+	envPath := writeConfig(t, `{"address": "env-file"}`)
+	flagPath := writeConfig(t, `{"address": "flag-file"}`)
+
+	for _, name := range []string{"-c", "-config"} {
+		var p testParams
+		err := newTestLoader(&p, map[string]string{"CONFIG": envPath}).Load([]string{name, flagPath})
+		if err != nil {
+			t.Fatalf("Load(%s) error = %v", name, err)
+		}
+
+		if p.Address != "flag-file" {
+			t.Errorf("Load(%s): Address = %q, want value from flag config file", name, p.Address)
+		}
+	}
+}
+
+func TestLoad_Durations(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		env      map[string]string
+		file     string
+		interval time.Duration
+		timeout  time.Duration
+	}{
+		{name: "flag string", args: []string{"-i", "1m30s"}, interval: 90 * time.Second, timeout: 500 * time.Millisecond},
+		{name: "flag bare number", args: []string{"-i", "10", "-slow", "20"}, interval: 10 * time.Second, timeout: 20 * time.Millisecond},
+		{name: "env string", env: map[string]string{"SLOW_THRESHOLD": "2s"}, interval: 300 * time.Second, timeout: 2 * time.Second},
+		{name: "file string", file: `{"store_interval": "500ms"}`, interval: 500 * time.Millisecond, timeout: 500 * time.Millisecond},
+		{name: "file number", file: `{"store_interval": 0, "slow_threshold": 100}`, interval: 0, timeout: 100 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := tt.env
+			if tt.file != "" {
+				env = map[string]string{"CONFIG": writeConfig(t, tt.file)}
+			}
+
+			var p testParams
+			if err := newTestLoader(&p, env).Load(tt.args); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+
+			if p.Interval != tt.interval || p.Timeout != tt.timeout {
+				t.Errorf("Load() = (%s, %s), want (%s, %s)", p.Interval, p.Timeout, tt.interval, tt.timeout)
+			}
+		})
+	}
+}
+
+func TestLoad_BoolFlagWithoutValue(t *testing.T) {
+	var p testParams
+	loader := newTestLoader(&p, map[string]string{"RESTORE": "false"})
+
+	if err := loader.Load([]string{"-r"}); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if !p.Restore {
+		t.Error("Restore = false, want true from flag without value")
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		file string
+		want []string
+	}{
+		{
+			name: "invalid env",
+			env:  map[string]string{"RATE_LIMIT": "many"},
+			want: []string{"environment variable RATE_LIMIT", `invalid integer "many"`},
+		},
+		{
+			name: "invalid flag",
+			args: []string{"-i", "soon"},
+			want: []string{"flag -i", `invalid duration "soon"`},
+		},
+		{
+			name: "unknown file key",
+			file: `{"adress": ":9090"}`,
+			want: []string{`unknown parameter "adress"`},
+		},
+		{
+			name: "wrong file type",
+			file: `{"restore": "yes", "rate_limit": "3"}`,
+			want: []string{`parameter "restore": expected boolean`, `parameter "rate_limit": expected integer`},
+		},
+		{
+			name: "invalid JSON",
+			file: `{"address": `,
+			want: []string{"invalid JSON"},
+		},
+		{
+			name: "missing file",
+			env:  map[string]string{"CONFIG": "/nonexistent/config.json"},
+			want: []string{"config file", "/nonexistent/config.json"},
+		},
+		{
+			name: "unexpected argument",
+			args: []string{"extra"},
+			want: []string{"unexpected arguments: extra"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := tt.env
+			if tt.file != "" {
+				env = map[string]string{"CONFIG": writeConfig(t, tt.file)}
+			}
+
+			var p testParams
+			err := newTestLoader(&p, env).Load(tt.args)
+			if err == nil {
+				t.Fatal("Load() error = nil, want error")
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Load() error = %q, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoad_Help(t *testing.T) {
+	var p testParams
+	if err := newTestLoader(&p, nil).Load([]string{"-h"}); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("Load(-h) error = %v, want %v", err, flag.ErrHelp)
+	}
+}
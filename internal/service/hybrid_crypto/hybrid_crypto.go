@@ -0,0 +1,192 @@
+// Package hybridcrypto реализует гибридное шифрование данных:
+// данные шифруются случайным ключом AES-GCM, а ключ - открытым ключом RSA (OAEP).
+// Это позволяет шифровать асимметричным ключом данные произвольного размера.
+package hybridcrypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Header - заголовок HTTP-запроса, которым помечаются зашифрованные тела.
+const Header = "Content-Encryption"
+
+// Scheme - значение заголовка Header для гибридной схемы RSA-OAEP + AES-GCM.
+const Scheme = "rsa-oaep-aes-gcm"
+
+// aesKeySize - размер симметричного ключа в байтах (AES-256).
+const aesKeySize = 32
+
+// keyLengthSize - размер поля с длиной зашифрованного ключа в байтах.
+const keyLengthSize = 2
+
+// ErrMalformedMessage возвращается, если зашифрованное сообщение имеет неверный формат.
+var ErrMalformedMessage = errors.New("malformed encrypted message")
+
+// LoadPublicKey загружает открытый ключ RSA из PEM-файла.
+// Поддерживаются форматы PKIX ("PUBLIC KEY"), PKCS#1 ("RSA PUBLIC KEY") и сертификаты X.509.
+func LoadPublicKey(path string) (*rsa.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		return asRSAPublicKey(cert.PublicKey)
+	default:
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key: %w", err)
+		}
+		return asRSAPublicKey(key)
+	}
+}
+
+// LoadPrivateKey загружает закрытый ключ RSA из PEM-файла.
+// Поддерживаются форматы PKCS#1 ("RSA PRIVATE KEY") и PKCS#8 ("PRIVATE KEY").
+func LoadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+
+	return rsaKey, nil
+}
+
+// Encrypt шифрует данные открытым ключом.
+// Формат сообщения: длина зашифрованного ключа (2 байта, big endian),
+// ключ AES, зашифрованный RSA-OAEP, nonce и шифротекст AES-GCM.
+func Encrypt(key *rsa.PublicKey, data []byte) ([]byte, error) {
+	aesKey := make([]byte, aesKeySize)
+	if _, err := rand.Read(aesKey); err != nil {
+		return nil, fmt.Errorf("failed to generate AES key: %w", err)
+	}
+
+	encryptedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, key, aesKey, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt AES key: %w", err)
+	}
+
+	gcm, err := newGCM(aesKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	message := make([]byte, keyLengthSize, keyLengthSize+len(encryptedKey)+len(nonce)+len(data)+gcm.Overhead())
+	binary.BigEndian.PutUint16(message, uint16(len(encryptedKey)))
+	message = append(message, encryptedKey...)
+	message = append(message, nonce...)
+
+	return gcm.Seal(message, nonce, data, nil), nil
+}
+
+// Decrypt расшифровывает сообщение, созданное Encrypt, закрытым ключом.
+func Decrypt(key *rsa.PrivateKey, message []byte) ([]byte, error) {
+	if len(message) < keyLengthSize {
+		return nil, ErrMalformedMessage
+	}
+
+	keyLength := int(binary.BigEndian.Uint16(message))
+	message = message[keyLengthSize:]
+
+	if len(message) < keyLength {
+		return nil, ErrMalformedMessage
+	}
+
+	aesKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, key, message[:keyLength], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt AES key: %w", err)
+	}
+	message = message[keyLength:]
+
+	gcm, err := newGCM(aesKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(message) < gcm.NonceSize() {
+		return nil, ErrMalformedMessage
+	}
+
+	data, err := gcm.Open(nil, message[:gcm.NonceSize()], message[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data: %w", err)
+	}
+
+	return data, nil
+}
+
+// newGCM создает шифр AES-GCM для ключа.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	return gcm, nil
+}
+
+// readPEM читает первый PEM-блок из файла.
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+
+	return block, nil
+}
+
+// asRSAPublicKey приводит открытый ключ к типу RSA.
+func asRSAPublicKey(key any) (*rsa.PublicKey, error) {
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not an RSA key")
+	}
+
+	return rsaKey, nil
+}
@@ -0,0 +1,83 @@
+package hybridcrypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeKeys(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+
+	dir := t.TempDir()
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey failed: %v", err)
+	}
+	privatePath := filepath.Join(dir, "private.pem")
+	os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600)
+
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey failed: %v", err)
+	}
+	publicPath := filepath.Join(dir, "public.pem")
+	os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644)
+
+	return publicPath, privatePath
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	publicPath, privatePath := writeKeys(t)
+
+	publicKey, err := LoadPublicKey(publicPath)
+	if err != nil {
+		t.Fatalf("LoadPublicKey failed: %v", err)
+	}
+
+	privateKey, err := LoadPrivateKey(privatePath)
+	if err != nil {
+		t.Fatalf("LoadPrivateKey failed: %v", err)
+	}
+
+	// Данные больше размера ключа RSA.
+	data := bytes.Repeat([]byte(`{"id":"Alloc","type":"gauge","value":1}`), 1000)
+
+	message, err := Encrypt(publicKey, data)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+
+	if bytes.Contains(message, []byte("Alloc")) {
+		t.Error("Encrypted message contains plaintext")
+	}
+
+	decrypted, err := Decrypt(privateKey, message)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+
+	if !bytes.Equal(decrypted, data) {
+		t.Error("Decrypted data does not match original")
+	}
+
+	message[len(message)-1] ^= 0xff
+	if _, err := Decrypt(privateKey, message); err == nil {
+		t.Error("Expected error for tampered message")
+	}
+
+	if _, err := Decrypt(privateKey, []byte{0x01}); err == nil {
+		t.Error("Expected error for truncated message")
+	}
+}
@@ -1,6 +1,8 @@
 package retriableagent
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/url"
@@ -21,6 +23,12 @@ func New(retries int, retryDelays []time.Duration) *RetriableAgent {
 }
 
 func (r *RetriableAgent) Send(operation func() error) error {
+	return r.SendContext(context.Background(), operation)
+}
+
+// SendContext выполняет операцию с повторными попытками, как Send.
+// Повторные попытки прекращаются, в том числе во время паузы между ними, при отмене ctx.
+func (r *RetriableAgent) SendContext(ctx context.Context, operation func() error) error {
 	var lastErr error
 
 	for attempt := 0; attempt <= r.MaxRetries; attempt++ {
@@ -31,19 +39,35 @@ func (r *RetriableAgent) Send(operation func() error) error {
 
 		lastErr = err
 
+		if ctx.Err() != nil {
+			return fmt.Errorf("retries cancelled: %w", err)
+		}
+
 		if !r.isRetriableError(err) {
 			return fmt.Errorf("non-retriable error: %w", err)
 		}
 
 		if attempt < r.MaxRetries {
 			delay := r.RetryDelays[attempt]
-			time.Sleep(delay)
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return fmt.Errorf("retries cancelled: %w", err)
+			}
 		}
 	}
 
 	return fmt.Errorf("failed after %d retries: %w", r.MaxRetries, lastErr)
 }
 
+// IsRetriable проверяет, является ли ошибка временной, то есть может ли повторная
+// попытка операции завершиться успешно.
+func (r *RetriableAgent) IsRetriable(err error) bool {
+	return r.isRetriableError(err)
+}
+
 func (r *RetriableAgent) isRetriableError(err error) bool {
 	if err == nil {
 		return false
@@ -66,12 +90,16 @@ func (r *RetriableAgent) isRetriableError(err error) bool {
 
 // isNetworkError проверяет сетевые ошибки.
 func (r *RetriableAgent) isNetworkError(err error) bool {
-	switch e := err.(type) {
-	case *url.Error:
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
 		return true
-	case net.Error:
-		return e.Timeout()
 	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return netErr.Timeout()
+	}
+
 	return false
 }
 
@@ -85,6 +113,7 @@ func (r *RetriableAgent) isRetriableByContent(err error) bool {
 		"no such host", "EOF", "broken pipe", "connection aborted",
 		"i/o timeout", "network is unreachable", "reset by peer",
 		"service unavailable", "bad gateway", "gateway timeout",
+		"deadline exceeded", "code = internal",
 	}
 
 	for _, pattern := range retriablePatterns {
@@ -96,8 +125,11 @@ func (r *RetriableAgent) isRetriableByContent(err error) bool {
 	return false
 }
 
+// isRetriableHTTPStatus проверяет коды ответа, при которых запрос стоит повторить:
+// 400 и ошибки сервера 5xx.
 func (r *RetriableAgent) isRetriableHTTPStatus(err error) bool {
 	errorStr := strings.ToLower(err.Error())
 
-	return strings.Contains(errorStr, "server error: 400")
+	return strings.Contains(errorStr, "server error: 400") ||
+		strings.Contains(errorStr, "server error: 5")
 }
\ No newline at end of file